@@ -0,0 +1,139 @@
+// Package audit forwards audit log entries to external sinks (syslog
+// collectors, JSON-lines files) next to the MongoDB logs collection.
+package audit
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/rafinhacuri/SanchezDNS/models"
+)
+
+// minRetryDelay and maxRetryDelay bound the wait between attempts to
+// deliver an entry the sink failed to take.
+const (
+	minRetryDelay = time.Second
+	maxRetryDelay = time.Minute
+)
+
+// Sink receives audit entries one at a time. Write may block; entries are
+// handed to it from a buffered queue so callers never wait on a sink.
+type Sink interface {
+	Name() string
+	Write(entry models.Log) error
+	Close() error
+}
+
+// queueSize is the number of entries buffered per sink (AUDIT_BUFFER,
+// 1000 by default). Entries are dropped while the queue is full, which is
+// the only case an entry is lost.
+func queueSize() int {
+	n, err := strconv.Atoi(os.Getenv("AUDIT_BUFFER"))
+	if err != nil || n < 1 {
+		return 1000
+	}
+	return n
+}
+
+// Start opens the sinks configured through AUDIT_SYSLOG_ADDR and
+// AUDIT_FILE and forwards every log entry inserted from then on. It does
+// nothing when neither is set.
+func Start(ctx context.Context) {
+	var sinks []Sink
+
+	if addr := os.Getenv("AUDIT_SYSLOG_ADDR"); addr != "" {
+		sink, err := NewSyslogSink(addr, os.Getenv("AUDIT_SYSLOG_CA"))
+		if err != nil {
+			slog.Error("invalid AUDIT_SYSLOG_ADDR, syslog forwarding disabled", "value", addr, "error", err)
+		} else {
+			sinks = append(sinks, sink)
+		}
+	}
+
+	if path := os.Getenv("AUDIT_FILE"); path != "" {
+		sink, err := NewFileSink(path)
+		if err != nil {
+			slog.Error("failed to open AUDIT_FILE, file forwarding disabled", "path", path, "error", err)
+		} else {
+			sinks = append(sinks, sink)
+		}
+	}
+
+	size := queueSize()
+	for _, sink := range sinks {
+		q := &queue{sink: sink, entries: make(chan models.Log, size)}
+		go q.run(ctx)
+		// push never blocks, so it runs inline and the sink gets the
+		// entries in chain order.
+		models.OnLogInsertSync(q.push)
+	}
+}
+
+type queue struct {
+	sink    Sink
+	entries chan models.Log
+	dropped atomic.Int64
+	failing bool
+}
+
+func (q *queue) push(entry models.Log) {
+	select {
+	case q.entries <- entry:
+	default:
+		if q.dropped.Add(1) == 1 {
+			slog.Warn("audit sink queue full, dropping entries", "sink", q.sink.Name())
+		}
+	}
+}
+
+func (q *queue) run(ctx context.Context) {
+	defer q.sink.Close()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case entry := <-q.entries:
+			q.write(ctx, entry)
+		}
+	}
+}
+
+// write delivers one entry, retrying with a doubling delay until the sink
+// takes it so entries are neither lost nor reordered while a sink is down.
+// Only the first error of a run of failures and the recovery after it are
+// logged.
+func (q *queue) write(ctx context.Context, entry models.Log) {
+	delay := minRetryDelay
+	for {
+		err := q.sink.Write(entry)
+		if err == nil {
+			break
+		}
+		if !q.failing {
+			slog.Error("failed to forward audit entry, retrying", "sink", q.sink.Name(), "error", err)
+		}
+		q.failing = true
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		delay = min(delay*2, maxRetryDelay)
+	}
+
+	if q.failing {
+		slog.Info("audit sink recovered", "sink", q.sink.Name())
+		q.failing = false
+	}
+	if n := q.dropped.Swap(0); n > 0 {
+		slog.Warn("audit sink dropped entries while its queue was full", "sink", q.sink.Name(), "dropped", n)
+	}
+}
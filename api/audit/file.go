@@ -0,0 +1,36 @@
+package audit
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/rafinhacuri/SanchezDNS/models"
+)
+
+// FileSink appends entries as JSON lines. The file is opened in append
+// mode, so it can be rotated with copytruncate.
+type FileSink struct {
+	path string
+	file *os.File
+	enc  *json.Encoder
+}
+
+func NewFileSink(path string) (*FileSink, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o640)
+	if err != nil {
+		return nil, err
+	}
+	return &FileSink{path: path, file: file, enc: json.NewEncoder(file)}, nil
+}
+
+func (s *FileSink) Name() string {
+	return "file:" + s.path
+}
+
+func (s *FileSink) Write(entry models.Log) error {
+	return s.enc.Encode(entry)
+}
+
+func (s *FileSink) Close() error {
+	return s.file.Close()
+}
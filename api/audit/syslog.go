@@ -0,0 +1,188 @@
+package audit
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rafinhacuri/SanchezDNS/models"
+)
+
+const (
+	// syslogPriority is facility 13 (log audit) with severity 5 (notice).
+	syslogPriority = 13*8 + 5
+
+	syslogAppName = "sanchezdns"
+
+	// syslogSDID is the structured data element carrying the entry fields.
+	// 32473 is the enterprise number reserved for documentation (RFC 5612).
+	syslogSDID = "audit@32473"
+)
+
+// SyslogSink sends entries as RFC 5424 messages over UDP, TCP or TLS. The
+// structured data holds the indexed fields and the message is the entry as
+// JSON. Stream transports use octet-counting framing (RFC 6587, RFC 5425).
+type SyslogSink struct {
+	network  string
+	addr     string
+	tls      *tls.Config
+	hostname string
+	conn     net.Conn
+}
+
+// NewSyslogSink parses an address such as "udp://siem:514",
+// "tcp://siem:601" or "tls://siem:6514". caFile optionally replaces the
+// system roots used to verify a TLS collector.
+func NewSyslogSink(rawURL, caFile string) (*SyslogSink, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Host == "" {
+		return nil, errors.New("missing host")
+	}
+
+	s := &SyslogSink{network: u.Scheme, addr: u.Host, hostname: "-"}
+	if h, err := os.Hostname(); err == nil && h != "" {
+		s.hostname = h
+	}
+
+	switch u.Scheme {
+	case "udp", "tcp":
+	case "tls":
+		s.tls = &tls.Config{ServerName: u.Hostname(), MinVersion: tls.VersionTLS12}
+		if caFile != "" {
+			pem, err := os.ReadFile(caFile)
+			if err != nil {
+				return nil, err
+			}
+			pool := x509.NewCertPool()
+			if !pool.AppendCertsFromPEM(pem) {
+				return nil, errors.New("no certificates found in AUDIT_SYSLOG_CA")
+			}
+			s.tls.RootCAs = pool
+		}
+	default:
+		return nil, fmt.Errorf("unsupported scheme %q, expected udp, tcp or tls", u.Scheme)
+	}
+
+	return s, nil
+}
+
+func (s *SyslogSink) Name() string {
+	return "syslog:" + s.network + "://" + s.addr
+}
+
+func (s *SyslogSink) dial() (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	if s.tls != nil {
+		return tls.DialWithDialer(dialer, "tcp", s.addr, s.tls)
+	}
+	return dialer.Dial(s.network, s.addr)
+}
+
+// Write sends one message, reconnecting once if the connection was lost.
+func (s *SyslogSink) Write(entry models.Log) error {
+	msg, err := s.format(entry)
+	if err != nil {
+		return err
+	}
+	if s.network != "udp" {
+		msg = append([]byte(strconv.Itoa(len(msg))+" "), msg...)
+	}
+
+	for attempt := 0; attempt < 2; attempt++ {
+		if s.conn == nil {
+			if s.conn, err = s.dial(); err != nil {
+				return err
+			}
+		}
+
+		_ = s.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+		if _, err = s.conn.Write(msg); err == nil {
+			return nil
+		}
+
+		_ = s.conn.Close()
+		s.conn = nil
+	}
+
+	return err
+}
+
+func (s *SyslogSink) Close() error {
+	if s.conn == nil {
+		return nil
+	}
+	return s.conn.Close()
+}
+
+// format renders the RFC 5424 message:
+// <PRI>1 TIMESTAMP HOSTNAME APP-NAME PROCID MSGID [SD] MSG
+func (s *SyslogSink) format(entry models.Log) ([]byte, error) {
+	body, err := json.Marshal(entry)
+	if err != nil {
+		return nil, err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "<%d>1 %s %s %s %d %s [%s",
+		syslogPriority,
+		entry.CreatedAt.UTC().Format("2006-01-02T15:04:05.000000Z07:00"),
+		syslogHeaderField(s.hostname, 255),
+		syslogAppName,
+		os.Getpid(),
+		syslogHeaderField(entry.Action, 32),
+		syslogSDID,
+	)
+
+	params := []struct{ name, value string }{
+		{"username", entry.Username},
+		{"connection", entry.IdConnection},
+		{"host", entry.HostServer},
+		{"zone", entry.Zone},
+		{"seq", strconv.FormatInt(entry.Seq, 10)},
+		{"hash", entry.Hash},
+	}
+	for _, p := range params {
+		if p.value != "" {
+			fmt.Fprintf(&b, ` %s="%s"`, p.name, syslogParamEscaper.Replace(p.value))
+		}
+	}
+
+	b.WriteString("] ")
+	b.Write(body)
+
+	return []byte(b.String()), nil
+}
+
+// syslogParamEscaper escapes the characters RFC 5424 reserves in
+// PARAM-VALUE.
+var syslogParamEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`)
+
+// syslogHeaderField keeps printable US-ASCII without spaces, as header
+// fields require, and uses the nil value "-" when nothing is left.
+func syslogHeaderField(value string, maxLen int) string {
+	out := strings.Map(func(r rune) rune {
+		if r < 33 || r > 126 {
+			return -1
+		}
+		return r
+	}, value)
+
+	if len(out) > maxLen {
+		out = out[:maxLen]
+	}
+	if out == "" {
+		return "-"
+	}
+	return out
+}
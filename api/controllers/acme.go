@@ -0,0 +1,265 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rafinhacuri/SanchezDNS/db"
+	"github.com/rafinhacuri/SanchezDNS/models"
+	"github.com/rafinhacuri/SanchezDNS/passwords"
+	"github.com/rafinhacuri/SanchezDNS/pdns"
+	"github.com/rafinhacuri/SanchezDNS/utils"
+	"github.com/rafinhacuri/SanchezDNS/workers"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var acmeAuthLimiter = utils.NewRateLimiter(10, 10*time.Minute)
+
+func GetAcmeAccounts(ctx *gin.Context) {
+	ok, connection := permission(ctx)
+	if !ok {
+		return
+	}
+
+	opts := options.Find().SetSort(bson.M{"fulldomain": 1})
+
+	cursor, err := db.Database.Collection("acme_accounts").Find(ctx.Request.Context(), bson.M{"idConnection": connection.ID.Hex()}, opts)
+	if err != nil {
+		ctx.JSON(500, gin.H{"message": "failed to fetch ACME accounts"})
+		return
+	}
+
+	accounts := []models.AcmeAccount{}
+	if err := cursor.All(ctx.Request.Context(), &accounts); err != nil {
+		ctx.JSON(500, gin.H{"message": "failed to parse ACME accounts"})
+		return
+	}
+
+	ctx.JSON(200, accounts)
+}
+
+// RegisterAcmeAccount creates acme-dns credentials for the _acme-challenge
+// name of a domain. The response has the acme-dns /register shape, and the
+// password is only returned here.
+func RegisterAcmeAccount(ctx *gin.Context) {
+	var req models.AcmeRegisterRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(400, gin.H{"message": fmt.Sprintf("invalid request body: %v", err)})
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		ctx.JSON(400, gin.H{"message": fmt.Sprintf("validation error: %v", err)})
+		return
+	}
+
+	ok, connection := permissionFor(ctx, req.Connection)
+	if !ok {
+		return
+	}
+
+	ctxReq, cancel := context.WithTimeout(ctx.Request.Context(), 15*time.Second)
+	defer cancel()
+
+	client, err := pdns.New(connection)
+	if err != nil {
+		ctx.JSON(500, gin.H{"message": err.Error()})
+		return
+	}
+
+	if _, err := client.Zone(ctxReq, req.Zone); err != nil {
+		pdnsError(ctx, err, "fetch zone")
+		return
+	}
+
+	count, err := db.Database.Collection("acme_accounts").CountDocuments(ctxReq, bson.M{"idConnection": connection.ID.Hex(), "fulldomain": req.ChallengeName()})
+	if err != nil {
+		ctx.JSON(500, gin.H{"message": err.Error()})
+		return
+	}
+	if count > 0 {
+		ctx.JSON(409, gin.H{"message": "an ACME account already exists for this name"})
+		return
+	}
+
+	user, err := utils.RandomToken(16)
+	if err != nil {
+		ctx.JSON(500, gin.H{"message": err.Error()})
+		return
+	}
+	password, err := utils.RandomToken(30)
+	if err != nil {
+		ctx.JSON(500, gin.H{"message": err.Error()})
+		return
+	}
+	hash, err := passwords.BCrypt(password)
+	if err != nil {
+		ctx.JSON(500, gin.H{"message": "failed to hash password"})
+		return
+	}
+
+	username := ctx.GetString("username")
+	id := primitive.NewObjectID()
+
+	account := models.AcmeAccount{
+		ID:           id,
+		Subdomain:    id.Hex(),
+		Username:     user,
+		Password:     hash,
+		FullDomain:   req.ChallengeName(),
+		Zone:         req.Zone,
+		IdConnection: connection.ID.Hex(),
+		AllowFrom:    req.AllowFrom,
+		Txt:          []models.AcmeTxt{},
+		CreatedBy:    username,
+		CreatedAt:    time.Now(),
+	}
+	if account.AllowFrom == nil {
+		account.AllowFrom = []string{}
+	}
+
+	if _, err := db.Database.Collection("acme_accounts").InsertOne(ctxReq, account); err != nil {
+		ctx.JSON(500, gin.H{"message": err.Error()})
+		return
+	}
+
+	log := &models.Log{
+		Username:     username,
+		IdConnection: account.IdConnection,
+		Action:       "create_acme_account",
+		Details:      fmt.Sprintf("User %s created ACME credentials for %s", username, account.FullDomain),
+		Zone:         account.Zone,
+		HostServer:   connection.Host,
+		CreatedAt:    time.Now(),
+	}
+
+	_ = log.Insert(ctxReq)
+
+	ctx.JSON(201, gin.H{
+		"username":   account.Username,
+		"password":   password,
+		"fulldomain": strings.TrimSuffix(account.FullDomain, "."),
+		"subdomain":  account.Subdomain,
+		"allowfrom":  account.AllowFrom,
+	})
+}
+
+// DeleteAcmeAccount removes an ACME account together with any challenge
+// values it still has published.
+func DeleteAcmeAccount(ctx *gin.Context) {
+	ok, connection := permission(ctx)
+	if !ok {
+		return
+	}
+
+	id, err := primitive.ObjectIDFromHex(ctx.Query("id"))
+	if err != nil {
+		ctx.JSON(400, gin.H{"message": "invalid ACME account ID"})
+		return
+	}
+
+	ctxReq, cancel := context.WithTimeout(ctx.Request.Context(), 15*time.Second)
+	defer cancel()
+
+	var account models.AcmeAccount
+	if err := db.Database.Collection("acme_accounts").FindOneAndDelete(ctxReq, bson.M{"_id": id, "idConnection": connection.ID.Hex()}).Decode(&account); err != nil {
+		ctx.JSON(404, gin.H{"message": "ACME account not found"})
+		return
+	}
+
+	if len(account.Txt) > 0 {
+		account.Txt = nil
+		if err := workers.PublishAcmeChallenge(ctxReq, &account); err != nil {
+			slog.Error("failed to remove ACME challenge", "name", account.FullDomain, "error", err)
+		}
+	}
+
+	username := ctx.GetString("username")
+
+	log := &models.Log{
+		Username:     username,
+		IdConnection: account.IdConnection,
+		Action:       "delete_acme_account",
+		Details:      fmt.Sprintf("User %s deleted ACME credentials for %s", username, account.FullDomain),
+		Zone:         account.Zone,
+		HostServer:   connection.Host,
+		CreatedAt:    time.Now(),
+	}
+
+	_ = log.Insert(ctxReq)
+
+	ctx.JSON(200, gin.H{"message": "ACME account deleted successfully"})
+}
+
+// AcmeUpdate implements the acme-dns /update call. Credentials are passed
+// in the X-Api-User and X-Api-Key headers and errors use the acme-dns
+// error names.
+func AcmeUpdate(ctx *gin.Context) {
+	clientIP := ctx.ClientIP()
+
+	// Throttled addresses are turned away before any credential check, so
+	// a correct guess cannot be told apart from a wrong one.
+	if acmeAuthLimiter.Limited(clientIP) {
+		ctx.JSON(429, gin.H{"error": "too_many_requests"})
+		return
+	}
+
+	ctxReq, cancel := context.WithTimeout(ctx.Request.Context(), 15*time.Second)
+	defer cancel()
+
+	var account models.AcmeAccount
+	err := db.Database.Collection("acme_accounts").FindOne(ctxReq, bson.M{"username": ctx.GetHeader("X-Api-User")}).Decode(&account)
+	if err != nil || !passwords.VerifyBCrypt(ctx.GetHeader("X-Api-Key"), account.Password) || !account.AllowsAddr(clientIP) {
+		acmeAuthLimiter.Add(clientIP)
+		ctx.JSON(401, gin.H{"error": "forbidden"})
+		return
+	}
+
+	var req models.AcmeUpdateRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(400, gin.H{"error": "malformed_json_payload"})
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		ctx.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Subdomain != account.Subdomain {
+		ctx.JSON(401, gin.H{"error": "forbidden"})
+		return
+	}
+
+	now := time.Now()
+	_, err = workers.UpdateAcmeChallenge(ctxReq, account.ID, func(a *models.AcmeAccount) bool {
+		a.AddTxt(req.Txt, now)
+		a.LastUpdateAt = &now
+		return true
+	})
+	if err != nil {
+		slog.Error("failed to publish ACME challenge", "name", account.FullDomain, "error", err)
+		ctx.JSON(500, gin.H{"error": "db_error"})
+		return
+	}
+
+	log := &models.Log{
+		Username:     account.CreatedBy,
+		IdConnection: account.IdConnection,
+		Action:       "acme_update",
+		Details:      fmt.Sprintf("ACME client %s from %s published a challenge on %s", account.Subdomain, clientIP, account.FullDomain),
+		Zone:         account.Zone,
+		CreatedAt:    now,
+	}
+
+	if err := log.Insert(ctxReq); err != nil {
+		slog.Error("failed to log ACME update", "name", account.FullDomain, "error", err)
+	}
+
+	ctx.JSON(200, gin.H{"txt": req.Txt})
+}
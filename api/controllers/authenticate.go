@@ -2,6 +2,7 @@ package controllers
 
 import (
 	"context"
+	"errors"
 	"log/slog"
 	"time"
 
@@ -18,6 +19,7 @@ func Auth(ctx *gin.Context) {
 
 	if err := user.Validate(); err != nil {
 		slog.Error("Validation error", "error", err)
+		recordSecurityEvent(ctx, models.SecurityLogin, user.Email, models.OutcomeFailure, "invalid_request")
 		ctx.JSON(400, gin.H{"message": "Username or password incorrect"})
 		return
 	}
@@ -27,9 +29,19 @@ func Auth(ctx *gin.Context) {
 
 	token, isAdmin, err := user.Login(ctxReq)
 	if err != nil {
+		reason := "error"
+		switch {
+		case errors.Is(err, models.ErrUnknownUser):
+			reason = "unknown_user"
+		case errors.Is(err, models.ErrWrongPassword):
+			reason = "wrong_password"
+		}
+		recordSecurityEvent(ctx, models.SecurityLogin, user.Email, models.OutcomeFailure, reason)
 		ctx.JSON(401, gin.H{"message": "Username or password incorrect"})
 		return
 	}
 
+	recordSecurityEvent(ctx, models.SecurityLogin, user.Email, models.OutcomeSuccess, "")
+
 	ctx.JSON(200, gin.H{"message": "Login successful", "token": token, "isAdmin": isAdmin})
 }
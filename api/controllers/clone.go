@@ -0,0 +1,107 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rafinhacuri/SanchezDNS/models"
+	"github.com/rafinhacuri/SanchezDNS/pdns"
+	"github.com/rafinhacuri/SanchezDNS/workers"
+)
+
+func CloneZone(ctx *gin.Context) {
+	var req models.CloneZoneRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(400, gin.H{"message": fmt.Sprintf("invalid request body: %v", err)})
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		ctx.JSON(400, gin.H{"message": fmt.Sprintf("validation error: %v", err)})
+		return
+	}
+
+	allowed, source := permissionFor(ctx, req.SourceConnection)
+	if !allowed {
+		return
+	}
+
+	allowed, destination := permissionFor(ctx, req.Connection)
+	if !allowed {
+		return
+	}
+
+	sourceClient, err := pdns.New(source)
+	if err != nil {
+		ctx.JSON(500, gin.H{"message": err.Error()})
+		return
+	}
+
+	destinationClient, err := pdns.New(destination)
+	if err != nil {
+		ctx.JSON(500, gin.H{"message": err.Error()})
+		return
+	}
+
+	ctxReq, cancel := context.WithTimeout(ctx.Request.Context(), 15*time.Second)
+	defer cancel()
+
+	zone, err := sourceClient.Zone(ctxReq, req.SourceZone)
+	if err != nil {
+		pdnsError(ctx, err, "fetch source zone")
+		return
+	}
+
+	rrsets, soa, issues := models.RewriteZone(zone, req.Domain)
+
+	if req.Soa != nil {
+		soa = req.Soa
+	}
+	if soa == nil {
+		ctx.JSON(400, gin.H{"message": "source zone has no SOA record, provide one in the 'soa' field"})
+		return
+	}
+
+	patch := make([]map[string]any, 0, len(rrsets))
+	records := 0
+	for i := range rrsets {
+		patch = append(patch, rrsets[i].Patch())
+		records += len(rrsets[i].Records)
+	}
+
+	if err := createZone(ctxReq, destinationClient, req.Domain, *soa, patch); err != nil {
+		pdnsError(ctx, err, "create zone")
+		return
+	}
+
+	log := &models.Log{
+		Username:     ctx.GetString("username"),
+		IdConnection: req.Connection,
+		Action:       "clone_zone",
+		Details:      fmt.Sprintf("Cloned zone %s from %s on %s (%d rrsets, %d not rewritten)", req.Domain, zone.Name, source.Name, len(rrsets), len(issues)),
+		Zone:         req.Domain,
+		HostServer:   destination.Host,
+		CreatedAt:    time.Now(),
+	}
+
+	if err := log.Insert(ctx.Request.Context()); err != nil {
+		ctx.JSON(500, gin.H{"message": fmt.Sprintf("failed to log zone clone: %v", err)})
+		return
+	}
+
+	workers.RecordZoneState(destination, req.Domain, ctx.GetString("username"))
+
+	if issues == nil {
+		issues = []models.CloneIssue{}
+	}
+
+	ctx.JSON(201, gin.H{
+		"message": "zone cloned successfully",
+		"zone":    req.Domain,
+		"rrsets":  len(rrsets),
+		"records": records,
+		"issues":  issues,
+	})
+}
@@ -9,9 +9,9 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/go-resty/resty/v2"
 	"github.com/rafinhacuri/SanchezDNS/db"
 	"github.com/rafinhacuri/SanchezDNS/models"
+	"github.com/rafinhacuri/SanchezDNS/pdns"
 	"github.com/rafinhacuri/SanchezDNS/utils"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -54,11 +54,13 @@ func InsertConnection(ctx *gin.Context) {
 	ctxReq, cancel := context.WithTimeout(ctx.Request.Context(), 5*time.Second)
 	defer cancel()
 
-	base := utils.NormalizeBase(connection.Host)
-
-	httpc := resty.New().SetBaseURL(base).SetHeader("X-API-Key", request.ApiKey).SetHeader("Accept", "application/json").SetTimeout(6 * time.Second).SetRetryCount(2)
+	client, err := pdns.New(connection)
+	if err != nil {
+		ctx.JSON(500, gin.H{"message": err.Error()})
+		return
+	}
 
-	resp, erro := httpc.R().SetContext(ctxReq).Get(fmt.Sprintf("/api/v1/servers/%s/statistics", connection.ServerId))
+	resp, erro := client.R(ctxReq).Get(client.Path("/statistics"))
 	if erro != nil {
 		ctx.JSON(http.StatusBadGateway, gin.H{"message": fmt.Sprintf("failed to reach PowerDNS: %v", erro)})
 		return
@@ -98,7 +100,7 @@ func InsertConnection(ctx *gin.Context) {
 		CreatedAt:    time.Now(),
 	}
 
-	_, _ = db.Database.Collection("logs").InsertOne(ctxReq, log)
+	_ = log.Insert(ctxReq)
 
 	ctx.JSON(201, gin.H{"message": "connection created successfully"})
 }
@@ -171,7 +173,7 @@ func AddUser(ctx *gin.Context) {
 		CreatedAt:    time.Now(),
 	}
 
-	_, _ = db.Database.Collection("logs").InsertOne(ctxReq, log)
+	_ = log.Insert(ctxReq)
 
 	ctx.JSON(200, gin.H{"message": "user added to connection successfully"})
 }
@@ -244,7 +246,7 @@ func RemoveUser(ctx *gin.Context) {
 		CreatedAt:    time.Now(),
 	}
 
-	_, _ = db.Database.Collection("logs").InsertOne(ctxReq, log)
+	_ = log.Insert(ctxReq)
 
 	ctx.JSON(200, gin.H{"message": "user removed from connection successfully"})
 }
@@ -371,7 +373,7 @@ func EditConnection(ctx *gin.Context) {
 		CreatedAt:    time.Now(),
 	}
 
-	_, _ = db.Database.Collection("logs").InsertOne(ctxReq, log)
+	_ = log.Insert(ctxReq)
 
 	ctx.JSON(200, gin.H{"message": "connection updated successfully"})
 }
@@ -423,7 +425,7 @@ func DeleteConnection(ctx *gin.Context) {
 		CreatedAt:    time.Now(),
 	}
 
-	_, _ = db.Database.Collection("logs").InsertOne(ctx.Request.Context(), log)
+	_ = log.Insert(ctx.Request.Context())
 
 	ctx.JSON(200, gin.H{"message": "connection deleted successfully"})
 }
@@ -477,7 +479,7 @@ func EditConnectionApiKey(ctx *gin.Context) {
 		CreatedAt:    time.Now(),
 	}
 
-	_, err = db.Database.Collection("logs").InsertOne(ctx.Request.Context(), log)
+	err = log.Insert(ctx.Request.Context())
 
 	if err != nil {
 		ctx.JSON(500, gin.H{"message": fmt.Sprintf("failed to log api key update: %v", err)})
@@ -0,0 +1,132 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rafinhacuri/SanchezDNS/db"
+	"github.com/rafinhacuri/SanchezDNS/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// maxHealthChecks bounds the probe results returned by GetHealthHistory.
+const maxHealthChecks = 5000
+
+// GetConnectionsHealth returns the current health of every connection the
+// user can access.
+func GetConnectionsHealth(ctx *gin.Context) {
+	username := ctx.GetString("username")
+
+	ctxReq, cancel := context.WithTimeout(ctx.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{}
+	if !ctx.GetBool("admin") {
+		cursor, err := db.Database.Collection("connections").Find(ctxReq, bson.M{"users": username}, options.Find().SetProjection(bson.M{"_id": 1}))
+		if err != nil {
+			ctx.JSON(500, gin.H{"message": "failed to fetch connections"})
+			return
+		}
+
+		var connections []models.Connection
+		if err := cursor.All(ctxReq, &connections); err != nil {
+			ctx.JSON(500, gin.H{"message": "failed to parse connections"})
+			return
+		}
+
+		ids := make([]string, 0, len(connections))
+		for _, c := range connections {
+			ids = append(ids, c.ID.Hex())
+		}
+		filter["idConnection"] = bson.M{"$in": ids}
+	}
+
+	cursor, err := db.Database.Collection("connection_health").Find(ctxReq, filter, options.Find().SetSort(bson.M{"name": 1}))
+	if err != nil {
+		ctx.JSON(500, gin.H{"message": "failed to fetch connection health"})
+		return
+	}
+
+	health := []models.ConnectionHealth{}
+	if err := cursor.All(ctxReq, &health); err != nil {
+		ctx.JSON(500, gin.H{"message": "failed to parse connection health"})
+		return
+	}
+
+	ctx.JSON(200, health)
+}
+
+// GetHealthHistory returns the probe results and up/down transitions of a
+// connection over ?range= (24h by default).
+func GetHealthHistory(ctx *gin.Context) {
+	ok, connection := permission(ctx)
+	if !ok {
+		return
+	}
+
+	rng := 24 * time.Hour
+	if raw := ctx.Query("range"); raw != "" {
+		d, err := time.ParseDuration(raw)
+		if err != nil || d <= 0 {
+			ctx.JSON(400, gin.H{"message": "invalid range"})
+			return
+		}
+		rng = d
+	}
+
+	since := time.Now().Add(-rng)
+	connectionID := connection.ID.Hex()
+
+	ctxReq, cancel := context.WithTimeout(ctx.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	var health *models.ConnectionHealth
+	var current models.ConnectionHealth
+	err := db.Database.Collection("connection_health").FindOne(ctxReq, bson.M{"idConnection": connectionID}).Decode(&current)
+	switch {
+	case err == nil:
+		health = &current
+	case !errors.Is(err, mongo.ErrNoDocuments):
+		ctx.JSON(500, gin.H{"message": "failed to fetch connection health"})
+		return
+	}
+
+	filter := bson.M{"idConnection": connectionID, "checkedAt": bson.M{"$gte": since}}
+	cursor, err := db.Database.Collection("health_checks").Find(ctxReq, filter, options.Find().SetSort(bson.M{"checkedAt": -1}).SetLimit(maxHealthChecks))
+	if err != nil {
+		ctx.JSON(500, gin.H{"message": "failed to fetch health checks"})
+		return
+	}
+
+	checks := []models.HealthCheck{}
+	if err := cursor.All(ctxReq, &checks); err != nil {
+		ctx.JSON(500, gin.H{"message": "failed to parse health checks"})
+		return
+	}
+	for i, j := 0, len(checks)-1; i < j; i, j = i+1, j-1 {
+		checks[i], checks[j] = checks[j], checks[i]
+	}
+
+	cursor, err = db.Database.Collection("health_events").Find(ctxReq, bson.M{"idConnection": connectionID, "at": bson.M{"$gte": since}}, options.Find().SetSort(bson.M{"at": 1}))
+	if err != nil {
+		ctx.JSON(500, gin.H{"message": "failed to fetch health events"})
+		return
+	}
+
+	events := []models.HealthEvent{}
+	if err := cursor.All(ctxReq, &events); err != nil {
+		ctx.JSON(500, gin.H{"message": "failed to parse health events"})
+		return
+	}
+
+	ctx.JSON(200, gin.H{
+		"health": health,
+		"uptime": models.UptimeRatio(checks),
+		"checks": checks,
+		"events": events,
+	})
+}
@@ -0,0 +1,246 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rafinhacuri/SanchezDNS/db"
+	"github.com/rafinhacuri/SanchezDNS/models"
+	"github.com/rafinhacuri/SanchezDNS/pdns"
+	"github.com/rafinhacuri/SanchezDNS/workers"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func GetDriftEvents(ctx *gin.Context) {
+	allowed, _ := permission(ctx)
+	if !allowed {
+		return
+	}
+
+	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(ctx.DefaultQuery("limit", "10"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
+
+	filter := bson.M{"idConnection": ctx.Query("connection")}
+	if zone := ctx.Query("zone"); zone != "" {
+		filter["zone"] = strings.ToLower(models.Fqdn(zone))
+	}
+
+	switch ctx.DefaultQuery("status", "open") {
+	case "open":
+		filter["resolved"] = false
+	case "resolved":
+		filter["resolved"] = true
+	}
+
+	total, _ := db.Database.Collection("drift_events").CountDocuments(ctx.Request.Context(), filter)
+
+	opts := options.Find().
+		SetSort(bson.M{"detectedAt": -1}).
+		SetSkip(int64((page - 1) * limit)).
+		SetLimit(int64(limit))
+
+	cursor, err := db.Database.Collection("drift_events").Find(ctx.Request.Context(), filter, opts)
+	if err != nil {
+		ctx.JSON(500, gin.H{"message": "failed to fetch drift events"})
+		return
+	}
+
+	events := []models.DriftEvent{}
+	if err := cursor.All(ctx.Request.Context(), &events); err != nil {
+		ctx.JSON(500, gin.H{"message": "failed to parse drift events"})
+		return
+	}
+
+	ctx.JSON(200, gin.H{"data": events, "total": total})
+}
+
+// CheckDrift runs drift detection for a single zone right away instead of
+// waiting for the next background pass.
+func CheckDrift(ctx *gin.Context) {
+	allowed, connection := permission(ctx)
+	if !allowed {
+		return
+	}
+
+	zoneID := ctx.Query("zone")
+	if zoneID == "" {
+		ctx.JSON(400, gin.H{"message": "zone ID is required"})
+		return
+	}
+
+	client, err := pdns.New(connection)
+	if err != nil {
+		ctx.JSON(500, gin.H{"message": err.Error()})
+		return
+	}
+
+	ctxReq, cancel := context.WithTimeout(ctx.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	event, err := workers.DetectZoneDrift(ctxReq, connection, client, zoneID)
+	if err != nil {
+		pdnsError(ctx, err, "check drift")
+		return
+	}
+
+	ctx.JSON(200, gin.H{"drift": event != nil, "event": event})
+}
+
+// SetBaseline makes the live content of a zone its known state, resolving
+// any open drift. With pin=true the baseline is kept even when the zone is
+// later changed through SanchezDNS.
+func SetBaseline(ctx *gin.Context) {
+	allowed, connection := permission(ctx)
+	if !allowed {
+		return
+	}
+
+	zoneID := ctx.Query("zone")
+	if zoneID == "" {
+		ctx.JSON(400, gin.H{"message": "zone ID is required"})
+		return
+	}
+
+	pin := ctx.Query("pin") == "true"
+	username := ctx.GetString("username")
+
+	client, err := pdns.New(connection)
+	if err != nil {
+		ctx.JSON(500, gin.H{"message": err.Error()})
+		return
+	}
+
+	ctxReq, cancel := context.WithTimeout(ctx.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	existing, err := workers.FindZoneState(ctxReq, connection.ID.Hex(), zoneID)
+	if err != nil {
+		ctx.JSON(500, gin.H{"message": err.Error()})
+		return
+	}
+
+	// Unpinning goes through SaveZoneState with pin=false, which keeps a
+	// pinned state untouched, so the old baseline is dropped first.
+	if existing != nil && existing.Pinned && !pin {
+		if _, err := db.Database.Collection("zone_states").DeleteOne(ctxReq, bson.M{"_id": existing.ID}); err != nil {
+			ctx.JSON(500, gin.H{"message": err.Error()})
+			return
+		}
+	}
+
+	state, err := workers.SaveZoneState(ctxReq, client, connection.ID.Hex(), zoneID, username, pin)
+	if err != nil {
+		pdnsError(ctx, err, "save baseline")
+		return
+	}
+
+	if err := workers.ResolveDrift(ctxReq, connection.ID.Hex(), zoneID, "accepted", username); err != nil {
+		ctx.JSON(500, gin.H{"message": err.Error()})
+		return
+	}
+
+	log := &models.Log{
+		Username:     username,
+		IdConnection: ctx.Query("connection"),
+		Action:       "set_baseline",
+		Details:      fmt.Sprintf("Set baseline for zone %s (pinned: %t)", zoneID, pin),
+		Zone:         zoneID,
+		HostServer:   connection.Host,
+		CreatedAt:    time.Now(),
+	}
+
+	if err := log.Insert(ctx.Request.Context()); err != nil {
+		ctx.JSON(500, gin.H{"message": fmt.Sprintf("failed to log baseline: %v", err)})
+		return
+	}
+
+	ctx.JSON(200, gin.H{"message": "baseline saved successfully", "zone": state.Zone, "pinned": state.Pinned, "hash": state.Hash})
+}
+
+// ReapplyBaseline reverts the changes of a drift event by writing the known
+// state back to PowerDNS.
+func ReapplyBaseline(ctx *gin.Context) {
+	allowed, connection := permission(ctx)
+	if !allowed {
+		return
+	}
+
+	eventID, err := primitive.ObjectIDFromHex(ctx.Query("id"))
+	if err != nil {
+		ctx.JSON(400, gin.H{"message": "invalid drift event ID"})
+		return
+	}
+
+	ctxReq, cancel := context.WithTimeout(ctx.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	var event models.DriftEvent
+	err = db.Database.Collection("drift_events").FindOne(ctxReq, bson.M{"_id": eventID, "idConnection": connection.ID.Hex()}).Decode(&event)
+	if err != nil {
+		ctx.JSON(404, gin.H{"message": "drift event not found"})
+		return
+	}
+
+	state, err := workers.FindZoneState(ctxReq, event.IdConnection, event.Zone)
+	if err != nil || state == nil {
+		ctx.JSON(404, gin.H{"message": "no baseline stored for this zone"})
+		return
+	}
+
+	client, err := pdns.New(connection)
+	if err != nil {
+		ctx.JSON(500, gin.H{"message": err.Error()})
+		return
+	}
+
+	zone, err := client.Zone(ctxReq, event.Zone)
+	if err != nil {
+		pdnsError(ctx, err, "fetch zone")
+		return
+	}
+
+	changes := state.Restore(zone.RRSets)
+	if len(changes) > 0 {
+		if err := client.PatchZone(ctxReq, event.Zone, models.PatchChanges(changes)); err != nil {
+			pdnsError(ctx, err, "reapply baseline")
+			return
+		}
+	}
+
+	username := ctx.GetString("username")
+
+	if err := workers.ResolveDrift(ctxReq, event.IdConnection, event.Zone, "reapplied", username); err != nil {
+		ctx.JSON(500, gin.H{"message": err.Error()})
+		return
+	}
+
+	log := &models.Log{
+		Username:     username,
+		IdConnection: event.IdConnection,
+		Action:       "reapply_baseline",
+		Details:      fmt.Sprintf("Reapplied baseline for zone %s (%d rrsets changed)", event.Zone, len(changes)),
+		Zone:         event.Zone,
+		HostServer:   connection.Host,
+		Changes:      changes,
+		CreatedAt:    time.Now(),
+	}
+
+	if err := log.Insert(ctx.Request.Context()); err != nil {
+		ctx.JSON(500, gin.H{"message": fmt.Sprintf("failed to log baseline reapply: %v", err)})
+		return
+	}
+
+	ctx.JSON(200, gin.H{"message": "baseline reapplied successfully", "changes": changes})
+}
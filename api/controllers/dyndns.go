@@ -0,0 +1,348 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/netip"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rafinhacuri/SanchezDNS/db"
+	"github.com/rafinhacuri/SanchezDNS/models"
+	"github.com/rafinhacuri/SanchezDNS/passwords"
+	"github.com/rafinhacuri/SanchezDNS/pdns"
+	"github.com/rafinhacuri/SanchezDNS/utils"
+	"github.com/rafinhacuri/SanchezDNS/workers"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	// nicAuthLimiter limits failed logins per client address and
+	// nicUpdateLimiter limits updates per host.
+	nicAuthLimiter   = utils.NewRateLimiter(10, 10*time.Minute)
+	nicUpdateLimiter = utils.NewRateLimiter(6, time.Minute)
+)
+
+func GetDynDnsHosts(ctx *gin.Context) {
+	if !ctx.GetBool("admin") {
+		ctx.JSON(403, gin.H{"error": "forbidden"})
+		return
+	}
+
+	filter := bson.M{}
+	if connection := ctx.Query("connection"); connection != "" {
+		filter["idConnection"] = connection
+	}
+
+	opts := options.Find().SetSort(bson.M{"hostname": 1})
+
+	cursor, err := db.Database.Collection("dyndns_hosts").Find(ctx.Request.Context(), filter, opts)
+	if err != nil {
+		ctx.JSON(500, gin.H{"message": "failed to fetch dyndns hosts"})
+		return
+	}
+
+	hosts := []models.DynDnsHost{}
+	if err := cursor.All(ctx.Request.Context(), &hosts); err != nil {
+		ctx.JSON(500, gin.H{"message": "failed to parse dyndns hosts"})
+		return
+	}
+
+	ctx.JSON(200, hosts)
+}
+
+// InsertDynDnsHost creates dyndns2 credentials for one record name. The
+// password is only returned in this response.
+func InsertDynDnsHost(ctx *gin.Context) {
+	if !ctx.GetBool("admin") {
+		ctx.JSON(403, gin.H{"error": "forbidden"})
+		return
+	}
+
+	var req models.DynDnsHostRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(400, gin.H{"message": fmt.Sprintf("invalid request body: %v", err)})
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		ctx.JSON(400, gin.H{"message": fmt.Sprintf("validation error: %v", err)})
+		return
+	}
+
+	ctxReq, cancel := context.WithTimeout(ctx.Request.Context(), 15*time.Second)
+	defer cancel()
+
+	connection, err := models.FindConnection(ctxReq, req.Connection)
+	if err != nil {
+		ctx.JSON(404, gin.H{"message": "connection not found"})
+		return
+	}
+
+	client, err := pdns.New(connection)
+	if err != nil {
+		ctx.JSON(500, gin.H{"message": err.Error()})
+		return
+	}
+
+	if _, err := client.Zone(ctxReq, req.Zone); err != nil {
+		pdnsError(ctx, err, "fetch zone")
+		return
+	}
+
+	count, err := db.Database.Collection("dyndns_hosts").CountDocuments(ctxReq, bson.M{"username": req.Username})
+	if err != nil {
+		ctx.JSON(500, gin.H{"message": err.Error()})
+		return
+	}
+	if count > 0 {
+		ctx.JSON(409, gin.H{"message": "dyndns username already exists"})
+		return
+	}
+
+	password := req.Password
+	if password == "" {
+		if password, err = utils.RandomToken(18); err != nil {
+			ctx.JSON(500, gin.H{"message": err.Error()})
+			return
+		}
+	}
+
+	hash, err := passwords.BCrypt(password)
+	if err != nil {
+		ctx.JSON(500, gin.H{"message": "failed to hash password"})
+		return
+	}
+
+	username := ctx.GetString("username")
+
+	host := models.DynDnsHost{
+		Hostname:     req.Hostname,
+		Zone:         req.Zone,
+		IdConnection: connection.ID.Hex(),
+		Username:     req.Username,
+		Password:     hash,
+		TTL:          req.TTL,
+		CreatedBy:    username,
+		CreatedAt:    time.Now(),
+	}
+
+	result, err := db.Database.Collection("dyndns_hosts").InsertOne(ctxReq, host)
+	if err != nil {
+		ctx.JSON(500, gin.H{"message": err.Error()})
+		return
+	}
+
+	log := &models.Log{
+		Username:     username,
+		IdConnection: host.IdConnection,
+		Action:       "create_dyndns_host",
+		Details:      fmt.Sprintf("User %s created dyndns credentials %s for %s", username, host.Username, host.Hostname),
+		Zone:         host.Zone,
+		HostServer:   connection.Host,
+		CreatedAt:    time.Now(),
+	}
+
+	_ = log.Insert(ctxReq)
+
+	ctx.JSON(201, gin.H{"message": "dyndns host created successfully", "id": result.InsertedID, "hostname": host.Hostname, "username": host.Username, "password": password})
+}
+
+func DeleteDynDnsHost(ctx *gin.Context) {
+	if !ctx.GetBool("admin") {
+		ctx.JSON(403, gin.H{"error": "forbidden"})
+		return
+	}
+
+	id, err := primitive.ObjectIDFromHex(ctx.Query("id"))
+	if err != nil {
+		ctx.JSON(400, gin.H{"message": "invalid dyndns host ID"})
+		return
+	}
+
+	var host models.DynDnsHost
+	if err := db.Database.Collection("dyndns_hosts").FindOneAndDelete(ctx.Request.Context(), bson.M{"_id": id}).Decode(&host); err != nil {
+		ctx.JSON(404, gin.H{"message": "dyndns host not found"})
+		return
+	}
+
+	username := ctx.GetString("username")
+
+	log := &models.Log{
+		Username:     username,
+		IdConnection: host.IdConnection,
+		Action:       "delete_dyndns_host",
+		Details:      fmt.Sprintf("User %s deleted dyndns credentials %s for %s", username, host.Username, host.Hostname),
+		Zone:         host.Zone,
+		CreatedAt:    time.Now(),
+	}
+
+	_ = log.Insert(ctx.Request.Context())
+
+	ctx.JSON(200, gin.H{"message": "dyndns host deleted successfully"})
+}
+
+// NicUpdate implements the dyndns2 update protocol
+// (GET /nic/update?hostname=&myip=) with HTTP basic authentication. The
+// answers are the plain text return codes routers expect.
+func NicUpdate(ctx *gin.Context) {
+	clientIP := ctx.ClientIP()
+
+	// Throttled addresses are turned away before any credential check, so
+	// a correct guess cannot be told apart from a wrong one.
+	if nicAuthLimiter.Limited(clientIP) {
+		ctx.String(200, "abuse")
+		return
+	}
+
+	user, password, ok := ctx.Request.BasicAuth()
+	if !ok {
+		nicBadAuth(ctx)
+		return
+	}
+
+	ctxReq, cancel := context.WithTimeout(ctx.Request.Context(), 15*time.Second)
+	defer cancel()
+
+	var host models.DynDnsHost
+	err := db.Database.Collection("dyndns_hosts").FindOne(ctxReq, bson.M{"username": user}).Decode(&host)
+	if err != nil || !passwords.VerifyBCrypt(password, host.Password) {
+		nicAuthLimiter.Add(clientIP)
+		nicBadAuth(ctx)
+		return
+	}
+
+	if !nicUpdateLimiter.Allow(host.Username) {
+		ctx.String(200, "abuse")
+		return
+	}
+
+	hostname := strings.TrimSpace(ctx.Query("hostname"))
+	if hostname == "" {
+		ctx.String(200, "notfqdn")
+		return
+	}
+	if strings.Contains(hostname, ",") {
+		ctx.String(200, "numhost")
+		return
+	}
+	if strings.ToLower(models.Fqdn(hostname)) != host.Hostname {
+		ctx.String(200, "nohost")
+		return
+	}
+
+	myip := strings.TrimSpace(ctx.Query("myip"))
+	if myip == "" {
+		myip = clientIP
+	}
+
+	var ipv4, ipv6 string
+	for _, value := range strings.Split(myip, ",") {
+		addr, err := netip.ParseAddr(strings.TrimSpace(value))
+		if err != nil {
+			ctx.String(200, "dnserr")
+			return
+		}
+		addr = addr.Unmap()
+		if addr.Is4() {
+			ipv4 = addr.String()
+		} else {
+			ipv6 = addr.String()
+		}
+	}
+
+	connection, err := models.FindConnection(ctxReq, host.IdConnection)
+	if err != nil {
+		ctx.String(200, "nohost")
+		return
+	}
+
+	client, err := pdns.New(connection)
+	if err != nil {
+		ctx.String(200, "911")
+		return
+	}
+
+	zone, err := client.Zone(ctxReq, host.Zone)
+	if err != nil {
+		slog.Error("dyndns update failed to fetch zone", "zone", host.Zone, "host", host.Hostname, "error", err)
+		ctx.String(200, "911")
+		return
+	}
+
+	var current, desired []models.RRSet
+	for _, update := range []struct{ rrType, content string }{{"A", ipv4}, {"AAAA", ipv6}} {
+		if update.content == "" {
+			continue
+		}
+
+		rr := models.RRSet{Name: host.Hostname, Type: update.rrType, TTL: host.TTL, Records: []models.Record{{Content: update.content}}}
+		if existing := zone.RRSet(host.Hostname, update.rrType); existing != nil {
+			current = append(current, *existing)
+			rr.Comments = existing.Comments
+		}
+		desired = append(desired, rr)
+	}
+
+	addresses := strings.Join(nonEmpty(ipv4, ipv6), ",")
+
+	changes := models.DiffRRSets(current, desired, false)
+	if len(changes) == 0 {
+		ctx.String(200, "nochg "+addresses)
+		return
+	}
+
+	if err := client.PatchZone(ctxReq, host.Zone, models.PatchChanges(changes)); err != nil {
+		slog.Error("dyndns update failed", "zone", host.Zone, "host", host.Hostname, "error", err)
+		ctx.String(200, "dnserr")
+		return
+	}
+
+	now := time.Now()
+	set := bson.M{"lastUpdateAt": now}
+	if ipv4 != "" {
+		set["lastIPv4"] = ipv4
+	}
+	if ipv6 != "" {
+		set["lastIPv6"] = ipv6
+	}
+	_, _ = db.Database.Collection("dyndns_hosts").UpdateOne(ctxReq, bson.M{"_id": host.ID}, bson.M{"$set": set})
+
+	log := &models.Log{
+		Username:     host.CreatedBy,
+		IdConnection: host.IdConnection,
+		Action:       "dyndns_update",
+		Details:      fmt.Sprintf("Dyndns client %s from %s set %s to %s", host.Username, clientIP, host.Hostname, addresses),
+		Zone:         host.Zone,
+		HostServer:   connection.Host,
+		Changes:      changes,
+		CreatedAt:    now,
+	}
+
+	if err := log.Insert(ctxReq); err != nil {
+		slog.Error("failed to log dyndns update", "host", host.Hostname, "error", err)
+	}
+
+	workers.RecordZoneState(connection, host.Zone, host.CreatedBy)
+
+	ctx.String(200, "good "+addresses)
+}
+
+func nicBadAuth(ctx *gin.Context) {
+	ctx.Header("WWW-Authenticate", `Basic realm="SanchezDNS"`)
+	ctx.String(401, "badauth")
+}
+
+func nonEmpty(values ...string) []string {
+	out := make([]string, 0, len(values))
+	for _, v := range values {
+		if v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
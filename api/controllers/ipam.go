@@ -0,0 +1,81 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rafinhacuri/SanchezDNS/models"
+	"github.com/rafinhacuri/SanchezDNS/pdns"
+)
+
+// GetIpam builds an address inventory for one or more ranges (repeated
+// cidr parameters) from the A, AAAA and PTR records of a connection.
+func GetIpam(ctx *gin.Context) {
+	allowed, connection := permission(ctx)
+	if !allowed {
+		return
+	}
+
+	cidrs := ctx.QueryArray("cidr")
+	if len(cidrs) == 0 {
+		ctx.JSON(400, gin.H{"message": "at least one cidr is required"})
+		return
+	}
+	if len(cidrs) > 16 {
+		ctx.JSON(400, gin.H{"message": "at most 16 cidr ranges can be requested at once"})
+		return
+	}
+
+	prefixes := make([]netip.Prefix, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			ctx.JSON(400, gin.H{"message": fmt.Sprintf("invalid cidr %q", cidr)})
+			return
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+
+	free, _ := strconv.Atoi(ctx.DefaultQuery("free", "5"))
+	if free < 0 || free > 256 {
+		free = 5
+	}
+
+	client, err := pdns.New(connection)
+	if err != nil {
+		ctx.JSON(500, gin.H{"message": err.Error()})
+		return
+	}
+
+	ctxReq, cancel := context.WithTimeout(ctx.Request.Context(), 30*time.Second)
+	defer cancel()
+
+	zoneList, err := client.Zones(ctxReq)
+	if err != nil {
+		pdnsError(ctx, err, "fetch zones")
+		return
+	}
+
+	ids := make([]string, 0, len(zoneList))
+	for _, z := range zoneList {
+		ids = append(ids, z.ID)
+	}
+
+	fetched, errs := client.FetchZones(ctxReq, ids, 4)
+
+	zones := make([]*models.Zone, 0, len(fetched))
+	zoneErrs := []zoneError{}
+	for i, err := range errs {
+		if err != nil {
+			zoneErrs = append(zoneErrs, zoneError{Zone: ids[i], Message: err.Error()})
+			continue
+		}
+		zones = append(zones, fetched[i])
+	}
+
+	ctx.JSON(200, gin.H{"ranges": models.BuildIpam(prefixes, zones, free), "errors": zoneErrs})
+}
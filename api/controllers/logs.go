@@ -1,68 +1,214 @@
 package controllers
 
 import (
-	"strconv"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/rafinhacuri/SanchezDNS/db"
+	"github.com/rafinhacuri/SanchezDNS/models"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// GetLogs lists audit log entries with structured filters (?from=&to=,
+// ?username=, ?action=, ?zone=, ?connection=), a literal ?filter= search
+// and ?sort=&order=. Pages are addressed with ?cursor=, taken from
+// nextCursor, or with ?page= for offset paging. The total is only counted
+// for the first request of a listing.
 func GetLogs(ctx *gin.Context) {
 	if isAdmin := ctx.GetBool("admin"); !isAdmin {
 		ctx.JSON(403, gin.H{"error": "forbidden"})
 		return
 	}
 
-	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(ctx.DefaultQuery("limit", "10"))
-	search, _ := ctx.GetQuery("filter")
+	q, err := models.ParseLogQuery(ctx.Request.URL.Query())
+	if err != nil {
+		ctx.JSON(400, gin.H{"message": err.Error()})
+		return
+	}
 
-	skip := (page - 1) * limit
+	response := gin.H{}
 
-	connectionID := ctx.Query("connection")
+	if q.Cursor == nil {
+		filter := q.Filter()
 
-	filter := bson.M{}
+		var total int64
+		if len(filter) == 0 {
+			total, err = db.Database.Collection("logs").EstimatedDocumentCount(ctx.Request.Context())
+		} else {
+			total, err = db.Database.Collection("logs").CountDocuments(ctx.Request.Context(), filter)
+		}
+		if err == nil {
+			response["total"] = total
+		}
+	}
+
+	opts := options.Find().
+		SetSort(q.SortSpec()).
+		SetLimit(int64(q.Limit))
+	if q.Page > 1 {
+		opts.SetSkip(int64((q.Page - 1) * q.Limit))
+	}
+
+	cursor, err := db.Database.Collection("logs").Find(ctx.Request.Context(), q.PageFilter(), opts)
+	if err != nil {
+		ctx.JSON(500, gin.H{"message": "failed to fetch logs"})
+		return
+	}
+
+	var raws []bson.Raw
+	if err := cursor.All(ctx.Request.Context(), &raws); err != nil {
+		ctx.JSON(500, gin.H{"message": "failed to parse logs"})
+		return
+	}
 
-	var orFilters []bson.M
-	if search != "" {
-		orFilters = []bson.M{
-			{"username": bson.M{"$regex": search, "$options": "i"}},
-			{"action": bson.M{"$regex": search, "$options": "i"}},
-			{"details": bson.M{"$regex": search, "$options": "i"}},
-			{"zone": bson.M{"$regex": search, "$options": "i"}},
-			{"hostServer": bson.M{"$regex": search, "$options": "i"}},
+	logs := make([]models.Log, 0, len(raws))
+	for _, raw := range raws {
+		var entry models.Log
+		if err := bson.Unmarshal(raw, &entry); err != nil {
+			ctx.JSON(500, gin.H{"message": "failed to parse logs"})
+			return
 		}
+		logs = append(logs, entry)
 	}
 
-	if len(orFilters) > 0 {
-		filter = bson.M{"$or": orFilters}
+	response["data"] = logs
+	if len(raws) == q.Limit {
+		if next, err := q.NextCursor(raws[len(raws)-1]); err == nil {
+			response["nextCursor"] = next
+		}
 	}
 
-	filter["idConnection"] = connectionID
+	ctx.JSON(200, response)
+}
+
+// ExportLogs streams the audit log as CSV or NDJSON, oldest first, for an
+// optional date range (?from=&to=) and set of connections (?connection=).
+// Entries are written as they are read from the cursor.
+func ExportLogs(ctx *gin.Context) {
+	if !ctx.GetBool("admin") {
+		ctx.JSON(403, gin.H{"error": "forbidden"})
+		return
+	}
 
-	total, _ := db.Database.Collection("logs").CountDocuments(ctx.Request.Context(), filter)
+	q, err := models.ParseLogExportQuery(ctx.Query("format"), ctx.Query("from"), ctx.Query("to"), ctx.QueryArray("connection"))
+	if err != nil {
+		ctx.JSON(400, gin.H{"message": err.Error()})
+		return
+	}
 
-	opts := options.Find().
-		SetSort(bson.M{"createdAt": -1}).
-		SetSkip(int64(skip)).
-		SetLimit(int64(limit))
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: 1}, {Key: "_id", Value: 1}}).SetBatchSize(500)
 
-	cursor, err := db.Database.Collection("logs").Find(ctx.Request.Context(), filter, opts)
+	cursor, err := db.Database.Collection("logs").Find(ctx.Request.Context(), q.Filter(), opts)
 	if err != nil {
 		ctx.JSON(500, gin.H{"message": "failed to fetch logs"})
 		return
 	}
+	defer cursor.Close(ctx.Request.Context())
 
-	var logs []bson.M
-	if err := cursor.All(ctx.Request.Context(), &logs); err != nil {
-		ctx.JSON(500, gin.H{"message": "failed to parse logs"})
+	filename := fmt.Sprintf("sanchezdns-logs-%s.%s", time.Now().UTC().Format("20060102-150405"), q.Format)
+	contentType := "application/x-ndjson"
+	if q.Format == "csv" {
+		contentType = "text/csv; charset=utf-8"
+	}
+
+	ctx.Header("Content-Type", contentType)
+	ctx.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	ctx.Status(200)
+
+	var (
+		csvw *csv.Writer
+		enc  *json.Encoder
+	)
+	if q.Format == "csv" {
+		csvw = csv.NewWriter(ctx.Writer)
+		_ = csvw.Write(models.LogCSVHeader)
+	} else {
+		enc = json.NewEncoder(ctx.Writer)
+	}
+
+	rows := 0
+	for cursor.Next(ctx.Request.Context()) {
+		var entry models.Log
+		if err := cursor.Decode(&entry); err != nil {
+			slog.Error("failed to decode log entry for export", "error", err)
+			return
+		}
+
+		if csvw != nil {
+			err = csvw.Write(entry.CSVRow())
+		} else {
+			err = enc.Encode(entry)
+		}
+		if err != nil {
+			return
+		}
+
+		if rows++; rows%500 == 0 {
+			if csvw != nil {
+				csvw.Flush()
+			}
+			ctx.Writer.Flush()
+		}
+	}
+
+	if err := cursor.Err(); err != nil {
+		slog.Error("log export interrupted", "error", err)
+	}
+
+	if csvw != nil {
+		csvw.Flush()
+	}
+	ctx.Writer.Flush()
+}
+
+// VerifyLogs walks the hash chains of the audit log, for one connection
+// (?connection=) or all of them, and reports missing or modified entries.
+// Entries written before chaining was introduced are only counted.
+func VerifyLogs(ctx *gin.Context) {
+	if !ctx.GetBool("admin") {
+		ctx.JSON(403, gin.H{"error": "forbidden"})
+		return
+	}
+
+	filter := bson.M{}
+	if connection, ok := ctx.GetQuery("connection"); ok {
+		filter["_id"] = connection
+	}
+
+	cursor, err := db.Database.Collection("log_chains").Find(ctx.Request.Context(), filter, options.Find().SetSort(bson.M{"_id": 1}))
+	if err != nil {
+		ctx.JSON(500, gin.H{"message": "failed to fetch log chains"})
 		return
 	}
 
-	ctx.JSON(200, gin.H{
-		"data":  logs,
-		"total": total,
-	})
+	var heads []models.LogChain
+	if err := cursor.All(ctx.Request.Context(), &heads); err != nil {
+		ctx.JSON(500, gin.H{"message": "failed to parse log chains"})
+		return
+	}
+
+	valid := true
+	reports := []*models.LogChainReport{}
+	for _, head := range heads {
+		report, err := models.VerifyLogChain(ctx.Request.Context(), head)
+		if err != nil {
+			ctx.JSON(500, gin.H{"message": fmt.Sprintf("failed to verify chain of %q: %v", head.ID, err)})
+			return
+		}
+		valid = valid && report.Valid
+		reports = append(reports, report)
+	}
+
+	unchainedFilter := bson.M{"seq": bson.M{"$exists": false}}
+	if connection, ok := filter["_id"]; ok {
+		unchainedFilter["idConnection"] = connection
+	}
+	unchained, _ := db.Database.Collection("logs").CountDocuments(ctx.Request.Context(), unchainedFilter)
+
+	ctx.JSON(200, gin.H{"valid": valid, "chains": reports, "unchained": unchained})
 }
@@ -0,0 +1,98 @@
+package controllers
+
+import (
+	"context"
+	"crypto/subtle"
+	"log/slog"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rafinhacuri/SanchezDNS/db"
+	"github.com/rafinhacuri/SanchezDNS/metrics"
+	"github.com/rafinhacuri/SanchezDNS/models"
+	"github.com/rafinhacuri/SanchezDNS/pdns"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Metrics serves the Prometheus metrics. When METRICS_TOKEN is set the
+// scraper must send it as a bearer token.
+func Metrics(ctx *gin.Context) {
+	if token := os.Getenv("METRICS_TOKEN"); token != "" {
+		if subtle.ConstantTimeCompare([]byte(ctx.GetHeader("Authorization")), []byte("Bearer "+token)) != 1 {
+			ctx.AbortWithStatusJSON(401, gin.H{"message": "Unauthorized"})
+			return
+		}
+	}
+
+	ctxReq, cancel := context.WithTimeout(ctx.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	metrics.SetMongoUp(db.TestMongo(ctxReq) == nil)
+
+	metrics.Handler().ServeHTTP(ctx.Writer, ctx.Request)
+}
+
+// CollectPdnsStats fetches the statistics of every connection, the same
+// values GetStatistics summarizes, for the metrics collector.
+func CollectPdnsStats(ctx context.Context) []metrics.PdnsStats {
+	cursor, err := db.Database.Collection("connections").Find(ctx, bson.M{})
+	if err != nil {
+		slog.Error("failed to fetch connections", "error", err)
+		return nil
+	}
+
+	var connections []models.Connection
+	if err := cursor.All(ctx, &connections); err != nil {
+		slog.Error("failed to parse connections", "error", err)
+		return nil
+	}
+
+	results := make([]metrics.PdnsStats, len(connections))
+
+	var wg sync.WaitGroup
+	for i := range connections {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			connection := &connections[i]
+			results[i] = metrics.PdnsStats{ID: connection.ID.Hex(), Name: connection.Name, Values: map[string]float64{}}
+
+			client, err := pdns.New(connection)
+			if err != nil {
+				return
+			}
+			stats, err := client.Statistics(ctx)
+			if err != nil {
+				return
+			}
+
+			results[i].Up = true
+			for _, stat := range stats {
+				if stat.Type != "" && stat.Type != "StatisticItem" {
+					continue
+				}
+				if value, ok := statValue(stat.Value); ok {
+					results[i].Values[stat.Name] = value
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	return results
+}
+
+func statValue(v any) (float64, bool) {
+	switch t := v.(type) {
+	case float64:
+		return t, true
+	case string:
+		f, err := strconv.ParseFloat(t, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
@@ -0,0 +1,201 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rafinhacuri/SanchezDNS/db"
+	"github.com/rafinhacuri/SanchezDNS/models"
+	"github.com/rafinhacuri/SanchezDNS/utils"
+	"github.com/rafinhacuri/SanchezDNS/workers"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func findNotificationChannel(ctx context.Context, channelID string) (*models.NotificationChannel, error) {
+	id, err := primitive.ObjectIDFromHex(channelID)
+	if err != nil {
+		return nil, err
+	}
+
+	var channel models.NotificationChannel
+	if err := db.Database.Collection("notification_channels").FindOne(ctx, bson.M{"_id": id}).Decode(&channel); err != nil {
+		return nil, err
+	}
+
+	return &channel, nil
+}
+
+func GetNotificationChannels(ctx *gin.Context) {
+	if !ctx.GetBool("admin") {
+		ctx.JSON(403, gin.H{"error": "forbidden"})
+		return
+	}
+
+	listByName[models.NotificationChannel](ctx, "notification_channels", "notification channel")
+}
+
+func InsertNotificationChannel(ctx *gin.Context) {
+	if !ctx.GetBool("admin") {
+		ctx.JSON(403, gin.H{"error": "forbidden"})
+		return
+	}
+
+	var req models.NotificationChannelRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(400, gin.H{"message": fmt.Sprintf("invalid request body: %v", err)})
+		return
+	}
+
+	if err := req.Validate(true); err != nil {
+		ctx.JSON(400, gin.H{"message": fmt.Sprintf("validation error: %v", err)})
+		return
+	}
+
+	encrypted, err := utils.Encrypt(req.URL)
+	if err != nil {
+		ctx.JSON(500, gin.H{"message": "failed to encrypt url"})
+		return
+	}
+
+	username := ctx.GetString("username")
+	now := time.Now()
+
+	channel := models.NotificationChannel{
+		Name:        req.Name,
+		Platform:    req.Platform,
+		URL:         encrypted,
+		Connections: req.Connections,
+		Zones:       req.Zones,
+		Actions:     req.Actions,
+		Enabled:     req.Enabled == nil || *req.Enabled,
+		CreatedBy:   username,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	ctxReq, cancel := context.WithTimeout(ctx.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	result, err := db.Database.Collection("notification_channels").InsertOne(ctxReq, channel)
+	if err != nil {
+		ctx.JSON(500, gin.H{"message": err.Error()})
+		return
+	}
+
+	logAdminAction(ctxReq, username, "create_notification_channel", fmt.Sprintf("User %s created %s notification channel %s", username, channel.Platform, channel.Name))
+
+	ctx.JSON(201, gin.H{"message": "notification channel created successfully", "id": result.InsertedID})
+}
+
+// EditNotificationChannel replaces the settings of a channel. The webhook
+// URL is kept unless a new one is given.
+func EditNotificationChannel(ctx *gin.Context) {
+	if !ctx.GetBool("admin") {
+		ctx.JSON(403, gin.H{"error": "forbidden"})
+		return
+	}
+
+	channel, err := findNotificationChannel(ctx.Request.Context(), ctx.Query("id"))
+	if err != nil {
+		ctx.JSON(404, gin.H{"message": "notification channel not found"})
+		return
+	}
+
+	var req models.NotificationChannelRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(400, gin.H{"message": fmt.Sprintf("invalid request body: %v", err)})
+		return
+	}
+
+	if err := req.Validate(false); err != nil {
+		ctx.JSON(400, gin.H{"message": fmt.Sprintf("validation error: %v", err)})
+		return
+	}
+
+	update := bson.M{
+		"name":        req.Name,
+		"platform":    req.Platform,
+		"connections": req.Connections,
+		"zones":       req.Zones,
+		"actions":     req.Actions,
+		"enabled":     req.Enabled == nil || *req.Enabled,
+		"updatedAt":   time.Now(),
+	}
+
+	if req.URL != "" {
+		encrypted, err := utils.Encrypt(req.URL)
+		if err != nil {
+			ctx.JSON(500, gin.H{"message": "failed to encrypt url"})
+			return
+		}
+		update["url"] = encrypted
+	}
+
+	ctxReq, cancel := context.WithTimeout(ctx.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	if _, err := db.Database.Collection("notification_channels").UpdateOne(ctxReq, bson.M{"_id": channel.ID}, bson.M{"$set": update}); err != nil {
+		ctx.JSON(500, gin.H{"message": err.Error()})
+		return
+	}
+
+	username := ctx.GetString("username")
+	logAdminAction(ctxReq, username, "edit_notification_channel", fmt.Sprintf("User %s edited notification channel %s", username, req.Name))
+
+	ctx.JSON(200, gin.H{"message": "notification channel updated successfully"})
+}
+
+func DeleteNotificationChannel(ctx *gin.Context) {
+	if !ctx.GetBool("admin") {
+		ctx.JSON(403, gin.H{"error": "forbidden"})
+		return
+	}
+
+	channel, ok := deleteByID[models.NotificationChannel](ctx, "notification_channels", "notification channel")
+	if !ok {
+		return
+	}
+
+	username := ctx.GetString("username")
+	logAdminAction(ctx.Request.Context(), username, "delete_notification_channel", fmt.Sprintf("User %s deleted notification channel %s", username, channel.Name))
+
+	ctx.JSON(200, gin.H{"message": "notification channel deleted successfully"})
+}
+
+// TestNotificationChannel posts a sample change summary to a channel.
+func TestNotificationChannel(ctx *gin.Context) {
+	if !ctx.GetBool("admin") {
+		ctx.JSON(403, gin.H{"error": "forbidden"})
+		return
+	}
+
+	ctxReq, cancel := context.WithTimeout(ctx.Request.Context(), 30*time.Second)
+	defer cancel()
+
+	channel, err := findNotificationChannel(ctxReq, ctx.Query("id"))
+	if err != nil {
+		ctx.JSON(404, gin.H{"message": "notification channel not found"})
+		return
+	}
+
+	username := ctx.GetString("username")
+	sample := &models.Log{
+		Username: username,
+		Action:   "test",
+		Zone:     "example.com.",
+		Details:  fmt.Sprintf("Test notification requested by %s", username),
+		Changes: []models.RRSetChange{
+			{Action: "update", Name: "www.example.com.", Type: "A", TTL: 300, Before: []string{"192.0.2.10"}, After: []string{"192.0.2.20"}},
+		},
+	}
+
+	if err := workers.SendNotification(ctxReq, channel, models.SummarizeLog(sample, "SanchezDNS")); err != nil {
+		ctx.JSON(502, gin.H{"message": fmt.Sprintf("test notification failed: %v", err)})
+		return
+	}
+
+	ctx.JSON(200, gin.H{"message": "test notification sent"})
+}
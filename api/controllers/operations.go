@@ -0,0 +1,115 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rafinhacuri/SanchezDNS/models"
+	"github.com/rafinhacuri/SanchezDNS/pdns"
+)
+
+func NotifyZone(ctx *gin.Context) {
+	zoneOperation(ctx, "notify", "notify_zone", "Sent NOTIFY for zone %s")
+}
+
+func RetrieveZone(ctx *gin.Context) {
+	zoneOperation(ctx, "axfr-retrieve", "axfr_retrieve_zone", "Requested AXFR retrieval for zone %s")
+}
+
+func RectifyZone(ctx *gin.Context) {
+	zoneOperation(ctx, "rectify", "rectify_zone", "Rectified zone %s")
+}
+
+func zoneOperation(ctx *gin.Context, operation, action, details string) {
+	allowed, connection := permission(ctx)
+	if !allowed {
+		return
+	}
+
+	zoneID := ctx.Query("zone")
+	if zoneID == "" {
+		ctx.JSON(400, gin.H{"message": "zone ID is required"})
+		return
+	}
+
+	client, err := pdns.New(connection)
+	if err != nil {
+		ctx.JSON(500, gin.H{"message": err.Error()})
+		return
+	}
+
+	ctxReq, cancel := context.WithTimeout(ctx.Request.Context(), 8*time.Second)
+	defer cancel()
+
+	result, err := client.ZoneOperation(ctxReq, zoneID, operation)
+	if err != nil {
+		pdnsError(ctx, err, operation+" zone")
+		return
+	}
+
+	log := &models.Log{
+		Username:     ctx.GetString("username"),
+		IdConnection: ctx.Query("connection"),
+		Action:       action,
+		Details:      fmt.Sprintf(details, zoneID) + ": " + result,
+		Zone:         zoneID,
+		HostServer:   connection.Host,
+		CreatedAt:    time.Now(),
+	}
+
+	if err := log.Insert(ctx.Request.Context()); err != nil {
+		ctx.JSON(500, gin.H{"message": fmt.Sprintf("failed to log %s: %v", operation, err)})
+		return
+	}
+
+	ctx.JSON(200, gin.H{"message": fmt.Sprintf("%s completed successfully", operation), "zone": zoneID, "operation": operation, "result": result})
+}
+
+func CheckZone(ctx *gin.Context) {
+	allowed, connection := permission(ctx)
+	if !allowed {
+		return
+	}
+
+	zoneID := ctx.Query("zone")
+	if zoneID == "" {
+		ctx.JSON(400, gin.H{"message": "zone ID is required"})
+		return
+	}
+
+	client, err := pdns.New(connection)
+	if err != nil {
+		ctx.JSON(500, gin.H{"message": err.Error()})
+		return
+	}
+
+	ctxReq, cancel := context.WithTimeout(ctx.Request.Context(), 8*time.Second)
+	defer cancel()
+
+	zone, err := client.Zone(ctxReq, zoneID)
+	if err != nil {
+		pdnsError(ctx, err, "fetch zone")
+		return
+	}
+
+	result := zone.Check()
+
+	log := &models.Log{
+		Username:     ctx.GetString("username"),
+		IdConnection: ctx.Query("connection"),
+		Action:       "check_zone",
+		Details:      fmt.Sprintf("Checked zone %s: %d errors, %d warnings", zoneID, result.Errors, result.Warnings),
+		Zone:         zoneID,
+		HostServer:   connection.Host,
+		CreatedAt:    time.Now(),
+	}
+
+	if err := log.Insert(ctx.Request.Context()); err != nil {
+		ctx.JSON(500, gin.H{"message": fmt.Sprintf("failed to log zone check: %v", err)})
+		return
+	}
+
+	ctx.JSON(200, result)
+}
@@ -0,0 +1,19 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rafinhacuri/SanchezDNS/pdns"
+)
+
+func pdnsError(ctx *gin.Context, err error, action string) {
+	var perr *pdns.Error
+	if errors.As(err, &perr) {
+		ctx.JSON(perr.StatusCode, gin.H{"message": fmt.Sprintf("PowerDNS error: %v", perr.Body)})
+		return
+	}
+
+	ctx.JSON(502, gin.H{"message": fmt.Sprintf("failed to %s: %v", action, err)})
+}
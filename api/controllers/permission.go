@@ -11,8 +11,10 @@ import (
 )
 
 func permission(ctx *gin.Context) (bool, *models.Connection) {
-	primitiveId := ctx.Query("connection")
+	return permissionFor(ctx, ctx.Query("connection"))
+}
 
+func permissionFor(ctx *gin.Context, primitiveId string) (bool, *models.Connection) {
 	username := ctx.GetString("username")
 	isAdmin := ctx.GetBool("admin")
 
@@ -0,0 +1,261 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rafinhacuri/SanchezDNS/models"
+	"github.com/rafinhacuri/SanchezDNS/pdns"
+	"github.com/rafinhacuri/SanchezDNS/workers"
+)
+
+type ptrResult struct {
+	Address string `json:"address"`
+	Action  string `json:"action"`
+	Zone    string `json:"zone,omitempty"`
+	Name    string `json:"name,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// syncPtr keeps the reverse record of an A/AAAA record in step with it.
+// oldIP is the address the name pointed at before (empty on insert) and
+// newIP the one it points at now (empty on delete). It returns nil unless
+// the request asked for PTR handling.
+func syncPtr(ctx *gin.Context, connection *models.Connection, req *models.AddRecordRequest, name, oldIP, newIP string) []ptrResult {
+	if !req.CreatePtr || (req.Type != "A" && req.Type != "AAAA") {
+		return nil
+	}
+
+	results := []ptrResult{}
+
+	client, err := pdns.New(connection)
+	if err != nil {
+		return append(results, ptrResult{Action: "failed", Message: err.Error()})
+	}
+
+	ctxReq, cancel := context.WithTimeout(ctx.Request.Context(), 8*time.Second)
+	defer cancel()
+
+	zones, err := client.Zones(ctxReq)
+	if err != nil {
+		return append(results, ptrResult{Action: "failed", Message: fmt.Sprintf("failed to list zones: %v", err)})
+	}
+	reverse := models.ReverseZones(zones)
+	username := ctx.GetString("username")
+
+	if oldIP != "" && oldIP != newIP {
+		results = append(results, updatePtr(ctxReq, client, connection, reverse, oldIP, name, 0, true, username))
+	}
+	if newIP != "" {
+		ttl := req.TTL
+		if ttl <= 0 {
+			ttl = 3600
+		}
+		results = append(results, updatePtr(ctxReq, client, connection, reverse, newIP, name, ttl, false, username))
+	}
+
+	return results
+}
+
+// updatePtr adds target to the PTR rrset of ip, or removes it from there.
+// Other PTR records at the same name are left in place.
+func updatePtr(ctx context.Context, client *pdns.Client, connection *models.Connection, reverse []models.PdnsZone, ip, target string, ttl int, remove bool, username string) ptrResult {
+	result := ptrResult{Address: ip}
+
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		result.Action, result.Message = "skipped", fmt.Sprintf("invalid address %q", ip)
+		return result
+	}
+
+	zone, name, ok := models.ReverseLocation(addr.Unmap(), reverse)
+	if !ok {
+		result.Action, result.Message = "skipped", "no reverse zone found on this connection"
+		return result
+	}
+	result.Zone, result.Name = zone.Name, name
+
+	zoneData, err := client.Zone(ctx, zone.ID)
+	if err != nil {
+		result.Action, result.Message = "failed", err.Error()
+		return result
+	}
+
+	target = strings.ToLower(models.Fqdn(target))
+	rr := zoneData.RRSet(name, "PTR")
+
+	var records []models.Record
+	if rr != nil {
+		records = rr.Records
+	}
+	found := slices.ContainsFunc(records, func(rec models.Record) bool {
+		return strings.EqualFold(models.Fqdn(rec.Content), target)
+	})
+
+	updated := models.RRSet{Name: name, Type: "PTR", TTL: ttl}
+	if rr != nil {
+		updated.Comments = rr.Comments
+		if remove {
+			updated.TTL = rr.TTL
+		}
+	}
+
+	var patch map[string]any
+	switch {
+	case remove != found:
+		result.Action = "unchanged"
+		return result
+	case remove:
+		updated.Records = slices.DeleteFunc(slices.Clone(records), func(rec models.Record) bool {
+			return strings.EqualFold(models.Fqdn(rec.Content), target)
+		})
+		result.Action = "removed"
+		if len(updated.Records) == 0 {
+			patch = map[string]any{"name": name, "type": "PTR", "changetype": "DELETE"}
+		}
+	default:
+		updated.Records = append(slices.Clone(records), models.Record{Content: target})
+		if len(updated.Comments) == 0 {
+			updated.Comments = []models.Comment{{Content: "Added via SanchezDNS", Account: username}}
+		}
+		result.Action = "created"
+		if rr != nil {
+			result.Action = "updated"
+		}
+	}
+
+	if patch == nil {
+		patch = updated.Patch()
+	}
+
+	if err := client.PatchZone(ctx, zone.ID, []map[string]any{patch}); err != nil {
+		result.Action, result.Message = "failed", err.Error()
+		return result
+	}
+
+	workers.RecordZoneState(connection, zone.ID, username)
+
+	return result
+}
+
+// PtrReport lists the A/AAAA records of a connection whose PTR is missing
+// or points elsewhere. With zone set only that forward zone is checked.
+func PtrReport(ctx *gin.Context) {
+	allowed, connection := permission(ctx)
+	if !allowed {
+		return
+	}
+
+	client, err := pdns.New(connection)
+	if err != nil {
+		ctx.JSON(500, gin.H{"message": err.Error()})
+		return
+	}
+
+	ctxReq, cancel := context.WithTimeout(ctx.Request.Context(), 30*time.Second)
+	defer cancel()
+
+	zones, err := client.Zones(ctxReq)
+	if err != nil {
+		pdnsError(ctx, err, "fetch zones")
+		return
+	}
+
+	reverse := models.ReverseZones(zones)
+	only := ctx.Query("zone")
+
+	var ids []string
+	for _, z := range zones {
+		if models.IsReverseZone(z.Name) {
+			ids = append(ids, z.ID)
+			continue
+		}
+		if only == "" || z.ID == only || strings.EqualFold(z.Name, models.Fqdn(only)) {
+			ids = append(ids, z.ID)
+		}
+	}
+
+	fetched, errs := client.FetchZones(ctxReq, ids, 4)
+
+	byID := map[string]*models.Zone{}
+	zoneErrs := []zoneError{}
+	for i, id := range ids {
+		if errs[i] != nil {
+			zoneErrs = append(zoneErrs, zoneError{Zone: id, Message: errs[i].Error()})
+			continue
+		}
+		byID[id] = fetched[i]
+	}
+
+	issues := []models.PtrIssue{}
+	checked := 0
+
+	for _, z := range zones {
+		forward := byID[z.ID]
+		if forward == nil || models.IsReverseZone(z.Name) {
+			continue
+		}
+
+		for _, rr := range forward.RRSets {
+			if rr.Type != "A" && rr.Type != "AAAA" {
+				continue
+			}
+			for _, rec := range rr.Records {
+				checked++
+				issue := models.PtrIssue{Zone: forward.Name, Name: rr.Name, Type: rr.Type, Address: rec.Content}
+
+				addr, err := netip.ParseAddr(rec.Content)
+				if err != nil {
+					issue.Status = "invalid_address"
+					issues = append(issues, issue)
+					continue
+				}
+
+				rz, name, ok := models.ReverseLocation(addr.Unmap(), reverse)
+				if !ok {
+					issue.Status = "missing_zone"
+					issue.ReverseName = models.ReverseName(addr.Unmap())
+					issues = append(issues, issue)
+					continue
+				}
+				issue.ReverseZone, issue.ReverseName = rz.Name, name
+
+				reverseZone := byID[rz.ID]
+				if reverseZone == nil {
+					// Listed in errors; the PTR cannot be checked.
+					checked--
+					continue
+				}
+
+				ptr := reverseZone.RRSet(name, "PTR")
+				if ptr == nil {
+					issue.Status = "missing"
+					issues = append(issues, issue)
+					continue
+				}
+
+				for _, p := range ptr.Records {
+					issue.Ptr = append(issue.Ptr, p.Content)
+				}
+				if !slices.ContainsFunc(issue.Ptr, func(p string) bool { return strings.EqualFold(models.Fqdn(p), rr.Name) }) {
+					issue.Status = "mismatch"
+					issues = append(issues, issue)
+				}
+			}
+		}
+	}
+
+	ctx.JSON(200, gin.H{"checked": checked, "issues": issues, "errors": zoneErrs})
+}
+
+// zoneError reports a zone left out of a multi-zone response because it
+// could not be fetched.
+type zoneError struct {
+	Zone    string `json:"zone"`
+	Message string `json:"message"`
+}
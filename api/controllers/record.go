@@ -4,15 +4,16 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"log/slog"
 	"sort"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/go-resty/resty/v2"
 	"github.com/rafinhacuri/SanchezDNS/models"
-	"github.com/rafinhacuri/SanchezDNS/utils"
+	"github.com/rafinhacuri/SanchezDNS/pdns"
+	"github.com/rafinhacuri/SanchezDNS/workers"
 )
 
 func GetRecords(ctx *gin.Context) {
@@ -27,20 +28,16 @@ func GetRecords(ctx *gin.Context) {
 		return
 	}
 
-	plainKey, err := utils.Decrypt(connection.ApiKey)
+	client, err := pdns.New(connection)
 	if err != nil {
-		ctx.JSON(500, gin.H{"message": fmt.Sprintf("failed to decrypt api key: %v", err)})
+		ctx.JSON(500, gin.H{"message": err.Error()})
 		return
 	}
 
 	ctxReq, cancel := context.WithTimeout(ctx.Request.Context(), 8*time.Second)
 	defer cancel()
 
-	base := utils.NormalizeBase(connection.Host)
-
-	httpc := resty.New().SetBaseURL(base).SetHeader("X-API-Key", plainKey).SetHeader("Accept", "application/json").SetTimeout(6 * time.Second).SetRetryCount(2)
-
-	resp, err := httpc.R().SetContext(ctxReq).Get(fmt.Sprintf("/api/v1/servers/%s/zones/%s", connection.ServerId, zoneID))
+	resp, err := client.R(ctxReq).Get(client.Path("/zones/%s", zoneID))
 
 	if err != nil {
 		ctx.JSON(502, gin.H{"message": fmt.Sprintf("failed to fetch records: %v", err)})
@@ -286,19 +283,15 @@ func InsertRecord(ctx *gin.Context) {
 		request.Comment = "Added via SanchezDNS"
 	}
 
-	plainKey, err := utils.Decrypt(connection.ApiKey)
+	client, err := pdns.New(connection)
 	if err != nil {
-		ctx.JSON(500, gin.H{"message": fmt.Sprintf("failed to decrypt api key: %v", err)})
+		ctx.JSON(500, gin.H{"message": err.Error()})
 		return
 	}
 
 	ctxReq, cancel := context.WithTimeout(ctx.Request.Context(), 8*time.Second)
 	defer cancel()
 
-	base := utils.NormalizeBase(connection.Host)
-
-	httpc := resty.New().SetBaseURL(base).SetHeader("X-API-Key", plainKey).SetHeader("Accept", "application/json").SetTimeout(6 * time.Second).SetRetryCount(2)
-
 	if !(request.Type == "HTTPS" || request.Type == "SRV" || (request.VL != "" && strings.TrimSpace(request.VL) != "")) {
 		ctx.JSON(400, gin.H{"message": "Value is required for this record type"})
 		return
@@ -321,7 +314,7 @@ func InsertRecord(ctx *gin.Context) {
 		}
 	}
 
-	getResp, err := httpc.R().SetContext(ctxReq).Get(fmt.Sprintf("/api/v1/servers/%s/zones/%s", connection.ServerId, request.Zone))
+	getResp, err := client.R(ctxReq).Get(client.Path("/zones/%s", request.Zone))
 	if err != nil {
 		ctx.JSON(502, gin.H{"message": fmt.Sprintf("failed to fetch existing records: %v", err)})
 		return
@@ -413,7 +406,7 @@ func InsertRecord(ctx *gin.Context) {
 		},
 	}
 
-	resp, err := httpc.R().SetContext(ctxReq).SetBody(map[string]any{
+	resp, err := client.R(ctxReq).SetBody(map[string]any{
 		"rrsets": []map[string]any{
 			{
 				"name":       name,
@@ -424,7 +417,7 @@ func InsertRecord(ctx *gin.Context) {
 				"comments":   mergedComments,
 			},
 		},
-	}).Patch(fmt.Sprintf("/api/v1/servers/%s/zones/%s", connection.ServerId, request.Zone))
+	}).Patch(client.Path("/zones/%s", request.Zone))
 
 	if err != nil {
 		ctx.JSON(502, gin.H{"message": fmt.Sprintf("failed to insert record: %v", err)})
@@ -436,7 +429,16 @@ func InsertRecord(ctx *gin.Context) {
 		return
 	}
 
-	ctx.JSON(201, gin.H{"message": "record inserted successfully"})
+	workers.RecordZoneState(connection, request.Zone, ctx.GetString("username"))
+	logRecordChange(ctx, connection, "create_record", request.Zone, fmt.Sprintf("Added %s %s %s", name, request.Type, request.VL),
+		models.RRSetChange{Action: "create", Name: name, Type: request.Type, TTL: request.TTL, After: []string{request.VL}})
+
+	response := gin.H{"message": "record inserted successfully"}
+	if ptr := syncPtr(ctx, connection, &request, name, "", request.VL); ptr != nil {
+		response["ptr"] = ptr
+	}
+
+	ctx.JSON(201, response)
 }
 
 func DeleteRecord(ctx *gin.Context) {
@@ -451,22 +453,15 @@ func DeleteRecord(ctx *gin.Context) {
 		return
 	}
 
-	plainKey, err := utils.Decrypt(connection.ApiKey)
+	client, err := pdns.New(connection)
 	if err != nil {
-		ctx.JSON(500, gin.H{"message": fmt.Sprintf("failed to decrypt api key: %v", err)})
+		ctx.JSON(500, gin.H{"message": err.Error()})
 		return
 	}
 
 	ctxReq, cancel := context.WithTimeout(ctx.Request.Context(), 8*time.Second)
 	defer cancel()
 
-	base := utils.NormalizeBase(connection.Host)
-	httpc := resty.New().SetBaseURL(base).
-		SetHeader("X-API-Key", plainKey).
-		SetHeader("Accept", "application/json").
-		SetTimeout(6 * time.Second).
-		SetRetryCount(2)
-
 	normalizeRecordValue(&request)
 
 	zone := strings.TrimSuffix(request.Zone, ".")
@@ -479,8 +474,8 @@ func DeleteRecord(ctx *gin.Context) {
 		}
 	}
 
-	getResp, err := httpc.R().SetContext(ctxReq).
-		Get(fmt.Sprintf("/api/v1/servers/%s/zones/%s", connection.ServerId, request.Zone))
+	getResp, err := client.R(ctxReq).
+		Get(client.Path("/zones/%s", request.Zone))
 	if err != nil {
 		ctx.JSON(502, gin.H{"message": fmt.Sprintf("failed to fetch existing records: %v", err)})
 		return
@@ -520,7 +515,7 @@ func DeleteRecord(ctx *gin.Context) {
 	}
 
 	if len(remainingRecords) == 0 {
-		resp, err := httpc.R().SetContext(ctxReq).
+		resp, err := client.R(ctxReq).
 			SetBody(map[string]any{
 				"rrsets": []map[string]any{
 					{
@@ -529,7 +524,7 @@ func DeleteRecord(ctx *gin.Context) {
 						"changetype": "DELETE",
 					},
 				},
-			}).Patch(fmt.Sprintf("/api/v1/servers/%s/zones/%s", connection.ServerId, request.Zone))
+			}).Patch(client.Path("/zones/%s", request.Zone))
 
 		if err != nil {
 			ctx.JSON(502, gin.H{"message": fmt.Sprintf("failed to delete record: %v", err)})
@@ -541,7 +536,16 @@ func DeleteRecord(ctx *gin.Context) {
 			return
 		}
 
-		ctx.JSON(200, gin.H{"message": "record deleted successfully"})
+		workers.RecordZoneState(connection, request.Zone, ctx.GetString("username"))
+		logRecordChange(ctx, connection, "delete_record", request.Zone, fmt.Sprintf("Deleted %s %s %s", name, request.Type, request.VL),
+			models.RRSetChange{Action: "delete", Name: name, Type: request.Type, TTL: request.TTL, Before: []string{request.VL}})
+
+		response := gin.H{"message": "record deleted successfully"}
+		if ptr := syncPtr(ctx, connection, &request, name, request.VL, ""); ptr != nil {
+			response["ptr"] = ptr
+		}
+
+		ctx.JSON(200, response)
 		return
 	}
 
@@ -564,7 +568,7 @@ func DeleteRecord(ctx *gin.Context) {
 		},
 	}
 
-	resp, err := httpc.R().SetContext(ctxReq).
+	resp, err := client.R(ctxReq).
 		SetBody(map[string]any{
 			"rrsets": []map[string]any{
 				{
@@ -576,7 +580,7 @@ func DeleteRecord(ctx *gin.Context) {
 					"comments":   mergedComments,
 				},
 			},
-		}).Patch(fmt.Sprintf("/api/v1/servers/%s/zones/%s", connection.ServerId, request.Zone))
+		}).Patch(client.Path("/zones/%s", request.Zone))
 
 	if err != nil {
 		ctx.JSON(502, gin.H{"message": fmt.Sprintf("failed to delete record: %v", err)})
@@ -588,7 +592,16 @@ func DeleteRecord(ctx *gin.Context) {
 		return
 	}
 
-	ctx.JSON(200, gin.H{"message": "record deleted successfully"})
+	workers.RecordZoneState(connection, request.Zone, ctx.GetString("username"))
+	logRecordChange(ctx, connection, "delete_record", request.Zone, fmt.Sprintf("Deleted %s %s %s", name, request.Type, request.VL),
+		models.RRSetChange{Action: "delete", Name: name, Type: request.Type, TTL: request.TTL, Before: []string{request.VL}})
+
+	response := gin.H{"message": "record deleted successfully"}
+	if ptr := syncPtr(ctx, connection, &request, name, request.VL, ""); ptr != nil {
+		response["ptr"] = ptr
+	}
+
+	ctx.JSON(200, response)
 }
 
 func EditRecord(ctx *gin.Context) {
@@ -613,19 +626,15 @@ func EditRecord(ctx *gin.Context) {
 		return
 	}
 
-	plainKey, err := utils.Decrypt(connection.ApiKey)
+	client, err := pdns.New(connection)
 	if err != nil {
-		ctx.JSON(500, gin.H{"message": fmt.Sprintf("failed to decrypt api key: %v", err)})
+		ctx.JSON(500, gin.H{"message": err.Error()})
 		return
 	}
 
 	ctxReq, cancel := context.WithTimeout(ctx.Request.Context(), 8*time.Second)
 	defer cancel()
 
-	base := utils.NormalizeBase(connection.Host)
-
-	httpc := resty.New().SetBaseURL(base).SetHeader("X-API-Key", plainKey).SetHeader("Accept", "application/json").SetTimeout(6 * time.Second).SetRetryCount(2)
-
 	normalizeRecordValue(&request.NewValue)
 	normalizeRecordValue(&request.OldValue)
 
@@ -640,7 +649,7 @@ func EditRecord(ctx *gin.Context) {
 		}
 	}
 
-	getResp, err := httpc.R().SetContext(ctxReq).Get(fmt.Sprintf("/api/v1/servers/%s/zones/%s", connection.ServerId, request.NewValue.Zone))
+	getResp, err := client.R(ctxReq).Get(client.Path("/zones/%s", request.NewValue.Zone))
 	if err != nil {
 		ctx.JSON(502, gin.H{"message": fmt.Sprintf("failed to fetch existing records: %v", err)})
 		return
@@ -704,7 +713,7 @@ func EditRecord(ctx *gin.Context) {
 		},
 	}
 
-	resp, err := httpc.R().SetContext(ctxReq).
+	resp, err := client.R(ctxReq).
 		SetBody(map[string]any{
 			"rrsets": []map[string]any{
 				{
@@ -716,7 +725,7 @@ func EditRecord(ctx *gin.Context) {
 					"comments":   mergedComments,
 				},
 			},
-		}).Patch(fmt.Sprintf("/api/v1/servers/%s/zones/%s", connection.ServerId, request.NewValue.Zone))
+		}).Patch(client.Path("/zones/%s", request.NewValue.Zone))
 
 	if err != nil {
 		ctx.JSON(502, gin.H{"message": fmt.Sprintf("failed to edit record: %v", err)})
@@ -728,5 +737,33 @@ func EditRecord(ctx *gin.Context) {
 		return
 	}
 
-	ctx.JSON(200, gin.H{"message": "record edited successfully"})
+	workers.RecordZoneState(connection, request.NewValue.Zone, ctx.GetString("username"))
+	logRecordChange(ctx, connection, "edit_record", request.NewValue.Zone, fmt.Sprintf("Changed %s %s from %s to %s", name, request.NewValue.Type, request.OldValue.VL, request.NewValue.VL),
+		models.RRSetChange{Action: "update", Name: name, Type: request.NewValue.Type, TTL: request.NewValue.TTL, Before: []string{request.OldValue.VL}, After: []string{request.NewValue.VL}})
+
+	response := gin.H{"message": "record edited successfully"}
+	if ptr := syncPtr(ctx, connection, &request.NewValue, name, request.OldValue.VL, request.NewValue.VL); ptr != nil {
+		response["ptr"] = ptr
+	}
+
+	ctx.JSON(200, response)
+}
+
+// logRecordChange logs a single record change. The change lists only the
+// record that was touched, not the whole rrset.
+func logRecordChange(ctx *gin.Context, connection *models.Connection, action, zone, details string, change models.RRSetChange) {
+	log := &models.Log{
+		Username:     ctx.GetString("username"),
+		IdConnection: connection.ID.Hex(),
+		Action:       action,
+		Details:      details,
+		Zone:         zone,
+		HostServer:   connection.Host,
+		Changes:      []models.RRSetChange{change},
+		CreatedAt:    time.Now(),
+	}
+
+	if err := log.Insert(ctx.Request.Context()); err != nil {
+		slog.Error("failed to log record change", "zone", zone, "action", action, "error", err)
+	}
 }
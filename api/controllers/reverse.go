@@ -0,0 +1,196 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rafinhacuri/SanchezDNS/models"
+	"github.com/rafinhacuri/SanchezDNS/pdns"
+	"github.com/rafinhacuri/SanchezDNS/workers"
+)
+
+// CreateReverseZones creates the in-addr.arpa or ip6.arpa zones covering a
+// prefix. With dryRun=true only the zones that would be created are listed.
+func CreateReverseZones(ctx *gin.Context) {
+	allowed, connection := permission(ctx)
+	if !allowed {
+		return
+	}
+
+	var req models.ReverseZoneRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(400, gin.H{"message": fmt.Sprintf("invalid request body: %v", err)})
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		ctx.JSON(400, gin.H{"message": fmt.Sprintf("validation error: %v", err)})
+		return
+	}
+
+	zones, err := req.Zones()
+	if err != nil {
+		ctx.JSON(400, gin.H{"message": fmt.Sprintf("validation error: %v", err)})
+		return
+	}
+
+	dryRun := ctx.Query("dryRun") == "true"
+	username := ctx.GetString("username")
+
+	client, err := pdns.New(connection)
+	if err != nil {
+		ctx.JSON(500, gin.H{"message": err.Error()})
+		return
+	}
+
+	ctxReq, cancel := context.WithTimeout(ctx.Request.Context(), 60*time.Second)
+	defer cancel()
+
+	existing, err := client.Zones(ctxReq)
+	if err != nil {
+		pdnsError(ctx, err, "fetch zones")
+		return
+	}
+
+	known := map[string]models.PdnsZone{}
+	for _, z := range existing {
+		known[strings.ToLower(z.Name)] = z
+	}
+
+	created := 0
+	var delegation gin.H
+
+	for i := range zones {
+		zone := &zones[i]
+
+		var patch []map[string]any
+		for _, rr := range req.Ptrs(zone) {
+			patch = append(patch, rr.Patch())
+		}
+		zone.Ptrs = len(patch)
+
+		if _, ok := known[strings.ToLower(zone.Name)]; ok {
+			zone.Status = "exists"
+			continue
+		}
+		if dryRun {
+			zone.Status = "planned"
+			continue
+		}
+
+		if err := createZone(ctxReq, client, zone.Name, req.Soa, patch); err != nil {
+			zone.Status, zone.Message = "failed", err.Error()
+			continue
+		}
+
+		zone.Status = "created"
+		created++
+		workers.RecordZoneState(connection, models.ZoneID(zone.Name), username)
+	}
+
+	// Only delegate to a classless zone that exists or is about to.
+	if len(zones) == 1 && zones[0].Classless && slices.Contains([]string{"created", "exists", "planned"}, zones[0].Status) {
+		delegation = classlessDelegation(ctxReq, client, connection, &zones[0], known, dryRun, username)
+	}
+
+	delegated := delegation != nil && delegation["status"] == "delegated"
+
+	if created > 0 || delegated {
+		action := "create_reverse_zones"
+		details := fmt.Sprintf("Created %d reverse zones for prefix %s", created, req.Prefix)
+		if delegated && created == 0 {
+			action = "delegate_reverse_zone"
+			details = fmt.Sprintf("Delegated classless reverse zone %s from %s", zones[0].Name, delegation["parent"])
+		} else if delegated {
+			details += fmt.Sprintf(" and delegated %s from %s", zones[0].Name, delegation["parent"])
+		}
+
+		log := &models.Log{
+			Username:     username,
+			IdConnection: ctx.Query("connection"),
+			Action:       action,
+			Details:      details,
+			HostServer:   connection.Host,
+			CreatedAt:    time.Now(),
+		}
+
+		if err := log.Insert(ctx.Request.Context()); err != nil {
+			ctx.JSON(500, gin.H{"message": fmt.Sprintf("failed to log reverse zone creation: %v", err)})
+			return
+		}
+	}
+
+	status := 201
+	if dryRun || created == 0 {
+		status = 200
+	}
+
+	ctx.JSON(status, gin.H{"prefix": req.Prefix, "dryRun": dryRun, "zones": zones, "delegation": delegation})
+}
+
+// classlessDelegation adds the RFC 2317 CNAMEs and NS delegation to the
+// parent /24 zone when it lives on the same connection. Names that already
+// hold other records are skipped and reported.
+func classlessDelegation(ctx context.Context, client *pdns.Client, connection *models.Connection, zone *models.ReverseZone, known map[string]models.PdnsZone, dryRun bool, username string) gin.H {
+	parentName, cnames := zone.ClasslessParent()
+	result := gin.H{"parent": parentName, "status": "skipped"}
+
+	parent, ok := known[strings.ToLower(parentName)]
+	if !ok {
+		result["message"] = "parent zone is not on this connection, delegate the classless zone there manually"
+		return result
+	}
+
+	parentZone, err := client.Zone(ctx, parent.ID)
+	if err != nil {
+		result["status"], result["message"] = "failed", err.Error()
+		return result
+	}
+
+	var patch []map[string]any
+	var skipped []string
+
+	for i := range cnames {
+		taken := false
+		for _, rr := range parentZone.RRSets {
+			if strings.EqualFold(rr.Name, cnames[i].Name) && rr.Type != "CNAME" {
+				taken = true
+				break
+			}
+		}
+		if taken {
+			skipped = append(skipped, cnames[i].Name)
+			continue
+		}
+		patch = append(patch, cnames[i].Patch())
+	}
+
+	if ns := parentZone.RRSet(parentZone.Name, "NS"); ns != nil {
+		delegation := models.RRSet{Name: zone.Name, Type: "NS", TTL: ns.TTL, Records: ns.Records}
+		patch = append(patch, delegation.Patch())
+	} else {
+		result["message"] = "parent zone has no NS records, the classless zone was not delegated"
+	}
+
+	result["cnames"] = len(cnames) - len(skipped)
+	result["skipped"] = skipped
+
+	if dryRun {
+		result["status"] = "planned"
+		return result
+	}
+
+	if err := client.PatchZone(ctx, parent.ID, patch); err != nil {
+		result["status"], result["message"] = "failed", err.Error()
+		return result
+	}
+
+	result["status"] = "delegated"
+	workers.RecordZoneState(connection, parent.ID, username)
+
+	return result
+}
@@ -0,0 +1,196 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rafinhacuri/SanchezDNS/db"
+	"github.com/rafinhacuri/SanchezDNS/models"
+	"github.com/rafinhacuri/SanchezDNS/pdns"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// searchMax caps the results taken from a single connection.
+const searchMax = 1000
+
+// searchError is a connection that could not be searched or, with Zone
+// set, a zone of it that could not be read.
+type searchError struct {
+	Connection string `json:"connection"`
+	Zone       string `json:"zone,omitempty"`
+	Message    string `json:"message"`
+}
+
+func Search(ctx *gin.Context) {
+	query := models.SearchQuery{Q: ctx.Query("q"), Type: ctx.Query("type"), Zone: ctx.Query("zone")}
+	if err := query.Validate(); err != nil {
+		ctx.JSON(400, gin.H{"message": err.Error()})
+		return
+	}
+
+	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(ctx.DefaultQuery("limit", "50"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 500 {
+		limit = 50
+	}
+
+	var connections []models.Connection
+
+	if ctx.Query("connection") != "" {
+		allowed, connection := permission(ctx)
+		if !allowed {
+			return
+		}
+		connections = append(connections, *connection)
+	} else {
+		filter := bson.M{}
+		if !ctx.GetBool("admin") {
+			filter = bson.M{"users": ctx.GetString("username")}
+		}
+
+		cursor, err := db.Database.Collection("connections").Find(ctx.Request.Context(), filter)
+		if err != nil {
+			ctx.JSON(500, gin.H{"message": "failed to fetch connections"})
+			return
+		}
+		if err := cursor.All(ctx.Request.Context(), &connections); err != nil {
+			ctx.JSON(500, gin.H{"message": "failed to parse connections"})
+			return
+		}
+	}
+
+	ctxReq, cancel := context.WithTimeout(ctx.Request.Context(), 20*time.Second)
+	defer cancel()
+
+	var (
+		mu        sync.Mutex
+		wg        sync.WaitGroup
+		results   []models.SearchResult
+		errs      = []searchError{}
+		truncated bool
+	)
+
+	for i := range connections {
+		wg.Add(1)
+		go func(connection *models.Connection) {
+			defer wg.Done()
+
+			found, capped, zoneErrs, err := searchConnection(ctxReq, connection, &query)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs = append(errs, searchError{Connection: connection.Name, Message: err.Error()})
+				return
+			}
+			for _, ze := range zoneErrs {
+				errs = append(errs, searchError{Connection: connection.Name, Zone: ze.Zone, Message: ze.Message})
+			}
+			results = append(results, found...)
+			truncated = truncated || capped
+		}(&connections[i])
+	}
+	wg.Wait()
+
+	models.SortSearchResults(results)
+
+	total := len(results)
+	start := min((page-1)*limit, total)
+	end := min(start+limit, total)
+
+	data := results[start:end]
+	if data == nil {
+		data = []models.SearchResult{}
+	}
+
+	ctx.JSON(200, gin.H{
+		"data":      data,
+		"total":     total,
+		"page":      page,
+		"limit":     limit,
+		"truncated": truncated,
+		"errors":    errs,
+	})
+}
+
+// searchConnection uses search-data when the server offers it and falls
+// back to fetching and scanning every zone otherwise. Zones the fallback
+// could not read are returned as zone errors.
+func searchConnection(ctx context.Context, connection *models.Connection, query *models.SearchQuery) ([]models.SearchResult, bool, []zoneError, error) {
+	client, err := pdns.New(connection)
+	if err != nil {
+		return nil, false, nil, err
+	}
+
+	var results []models.SearchResult
+
+	found, err := client.Search(ctx, query.Pattern(), "record", searchMax)
+	if err == nil {
+		for _, r := range found {
+			if !query.Filter(r.Zone, r.Type) {
+				continue
+			}
+			results = append(results, models.SearchResult{
+				Zone:     r.Zone,
+				Name:     r.Name,
+				Type:     r.Type,
+				Content:  r.Content,
+				TTL:      r.TTL,
+				Disabled: r.Disabled,
+			})
+		}
+		tagResults(results, connection)
+		return results, len(found) >= searchMax, nil, nil
+	}
+
+	// Only a server without the search endpoint is scanned zone by zone;
+	// any other failure would fail the scan the same way.
+	var pdnsErr *pdns.Error
+	if !errors.As(err, &pdnsErr) || (pdnsErr.StatusCode != 404 && pdnsErr.StatusCode != 501) {
+		return nil, false, nil, err
+	}
+
+	zones, err := client.Zones(ctx)
+	if err != nil {
+		return nil, false, nil, err
+	}
+
+	var ids []string
+	for _, z := range zones {
+		if query.MatchesZone(z.Name) {
+			ids = append(ids, z.ID)
+		}
+	}
+
+	zoneErrs := []zoneError{}
+	fetched, errs := client.FetchZones(ctx, ids, 4)
+	for i, zone := range fetched {
+		if errs[i] != nil {
+			zoneErrs = append(zoneErrs, zoneError{Zone: ids[i], Message: errs[i].Error()})
+			continue
+		}
+		results = append(results, query.Scan(zone)...)
+		if len(results) >= searchMax {
+			results = results[:searchMax]
+			tagResults(results, connection)
+			return results, true, zoneErrs, nil
+		}
+	}
+
+	tagResults(results, connection)
+	return results, false, zoneErrs, nil
+}
+
+func tagResults(results []models.SearchResult, connection *models.Connection) {
+	for i := range results {
+		results[i].Connection = connection.ID.Hex()
+		results[i].ConnectionName = connection.Name
+	}
+}
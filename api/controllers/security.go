@@ -0,0 +1,130 @@
+package controllers
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rafinhacuri/SanchezDNS/db"
+	"github.com/rafinhacuri/SanchezDNS/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const maxUserAgent = 512
+
+// recordSecurityEvent stores an authentication or account event. The
+// source address is ctx.ClientIP, which only trusts forwarding headers
+// from the proxies given to SetTrustedProxies. It is written with its own
+// deadline so an aborted request is still recorded.
+func recordSecurityEvent(ctx *gin.Context, eventType, email, outcome, reason string) {
+	userAgent := ctx.Request.UserAgent()
+	if len(userAgent) > maxUserAgent {
+		userAgent = userAgent[:maxUserAgent]
+	}
+
+	event := &models.SecurityEvent{
+		Type:      eventType,
+		Email:     strings.ToLower(strings.TrimSpace(email)),
+		Actor:     ctx.GetString("username"),
+		SourceIP:  ctx.ClientIP(),
+		UserAgent: userAgent,
+		Outcome:   outcome,
+		Reason:    reason,
+	}
+
+	ctxSave, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := event.Insert(ctxSave); err != nil {
+		slog.Error("failed to record security event", "type", eventType, "email", event.Email, "error", err)
+	}
+}
+
+// GetSecurityEvents lists security events, newest first, filtered by
+// ?email=, ?type=, ?outcome=, ?ip= and ?from=&to=.
+func GetSecurityEvents(ctx *gin.Context) {
+	if !ctx.GetBool("admin") {
+		ctx.JSON(403, gin.H{"error": "forbidden"})
+		return
+	}
+
+	q, err := models.ParseSecurityEventQuery(ctx.Request.URL.Query())
+	if err != nil {
+		ctx.JSON(400, gin.H{"message": err.Error()})
+		return
+	}
+
+	filter := q.Filter()
+
+	total, _ := db.Database.Collection("security_events").CountDocuments(ctx.Request.Context(), filter)
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "createdAt", Value: -1}, {Key: "_id", Value: -1}}).
+		SetSkip(int64((q.Page - 1) * q.Limit)).
+		SetLimit(int64(q.Limit))
+
+	cursor, err := db.Database.Collection("security_events").Find(ctx.Request.Context(), filter, opts)
+	if err != nil {
+		ctx.JSON(500, gin.H{"message": "failed to fetch security events"})
+		return
+	}
+
+	events := []models.SecurityEvent{}
+	if err := cursor.All(ctx.Request.Context(), &events); err != nil {
+		ctx.JSON(500, gin.H{"message": "failed to parse security events"})
+		return
+	}
+
+	ctx.JSON(200, gin.H{"data": events, "total": total, "page": q.Page, "limit": q.Limit})
+}
+
+// GetUserActivity returns the recent security events of the current user,
+// or of ?email= for admins, with the last successful login and the number
+// of failed logins since then.
+func GetUserActivity(ctx *gin.Context) {
+	email := strings.ToLower(ctx.GetString("username"))
+	if other := ctx.Query("email"); other != "" && !strings.EqualFold(other, email) {
+		if !ctx.GetBool("admin") {
+			ctx.JSON(403, gin.H{"error": "forbidden"})
+			return
+		}
+		email = strings.ToLower(strings.TrimSpace(other))
+	}
+
+	ctxReq, cancel := context.WithTimeout(ctx.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	collection := db.Database.Collection("security_events")
+	newest := bson.D{{Key: "createdAt", Value: -1}, {Key: "_id", Value: -1}}
+
+	cursor, err := collection.Find(ctxReq, bson.M{"email": email}, options.Find().SetSort(newest).SetLimit(20))
+	if err != nil {
+		ctx.JSON(500, gin.H{"message": "failed to fetch security events"})
+		return
+	}
+
+	events := []models.SecurityEvent{}
+	if err := cursor.All(ctxReq, &events); err != nil {
+		ctx.JSON(500, gin.H{"message": "failed to parse security events"})
+		return
+	}
+
+	response := gin.H{"email": email, "events": events}
+
+	failedFilter := bson.M{"email": email, "type": models.SecurityLogin, "outcome": models.OutcomeFailure}
+
+	var lastLogin models.SecurityEvent
+	err = collection.FindOne(ctxReq, bson.M{"email": email, "type": models.SecurityLogin, "outcome": models.OutcomeSuccess}, options.FindOne().SetSort(newest)).Decode(&lastLogin)
+	if err == nil {
+		response["lastLogin"] = lastLogin
+		failedFilter["createdAt"] = bson.M{"$gt": lastLogin.CreatedAt}
+	}
+
+	failed, _ := collection.CountDocuments(ctxReq, failedFilter)
+	response["failedLoginsSinceLastLogin"] = failed
+
+	ctx.JSON(200, response)
+}
@@ -0,0 +1,130 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/goccy/go-yaml"
+	"github.com/rafinhacuri/SanchezDNS/models"
+	"github.com/rafinhacuri/SanchezDNS/pdns"
+	"github.com/rafinhacuri/SanchezDNS/workers"
+)
+
+// bindZoneSpec reads a zone spec sent either as JSON or, when the content
+// type says so, as YAML.
+func bindZoneSpec(ctx *gin.Context) (*models.ZoneSpec, error) {
+	body, err := io.ReadAll(io.LimitReader(ctx.Request.Body, 4<<20))
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.Contains(ctx.ContentType(), "yaml") {
+		if body, err = yaml.YAMLToJSON(body); err != nil {
+			return nil, err
+		}
+	}
+
+	var spec models.ZoneSpec
+	if err := json.Unmarshal(body, &spec); err != nil {
+		return nil, err
+	}
+
+	return &spec, nil
+}
+
+// ApplyZoneSpec reconciles a zone with a declarative spec. With mode=plan
+// (the default) only the plan is returned; mode=apply refuses to run while
+// the plan has conflicts.
+func ApplyZoneSpec(ctx *gin.Context) {
+	allowed, connection := permission(ctx)
+	if !allowed {
+		return
+	}
+
+	mode := ctx.DefaultQuery("mode", "plan")
+	if mode != "plan" && mode != "apply" {
+		ctx.JSON(400, gin.H{"message": "mode must be 'plan' or 'apply'"})
+		return
+	}
+
+	spec, err := bindZoneSpec(ctx)
+	if err != nil {
+		ctx.JSON(400, gin.H{"message": fmt.Sprintf("invalid zone spec: %v", err)})
+		return
+	}
+
+	if err := spec.Validate(); err != nil {
+		ctx.JSON(400, gin.H{"message": fmt.Sprintf("validation error: %v", err)})
+		return
+	}
+
+	client, err := pdns.New(connection)
+	if err != nil {
+		ctx.JSON(500, gin.H{"message": err.Error()})
+		return
+	}
+
+	ctxReq, cancel := context.WithTimeout(ctx.Request.Context(), 15*time.Second)
+	defer cancel()
+
+	zone, err := client.Zone(ctxReq, spec.Zone)
+	if err != nil {
+		var pdnsErr *pdns.Error
+		if !errors.As(err, &pdnsErr) || pdnsErr.StatusCode != 404 {
+			pdnsError(ctx, err, "fetch zone")
+			return
+		}
+		zone = nil
+	}
+
+	plan := spec.Plan(zone)
+	plan.Mode = mode
+
+	if mode == "plan" || !plan.HasChanges {
+		ctx.JSON(200, plan)
+		return
+	}
+
+	if len(plan.Conflicts) > 0 {
+		ctx.JSON(409, plan)
+		return
+	}
+
+	if plan.CreateZone {
+		err = createZone(ctxReq, client, spec.Zone, *spec.Soa, models.PatchChanges(plan.Changes))
+	} else {
+		err = client.PatchZone(ctxReq, spec.Zone, models.PatchChanges(plan.Changes))
+	}
+	if err != nil {
+		pdnsError(ctx, err, "apply zone spec")
+		return
+	}
+
+	plan.Applied = true
+
+	log := &models.Log{
+		Username:     ctx.GetString("username"),
+		IdConnection: ctx.Query("connection"),
+		Action:       "apply_zone_spec",
+		Details:      fmt.Sprintf("Applied zone spec managed by %s to zone %s (%d created, %d updated, %d deleted)", spec.ManagedBy, spec.Zone, plan.Summary.Create, plan.Summary.Update, plan.Summary.Delete),
+		Zone:         spec.Zone,
+		HostServer:   connection.Host,
+		Changes:      plan.Changes,
+		CreatedAt:    time.Now(),
+	}
+
+	if err := log.Insert(ctx.Request.Context()); err != nil {
+		ctx.JSON(500, gin.H{"message": fmt.Sprintf("failed to log zone spec: %v", err)})
+		return
+	}
+
+	workers.RecordZoneState(connection, spec.Zone, ctx.GetString("username"))
+
+	ctx.JSON(200, plan)
+}
@@ -3,18 +3,16 @@ package controllers
 import (
 	"context"
 	"fmt"
-	"net/http"
-	"slices"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/go-resty/resty/v2"
 	"github.com/rafinhacuri/SanchezDNS/db"
 	"github.com/rafinhacuri/SanchezDNS/models"
-	"github.com/rafinhacuri/SanchezDNS/utils"
+	"github.com/rafinhacuri/SanchezDNS/pdns"
 	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type StatisticsResponse struct {
@@ -28,64 +26,42 @@ type StatisticsResponse struct {
 	TCPQueries    int    `json:"tcpQueries"`
 	ServerID      string `json:"serverId"`
 	StartedAt     string `json:"startedAt"`
+	Complete      bool   `json:"complete"`
 }
 
-func GetStatistics(ctx *gin.Context) {
-	id := ctx.Query("connection")
-	username := ctx.GetString("username")
-	isAdmin := ctx.GetBool("admin")
+// statsZoneWorkers bounds the zone fetches GetStatistics runs in parallel.
+const statsZoneWorkers = 8
 
-	if id == "" {
-		ctx.JSON(400, gin.H{"message": "connection ID is required"})
-		return
-	}
+type zoneRecordCount struct {
+	serial  int64
+	records int
+}
 
-	connectionID, err := primitive.ObjectIDFromHex(id)
-	if err != nil {
-		ctx.JSON(400, gin.H{"message": "invalid connection ID"})
+// recordCounts caches the record count of each zone per connection, keyed
+// by zone ID and only valid while the zone serial is unchanged.
+var recordCounts = struct {
+	sync.Mutex
+	byConnection map[string]map[string]zoneRecordCount
+}{byConnection: map[string]map[string]zoneRecordCount{}}
+
+func GetStatistics(ctx *gin.Context) {
+	ok, connection := permission(ctx)
+	if !ok {
 		return
 	}
 
-	var connection models.Connection
-
 	ctxReq, cancel := context.WithTimeout(ctx.Request.Context(), 8*time.Second)
 	defer cancel()
 
-	err = db.Database.Collection("connections").FindOne(ctxReq, bson.M{"_id": connectionID}).Decode(&connection)
-	if err != nil {
-		ctx.JSON(404, gin.H{"message": "connection not found"})
-		return
-	}
-
-	if !isAdmin && !slices.Contains(connection.Users, username) {
-		ctx.JSON(403, gin.H{"message": "forbidden"})
-		return
-	}
-
-	plainKey, err := utils.Decrypt(connection.ApiKey)
+	client, err := pdns.New(connection)
 	if err != nil {
-		ctx.JSON(500, gin.H{"message": fmt.Sprintf("failed to decrypt api key: %v", err)})
+		ctx.JSON(500, gin.H{"message": err.Error()})
 		return
 	}
 
-	base := utils.NormalizeBase(connection.Host)
-
-	serverID := connection.ServerId
-	if serverID == "" {
-		serverID = "localhost"
-	}
-
-	httpc := resty.New().SetBaseURL(base).SetHeader("X-API-Key", plainKey).SetHeader("Accept", "application/json").SetTimeout(6 * time.Second).SetRetryCount(2)
-
-	var statsRaw []models.PdnsStat
-	statResp, err := httpc.R().SetContext(ctxReq).SetResult(&statsRaw).Get(fmt.Sprintf("/api/v1/servers/%s/statistics", serverID))
-
+	statsRaw, err := client.Statistics(ctxReq)
 	if err != nil {
-		ctx.JSON(http.StatusBadGateway, gin.H{"message": fmt.Sprintf("failed to reach PowerDNS: %v", err)})
-		return
-	}
-	if statResp.IsError() {
-		ctx.JSON(statResp.StatusCode(), gin.H{"message": fmt.Sprintf("PowerDNS statistics error: %s", statResp.Status())})
+		pdnsError(ctx, err, "reach PowerDNS")
 		return
 	}
 
@@ -114,28 +90,25 @@ func GetStatistics(ctx *gin.Context) {
 		return 0
 	}
 
-	var zones []models.PdnsZone
-	zonesResp, err := httpc.R().SetContext(ctxReq).SetResult(&zones).Get(fmt.Sprintf("/api/v1/servers/%s/zones", serverID))
-
+	zones, err := client.Zones(ctxReq)
 	if err != nil {
-		ctx.JSON(http.StatusBadGateway, gin.H{"message": fmt.Sprintf("failed to fetch zones: %v", err)})
-		return
-	}
-	if zonesResp.IsError() {
-		ctx.JSON(zonesResp.StatusCode(), gin.H{"message": fmt.Sprintf("PowerDNS zones error: %s", zonesResp.Status())})
+		pdnsError(ctx, err, "fetch zones")
 		return
 	}
 
-	records := 0
-	for _, z := range zones {
-		var zd models.PdnsZoneDetails
-		zr, zerr := httpc.R().SetContext(ctxReq).SetResult(&zd).Get(fmt.Sprintf("/api/v1/servers/%s/zones/%s", serverID, z.ID))
-		if zerr != nil || zr.IsError() {
-			continue
-		}
-		for _, rr := range zd.RRsets {
-			records += len(rr.Records)
-		}
+	// Leave time to answer with partial counts when the zone fetches run
+	// into the deadline.
+	ctxZones, cancelZones := context.WithTimeout(ctxReq, 6*time.Second)
+	defer cancelZones()
+
+	records, complete := countRecords(ctxZones, client, connection.ID.Hex(), zones)
+
+	// The status is the one kept by the health checks, so it matches the
+	// health page instead of only reflecting this request.
+	status := models.HealthUnknown
+	var health models.ConnectionHealth
+	if err := db.Database.Collection("connection_health").FindOne(ctxReq, bson.M{"idConnection": connection.ID.Hex()}).Decode(&health); err == nil && health.Status != "" {
+		status = health.Status
 	}
 
 	uptimeSec := getInt("uptime")
@@ -144,17 +117,70 @@ func GetStatistics(ctx *gin.Context) {
 		Records:       records,
 		Users:         len(connection.Users),
 		Uptime:        humanUptime(uptimeSec),
-		Status:        "online",
+		Status:        status,
 		UDPQueries:    getInt("udp-queries"),
 		TCPQueries:    getInt("tcp-queries"),
 		FailedQueries: getInt("servfail-answers", "nxdomain-answers", "recursion-failures"),
-		ServerID:      serverID,
+		ServerID:      client.ServerId,
 		StartedAt:     startedAtFromNow(uptimeSec).Format(time.RFC3339),
+		Complete:      complete,
 	}
 
 	ctx.JSON(200, resp)
 }
 
+// countRecords sums the records of all zones, fetching only the zones whose
+// serial changed since they were last counted. A zone that fails to be
+// refetched keeps its previous count, if it has one, and is retried on the
+// next call. It reports false when some zones could not be counted.
+func countRecords(ctx context.Context, client *pdns.Client, connectionID string, zones []models.PdnsZone) (int, bool) {
+	recordCounts.Lock()
+	cached := recordCounts.byConnection[connectionID]
+	recordCounts.Unlock()
+
+	counts := make(map[string]zoneRecordCount, len(zones))
+	var missing []models.PdnsZone
+	for _, z := range zones {
+		if c, ok := cached[z.ID]; ok && z.Serial != 0 && c.serial == z.Serial {
+			counts[z.ID] = c
+			continue
+		}
+		missing = append(missing, z)
+	}
+
+	ids := make([]string, len(missing))
+	for i, z := range missing {
+		ids[i] = z.ID
+	}
+
+	complete := true
+	fetched, errs := client.FetchZones(ctx, ids, statsZoneWorkers)
+	for i, z := range missing {
+		if errs[i] != nil {
+			complete = false
+			if c, ok := cached[z.ID]; ok {
+				counts[z.ID] = c
+			}
+			continue
+		}
+		records := 0
+		for _, rr := range fetched[i].RRSets {
+			records += len(rr.Records)
+		}
+		counts[z.ID] = zoneRecordCount{serial: z.Serial, records: records}
+	}
+
+	recordCounts.Lock()
+	recordCounts.byConnection[connectionID] = counts
+	recordCounts.Unlock()
+
+	total := 0
+	for _, c := range counts {
+		total += c.records
+	}
+	return total, complete
+}
+
 func humanUptime(sec int) string {
 	if sec <= 0 {
 		return "0s"
@@ -178,3 +204,51 @@ func startedAtFromNow(uptimeSec int) time.Time {
 	}
 	return time.Now().UTC().Add(-time.Duration(uptimeSec) * time.Second)
 }
+
+// GetStatisticsHistory returns the sampled statistics of a connection as
+// rates over ?range= (24h by default) in buckets of ?resolution=.
+func GetStatisticsHistory(ctx *gin.Context) {
+	ok, connection := permission(ctx)
+	if !ok {
+		return
+	}
+
+	q, err := models.ParseStatHistoryQuery(ctx.Query("range"), ctx.Query("resolution"))
+	if err != nil {
+		ctx.JSON(400, gin.H{"message": err.Error()})
+		return
+	}
+
+	to := time.Now().UTC()
+	from := to.Add(-q.Range)
+
+	ctxReq, cancel := context.WithTimeout(ctx.Request.Context(), 15*time.Second)
+	defer cancel()
+
+	// One sample before the range is needed for the first delta.
+	filter := bson.M{
+		"meta.idConnection": connection.ID.Hex(),
+		"timestamp":         bson.M{"$gte": from.Truncate(q.Resolution).Add(-q.Resolution), "$lte": to},
+	}
+	opts := options.Find().SetSort(bson.M{"timestamp": 1})
+
+	cursor, err := db.Database.Collection("statistics").Find(ctxReq, filter, opts)
+	if err != nil {
+		ctx.JSON(500, gin.H{"message": "failed to fetch statistics"})
+		return
+	}
+
+	samples := []models.StatSample{}
+	if err := cursor.All(ctxReq, &samples); err != nil {
+		ctx.JSON(500, gin.H{"message": "failed to parse statistics"})
+		return
+	}
+
+	ctx.JSON(200, gin.H{
+		"connection": connection.ID.Hex(),
+		"from":       from,
+		"to":         to,
+		"resolution": q.Resolution.String(),
+		"points":     models.BuildStatHistory(samples, from, q),
+	})
+}
@@ -0,0 +1,65 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rafinhacuri/SanchezDNS/db"
+	"github.com/rafinhacuri/SanchezDNS/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// The helpers below back the admin endpoints of the log subscriptions,
+// webhooks and notification channels, which are stored the same way.
+
+// listByName answers with every document of collection sorted by name.
+// label names the documents in error messages.
+func listByName[T any](ctx *gin.Context, collection, label string) {
+	cursor, err := db.Database.Collection(collection).Find(ctx.Request.Context(), bson.M{}, options.Find().SetSort(bson.M{"name": 1}))
+	if err != nil {
+		ctx.JSON(500, gin.H{"message": "failed to fetch " + label + "s"})
+		return
+	}
+
+	docs := []T{}
+	if err := cursor.All(ctx.Request.Context(), &docs); err != nil {
+		ctx.JSON(500, gin.H{"message": "failed to parse " + label + "s"})
+		return
+	}
+
+	ctx.JSON(200, docs)
+}
+
+// deleteByID removes the document given by ?id= and returns it. When it
+// returns false the request has already been answered.
+func deleteByID[T any](ctx *gin.Context, collection, label string) (*T, bool) {
+	id, err := primitive.ObjectIDFromHex(ctx.Query("id"))
+	if err != nil {
+		ctx.JSON(400, gin.H{"message": "invalid " + label + " ID"})
+		return nil, false
+	}
+
+	var doc T
+	if err := db.Database.Collection(collection).FindOneAndDelete(ctx.Request.Context(), bson.M{"_id": id}).Decode(&doc); err != nil {
+		ctx.JSON(404, gin.H{"message": label + " not found"})
+		return nil, false
+	}
+
+	return &doc, true
+}
+
+// logAdminAction records a settings change made by username. Failing to
+// log does not fail the change.
+func logAdminAction(ctx context.Context, username, action, details string) {
+	log := &models.Log{
+		Username:  username,
+		Action:    action,
+		Details:   details,
+		CreatedAt: time.Now(),
+	}
+
+	_ = log.Insert(ctx)
+}
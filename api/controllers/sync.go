@@ -0,0 +1,259 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rafinhacuri/SanchezDNS/db"
+	"github.com/rafinhacuri/SanchezDNS/models"
+	"github.com/rafinhacuri/SanchezDNS/workers"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func findSyncJob(ctx context.Context, jobID string) (*models.SyncJob, error) {
+	id, err := primitive.ObjectIDFromHex(jobID)
+	if err != nil {
+		return nil, err
+	}
+
+	var job models.SyncJob
+	if err := db.Database.Collection("sync_jobs").FindOne(ctx, bson.M{"_id": id}).Decode(&job); err != nil {
+		return nil, err
+	}
+
+	return &job, nil
+}
+
+func validateSyncEndpoints(ctx context.Context, request *models.SyncJobRequest) error {
+	if _, err := models.FindConnection(ctx, request.Source.Connection); err != nil {
+		return fmt.Errorf("source: %w", err)
+	}
+	if _, err := models.FindConnection(ctx, request.Destination.Connection); err != nil {
+		return fmt.Errorf("destination: %w", err)
+	}
+	return nil
+}
+
+func GetSyncJobs(ctx *gin.Context) {
+	if !ctx.GetBool("admin") {
+		ctx.JSON(403, gin.H{"error": "forbidden"})
+		return
+	}
+
+	opts := options.Find().SetSort(bson.M{"name": 1})
+
+	cursor, err := db.Database.Collection("sync_jobs").Find(ctx.Request.Context(), bson.M{}, opts)
+	if err != nil {
+		ctx.JSON(500, gin.H{"message": "failed to fetch sync jobs"})
+		return
+	}
+
+	jobs := []models.SyncJob{}
+	if err := cursor.All(ctx.Request.Context(), &jobs); err != nil {
+		ctx.JSON(500, gin.H{"message": "failed to parse sync jobs"})
+		return
+	}
+
+	ctx.JSON(200, jobs)
+}
+
+func InsertSyncJob(ctx *gin.Context) {
+	if !ctx.GetBool("admin") {
+		ctx.JSON(403, gin.H{"error": "forbidden"})
+		return
+	}
+
+	var request models.SyncJobRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(400, gin.H{"message": "failed to bind JSON"})
+		return
+	}
+
+	if err := request.Validate(); err != nil {
+		ctx.JSON(400, gin.H{"message": err.Error()})
+		return
+	}
+
+	ctxReq, cancel := context.WithTimeout(ctx.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	if err := validateSyncEndpoints(ctxReq, &request); err != nil {
+		ctx.JSON(400, gin.H{"message": err.Error()})
+		return
+	}
+
+	username := ctx.GetString("username")
+
+	job := &models.SyncJob{
+		Name:           request.Name,
+		Source:         request.Source,
+		Destination:    request.Destination,
+		IncludeTypes:   request.IncludeTypes,
+		ExcludeTypes:   request.ExcludeTypes,
+		IncludeNames:   request.IncludeNames,
+		ExcludeNames:   request.ExcludeNames,
+		ConflictPolicy: request.ConflictPolicy,
+		DeleteMissing:  request.DeleteMissing,
+		Interval:       request.Interval,
+		Enabled:        request.Enabled,
+		CreatedBy:      username,
+		CreatedAt:      time.Now(),
+		UpdatedAt:      time.Now(),
+	}
+
+	result, err := db.Database.Collection("sync_jobs").InsertOne(ctxReq, job)
+	if err != nil {
+		ctx.JSON(500, gin.H{"message": err.Error()})
+		return
+	}
+
+	log := &models.Log{
+		Username:     username,
+		IdConnection: request.Destination.Connection,
+		Zone:         request.Destination.Zone,
+		Action:       "create_sync_job",
+		Details:      fmt.Sprintf("User %s created sync job %s (%s -> %s)", username, job.Name, job.Source.Zone, job.Destination.Zone),
+		CreatedAt:    time.Now(),
+	}
+
+	_ = log.Insert(ctxReq)
+
+	ctx.JSON(201, gin.H{"message": "sync job created successfully", "id": result.InsertedID})
+}
+
+func EditSyncJob(ctx *gin.Context) {
+	if !ctx.GetBool("admin") {
+		ctx.JSON(403, gin.H{"error": "forbidden"})
+		return
+	}
+
+	job, err := findSyncJob(ctx.Request.Context(), ctx.Query("id"))
+	if err != nil {
+		ctx.JSON(404, gin.H{"message": "sync job not found"})
+		return
+	}
+
+	var request models.SyncJobRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(400, gin.H{"message": "failed to bind JSON"})
+		return
+	}
+
+	if err := request.Validate(); err != nil {
+		ctx.JSON(400, gin.H{"message": err.Error()})
+		return
+	}
+
+	ctxReq, cancel := context.WithTimeout(ctx.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	if err := validateSyncEndpoints(ctxReq, &request); err != nil {
+		ctx.JSON(400, gin.H{"message": err.Error()})
+		return
+	}
+
+	update := bson.M{
+		"name":           request.Name,
+		"source":         request.Source,
+		"destination":    request.Destination,
+		"includeTypes":   request.IncludeTypes,
+		"excludeTypes":   request.ExcludeTypes,
+		"includeNames":   request.IncludeNames,
+		"excludeNames":   request.ExcludeNames,
+		"conflictPolicy": request.ConflictPolicy,
+		"deleteMissing":  request.DeleteMissing,
+		"interval":       request.Interval,
+		"enabled":        request.Enabled,
+		"updatedAt":      time.Now(),
+	}
+
+	if _, err := db.Database.Collection("sync_jobs").UpdateOne(ctxReq, bson.M{"_id": job.ID}, bson.M{"$set": update}); err != nil {
+		ctx.JSON(500, gin.H{"message": err.Error()})
+		return
+	}
+
+	username := ctx.GetString("username")
+
+	log := &models.Log{
+		Username:     username,
+		IdConnection: request.Destination.Connection,
+		Zone:         request.Destination.Zone,
+		Action:       "edit_sync_job",
+		Details:      fmt.Sprintf("User %s edited sync job %s", username, request.Name),
+		CreatedAt:    time.Now(),
+	}
+
+	_ = log.Insert(ctxReq)
+
+	ctx.JSON(200, gin.H{"message": "sync job updated successfully"})
+}
+
+func DeleteSyncJob(ctx *gin.Context) {
+	if !ctx.GetBool("admin") {
+		ctx.JSON(403, gin.H{"error": "forbidden"})
+		return
+	}
+
+	job, err := findSyncJob(ctx.Request.Context(), ctx.Query("id"))
+	if err != nil {
+		ctx.JSON(404, gin.H{"message": "sync job not found"})
+		return
+	}
+
+	if _, err := db.Database.Collection("sync_jobs").DeleteOne(ctx.Request.Context(), bson.M{"_id": job.ID}); err != nil {
+		ctx.JSON(500, gin.H{"message": err.Error()})
+		return
+	}
+
+	username := ctx.GetString("username")
+
+	log := &models.Log{
+		Username:     username,
+		IdConnection: job.Destination.Connection,
+		Zone:         job.Destination.Zone,
+		Action:       "delete_sync_job",
+		Details:      fmt.Sprintf("User %s deleted sync job %s", username, job.Name),
+		CreatedAt:    time.Now(),
+	}
+
+	_ = log.Insert(ctx.Request.Context())
+
+	ctx.JSON(200, gin.H{"message": "sync job deleted successfully"})
+}
+
+// RunSyncJob runs a sync job on demand. It is a dry run unless dryRun=false
+// is passed.
+func RunSyncJob(ctx *gin.Context) {
+	if !ctx.GetBool("admin") {
+		ctx.JSON(403, gin.H{"error": "forbidden"})
+		return
+	}
+
+	job, err := findSyncJob(ctx.Request.Context(), ctx.Query("id"))
+	if err != nil {
+		ctx.JSON(404, gin.H{"message": "sync job not found"})
+		return
+	}
+
+	dryRun := ctx.DefaultQuery("dryRun", "true") != "false"
+
+	ctxReq, cancel := context.WithTimeout(ctx.Request.Context(), 30*time.Second)
+	defer cancel()
+
+	report, err := workers.RunSyncJob(ctxReq, job, dryRun, ctx.GetString("username"))
+	if errors.Is(err, workers.ErrSyncRunning) {
+		ctx.JSON(409, gin.H{"message": err.Error()})
+		return
+	}
+	if err != nil {
+		pdnsError(ctx, err, "run sync job")
+		return
+	}
+
+	ctx.JSON(200, report)
+}
@@ -0,0 +1,347 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rafinhacuri/SanchezDNS/db"
+	"github.com/rafinhacuri/SanchezDNS/models"
+	"github.com/rafinhacuri/SanchezDNS/pdns"
+	"github.com/rafinhacuri/SanchezDNS/workers"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func findTemplate(ctx context.Context, templateID string) (*models.ZoneTemplate, error) {
+	id, err := primitive.ObjectIDFromHex(templateID)
+	if err != nil {
+		return nil, err
+	}
+
+	var template models.ZoneTemplate
+	if err := db.Database.Collection("templates").FindOne(ctx, bson.M{"_id": id}).Decode(&template); err != nil {
+		return nil, err
+	}
+
+	return &template, nil
+}
+
+func GetTemplates(ctx *gin.Context) {
+	opts := options.Find().SetSort(bson.M{"name": 1})
+
+	cursor, err := db.Database.Collection("templates").Find(ctx.Request.Context(), bson.M{}, opts)
+	if err != nil {
+		ctx.JSON(500, gin.H{"message": "failed to fetch templates"})
+		return
+	}
+
+	templates := []models.ZoneTemplate{}
+	if err := cursor.All(ctx.Request.Context(), &templates); err != nil {
+		ctx.JSON(500, gin.H{"message": "failed to parse templates"})
+		return
+	}
+
+	ctx.JSON(200, templates)
+}
+
+func GetTemplate(ctx *gin.Context) {
+	template, err := findTemplate(ctx.Request.Context(), ctx.Query("id"))
+	if err != nil {
+		ctx.JSON(404, gin.H{"message": "template not found"})
+		return
+	}
+
+	ctx.JSON(200, template)
+}
+
+func InsertTemplate(ctx *gin.Context) {
+	if !ctx.GetBool("admin") {
+		ctx.JSON(403, gin.H{"error": "forbidden"})
+		return
+	}
+
+	var request models.ZoneTemplateRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(400, gin.H{"message": "failed to bind JSON"})
+		return
+	}
+
+	if err := request.Validate(); err != nil {
+		ctx.JSON(400, gin.H{"message": err.Error()})
+		return
+	}
+
+	ctxReq, cancel := context.WithTimeout(ctx.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	count, err := db.Database.Collection("templates").CountDocuments(ctxReq, bson.M{"name": request.Name})
+	if err != nil {
+		ctx.JSON(500, gin.H{"message": err.Error()})
+		return
+	}
+	if count > 0 {
+		ctx.JSON(409, gin.H{"message": "template already exists"})
+		return
+	}
+
+	username := ctx.GetString("username")
+
+	template := &models.ZoneTemplate{
+		Name:        request.Name,
+		Description: request.Description,
+		Soa:         request.Soa,
+		RRSets:      request.RRSets,
+		Variables:   request.Variables,
+		CreatedBy:   username,
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+	}
+
+	result, err := db.Database.Collection("templates").InsertOne(ctxReq, template)
+	if err != nil {
+		ctx.JSON(500, gin.H{"message": err.Error()})
+		return
+	}
+
+	log := &models.Log{
+		Username:  username,
+		Action:    "create_template",
+		Details:   fmt.Sprintf("User %s created zone template %s", username, template.Name),
+		CreatedAt: time.Now(),
+	}
+
+	_ = log.Insert(ctxReq)
+
+	ctx.JSON(201, gin.H{"message": "template created successfully", "id": result.InsertedID})
+}
+
+func EditTemplate(ctx *gin.Context) {
+	if !ctx.GetBool("admin") {
+		ctx.JSON(403, gin.H{"error": "forbidden"})
+		return
+	}
+
+	template, err := findTemplate(ctx.Request.Context(), ctx.Query("id"))
+	if err != nil {
+		ctx.JSON(404, gin.H{"message": "template not found"})
+		return
+	}
+
+	var request models.ZoneTemplateRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(400, gin.H{"message": "failed to bind JSON"})
+		return
+	}
+
+	if err := request.Validate(); err != nil {
+		ctx.JSON(400, gin.H{"message": err.Error()})
+		return
+	}
+
+	ctxReq, cancel := context.WithTimeout(ctx.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	if request.Name != template.Name {
+		count, err := db.Database.Collection("templates").CountDocuments(ctxReq, bson.M{"name": request.Name})
+		if err != nil {
+			ctx.JSON(500, gin.H{"message": err.Error()})
+			return
+		}
+		if count > 0 {
+			ctx.JSON(409, gin.H{"message": "template already exists"})
+			return
+		}
+	}
+
+	update := bson.M{
+		"name":        request.Name,
+		"description": request.Description,
+		"soa":         request.Soa,
+		"rrsets":      request.RRSets,
+		"variables":   request.Variables,
+		"updatedAt":   time.Now(),
+	}
+
+	if _, err := db.Database.Collection("templates").UpdateOne(ctxReq, bson.M{"_id": template.ID}, bson.M{"$set": update}); err != nil {
+		ctx.JSON(500, gin.H{"message": err.Error()})
+		return
+	}
+
+	username := ctx.GetString("username")
+
+	log := &models.Log{
+		Username:  username,
+		Action:    "edit_template",
+		Details:   fmt.Sprintf("User %s edited zone template %s", username, request.Name),
+		CreatedAt: time.Now(),
+	}
+
+	_ = log.Insert(ctxReq)
+
+	ctx.JSON(200, gin.H{"message": "template updated successfully"})
+}
+
+func DeleteTemplate(ctx *gin.Context) {
+	if !ctx.GetBool("admin") {
+		ctx.JSON(403, gin.H{"error": "forbidden"})
+		return
+	}
+
+	template, err := findTemplate(ctx.Request.Context(), ctx.Query("id"))
+	if err != nil {
+		ctx.JSON(404, gin.H{"message": "template not found"})
+		return
+	}
+
+	if _, err := db.Database.Collection("templates").DeleteOne(ctx.Request.Context(), bson.M{"_id": template.ID}); err != nil {
+		ctx.JSON(500, gin.H{"message": err.Error()})
+		return
+	}
+
+	username := ctx.GetString("username")
+
+	log := &models.Log{
+		Username:  username,
+		Action:    "delete_template",
+		Details:   fmt.Sprintf("User %s deleted zone template %s", username, template.Name),
+		CreatedAt: time.Now(),
+	}
+
+	_ = log.Insert(ctx.Request.Context())
+
+	ctx.JSON(200, gin.H{"message": "template deleted successfully"})
+}
+
+// ApplyTemplate applies a template to an existing zone. With mode=preview
+// (the default) it only returns the rrset changes that would be made.
+func ApplyTemplate(ctx *gin.Context) {
+	allowed, connection := permission(ctx)
+	if !allowed {
+		return
+	}
+
+	zoneID := ctx.Query("zone")
+	if zoneID == "" {
+		ctx.JSON(400, gin.H{"message": "zone ID is required"})
+		return
+	}
+
+	mode := ctx.DefaultQuery("mode", "preview")
+	if mode != "preview" && mode != "apply" {
+		ctx.JSON(400, gin.H{"message": "mode must be 'preview' or 'apply'"})
+		return
+	}
+
+	template, err := findTemplate(ctx.Request.Context(), ctx.Query("template"))
+	if err != nil {
+		ctx.JSON(404, gin.H{"message": "template not found"})
+		return
+	}
+
+	var req models.ApplyTemplateRequest
+	if ctx.Request.ContentLength > 0 {
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			ctx.JSON(400, gin.H{"message": fmt.Sprintf("invalid request body: %v", err)})
+			return
+		}
+	}
+
+	if err := req.Validate(); err != nil {
+		ctx.JSON(400, gin.H{"message": fmt.Sprintf("validation error: %v", err)})
+		return
+	}
+
+	client, err := pdns.New(connection)
+	if err != nil {
+		ctx.JSON(500, gin.H{"message": err.Error()})
+		return
+	}
+
+	ctxReq, cancel := context.WithTimeout(ctx.Request.Context(), 8*time.Second)
+	defer cancel()
+
+	zone, err := client.Zone(ctxReq, zoneID)
+	if err != nil {
+		pdnsError(ctx, err, "fetch zone")
+		return
+	}
+
+	rendered, err := template.Render(zone.Name, req.Variables, ctx.GetString("username"))
+	if err != nil {
+		ctx.JSON(400, gin.H{"message": fmt.Sprintf("template error: %v", err)})
+		return
+	}
+
+	if req.Strategy == "merge" {
+		rendered = mergeRRSets(zone.RRSets, rendered)
+	}
+
+	changes := models.DiffRRSets(zone.RRSets, rendered, false)
+
+	if mode == "preview" || len(changes) == 0 {
+		ctx.JSON(200, gin.H{"zone": zone.Name, "template": template.Name, "mode": mode, "changes": changes, "applied": false})
+		return
+	}
+
+	if err := client.PatchZone(ctxReq, zoneID, models.PatchChanges(changes)); err != nil {
+		pdnsError(ctx, err, "apply template")
+		return
+	}
+
+	log := &models.Log{
+		Username:     ctx.GetString("username"),
+		IdConnection: ctx.Query("connection"),
+		Action:       "apply_template",
+		Details:      fmt.Sprintf("Applied template %s to zone %s (%d rrsets changed)", template.Name, zone.Name, len(changes)),
+		Zone:         zoneID,
+		HostServer:   connection.Host,
+		Changes:      changes,
+		CreatedAt:    time.Now(),
+	}
+
+	if err := log.Insert(ctx.Request.Context()); err != nil {
+		ctx.JSON(500, gin.H{"message": fmt.Sprintf("failed to log template application: %v", err)})
+		return
+	}
+
+	workers.RecordZoneState(connection, zoneID, ctx.GetString("username"))
+
+	ctx.JSON(200, gin.H{"zone": zone.Name, "template": template.Name, "mode": mode, "changes": changes, "applied": true})
+}
+
+// mergeRRSets adds the records of desired to the matching current rrsets
+// instead of replacing them. CNAME rrsets can only hold one record and are
+// always replaced.
+func mergeRRSets(current, desired []models.RRSet) []models.RRSet {
+	existing := make(map[string]models.RRSet, len(current))
+	for _, rr := range current {
+		existing[rr.Key()] = rr
+	}
+
+	merged := make([]models.RRSet, 0, len(desired))
+	for _, rr := range desired {
+		old, ok := existing[rr.Key()]
+		if !ok || rr.Type == "CNAME" {
+			merged = append(merged, rr)
+			continue
+		}
+
+		seen := map[string]bool{}
+		out := old
+		out.Records = append([]models.Record{}, old.Records...)
+		for _, rec := range old.Records {
+			seen[rec.Content] = true
+		}
+		for _, rec := range rr.Records {
+			if !seen[rec.Content] {
+				out.Records = append(out.Records, rec)
+			}
+		}
+		merged = append(merged, out)
+	}
+
+	return merged
+}
@@ -0,0 +1,324 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rafinhacuri/SanchezDNS/models"
+	"github.com/rafinhacuri/SanchezDNS/pdns"
+)
+
+func GetTsigKeys(ctx *gin.Context) {
+	allowed, connection := permission(ctx)
+	if !allowed {
+		return
+	}
+
+	client, err := pdns.New(connection)
+	if err != nil {
+		ctx.JSON(500, gin.H{"message": err.Error()})
+		return
+	}
+
+	ctxReq, cancel := context.WithTimeout(ctx.Request.Context(), 8*time.Second)
+	defer cancel()
+
+	keys, err := client.TsigKeys(ctxReq)
+	if err != nil {
+		pdnsError(ctx, err, "fetch TSIG keys")
+		return
+	}
+
+	for i := range keys {
+		keys[i].Key = ""
+	}
+
+	ctx.JSON(200, gin.H{"keys": keys})
+}
+
+func CreateTsigKey(ctx *gin.Context) {
+	allowed, connection := permission(ctx)
+	if !allowed {
+		return
+	}
+
+	var req models.TsigKeyRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(400, gin.H{"message": fmt.Sprintf("invalid request body: %v", err)})
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		ctx.JSON(400, gin.H{"message": fmt.Sprintf("validation error: %v", err)})
+		return
+	}
+
+	action := "import_tsigkey"
+	secret := req.Key
+	if secret == "" {
+		generated, err := models.GenerateTsigSecret(req.Algorithm)
+		if err != nil {
+			ctx.JSON(500, gin.H{"message": err.Error()})
+			return
+		}
+		secret = generated
+		action = "generate_tsigkey"
+	}
+
+	client, err := pdns.New(connection)
+	if err != nil {
+		ctx.JSON(500, gin.H{"message": err.Error()})
+		return
+	}
+
+	ctxReq, cancel := context.WithTimeout(ctx.Request.Context(), 8*time.Second)
+	defer cancel()
+
+	created, err := client.CreateTsigKey(ctxReq, models.PdnsTsigKey{Name: req.Name, Algorithm: req.Algorithm, Key: secret})
+	if err != nil {
+		pdnsError(ctx, err, "create TSIG key")
+		return
+	}
+
+	if created.Key == "" {
+		created.Key = secret
+	}
+
+	log := &models.Log{
+		Username:     ctx.GetString("username"),
+		IdConnection: ctx.Query("connection"),
+		Action:       action,
+		Details:      fmt.Sprintf("Created TSIG key %s (%s)", created.Name, created.Algorithm),
+		HostServer:   connection.Host,
+		CreatedAt:    time.Now(),
+	}
+
+	if err := log.Insert(ctx.Request.Context()); err != nil {
+		ctx.JSON(500, gin.H{"message": fmt.Sprintf("failed to log TSIG key creation: %v", err)})
+		return
+	}
+
+	ctx.JSON(201, gin.H{"message": "TSIG key created successfully, store the secret now as it will not be shown again", "key": created})
+}
+
+func RenameTsigKey(ctx *gin.Context) {
+	allowed, connection := permission(ctx)
+	if !allowed {
+		return
+	}
+
+	keyID := ctx.Query("id")
+	if keyID == "" {
+		ctx.JSON(400, gin.H{"message": "TSIG key ID is required"})
+		return
+	}
+
+	var req models.TsigRenameRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(400, gin.H{"message": fmt.Sprintf("invalid request body: %v", err)})
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		ctx.JSON(400, gin.H{"message": fmt.Sprintf("validation error: %v", err)})
+		return
+	}
+
+	client, err := pdns.New(connection)
+	if err != nil {
+		ctx.JSON(500, gin.H{"message": err.Error()})
+		return
+	}
+
+	ctxReq, cancel := context.WithTimeout(ctx.Request.Context(), 8*time.Second)
+	defer cancel()
+
+	current, err := client.TsigKey(ctxReq, keyID)
+	if err != nil {
+		pdnsError(ctx, err, "fetch TSIG key")
+		return
+	}
+
+	updated, err := client.UpdateTsigKey(ctxReq, keyID, models.PdnsTsigKey{Name: req.Name})
+	if err != nil {
+		pdnsError(ctx, err, "rename TSIG key")
+		return
+	}
+
+	updated.Key = ""
+
+	log := &models.Log{
+		Username:     ctx.GetString("username"),
+		IdConnection: ctx.Query("connection"),
+		Action:       "rename_tsigkey",
+		Details:      fmt.Sprintf("Renamed TSIG key %s -> %s", current.Name, updated.Name),
+		HostServer:   connection.Host,
+		CreatedAt:    time.Now(),
+	}
+
+	if err := log.Insert(ctx.Request.Context()); err != nil {
+		ctx.JSON(500, gin.H{"message": fmt.Sprintf("failed to log TSIG key rename: %v", err)})
+		return
+	}
+
+	ctx.JSON(200, gin.H{"message": "TSIG key renamed successfully", "key": updated})
+}
+
+func DeleteTsigKey(ctx *gin.Context) {
+	allowed, connection := permission(ctx)
+	if !allowed {
+		return
+	}
+
+	keyID := ctx.Query("id")
+	if keyID == "" {
+		ctx.JSON(400, gin.H{"message": "TSIG key ID is required"})
+		return
+	}
+
+	client, err := pdns.New(connection)
+	if err != nil {
+		ctx.JSON(500, gin.H{"message": err.Error()})
+		return
+	}
+
+	ctxReq, cancel := context.WithTimeout(ctx.Request.Context(), 8*time.Second)
+	defer cancel()
+
+	if err := client.DeleteTsigKey(ctxReq, keyID); err != nil {
+		pdnsError(ctx, err, "delete TSIG key")
+		return
+	}
+
+	log := &models.Log{
+		Username:     ctx.GetString("username"),
+		IdConnection: ctx.Query("connection"),
+		Action:       "delete_tsigkey",
+		Details:      fmt.Sprintf("Deleted TSIG key %s", keyID),
+		HostServer:   connection.Host,
+		CreatedAt:    time.Now(),
+	}
+
+	if err := log.Insert(ctx.Request.Context()); err != nil {
+		ctx.JSON(500, gin.H{"message": fmt.Sprintf("failed to log TSIG key deletion: %v", err)})
+		return
+	}
+
+	ctx.JSON(200, gin.H{"message": "TSIG key deleted successfully"})
+}
+
+func GetZoneTsigKeys(ctx *gin.Context) {
+	allowed, connection := permission(ctx)
+	if !allowed {
+		return
+	}
+
+	zoneID := ctx.Query("zone")
+	if zoneID == "" {
+		ctx.JSON(400, gin.H{"message": "zone ID is required"})
+		return
+	}
+
+	client, err := pdns.New(connection)
+	if err != nil {
+		ctx.JSON(500, gin.H{"message": err.Error()})
+		return
+	}
+
+	ctxReq, cancel := context.WithTimeout(ctx.Request.Context(), 8*time.Second)
+	defer cancel()
+
+	allowAxfr, err := client.Metadata(ctxReq, zoneID, "TSIG-ALLOW-AXFR")
+	if err != nil {
+		pdnsError(ctx, err, "fetch zone metadata")
+		return
+	}
+
+	masterTsig, err := client.Metadata(ctxReq, zoneID, "AXFR-MASTER-TSIG")
+	if err != nil {
+		pdnsError(ctx, err, "fetch zone metadata")
+		return
+	}
+
+	ctx.JSON(200, models.ZoneTsigRequest{AllowAxfr: allowAxfr, MasterTsig: masterTsig})
+}
+
+func SetZoneTsigKeys(ctx *gin.Context) {
+	allowed, connection := permission(ctx)
+	if !allowed {
+		return
+	}
+
+	zoneID := ctx.Query("zone")
+	if zoneID == "" {
+		ctx.JSON(400, gin.H{"message": "zone ID is required"})
+		return
+	}
+
+	var req models.ZoneTsigRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(400, gin.H{"message": fmt.Sprintf("invalid request body: %v", err)})
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		ctx.JSON(400, gin.H{"message": fmt.Sprintf("validation error: %v", err)})
+		return
+	}
+
+	client, err := pdns.New(connection)
+	if err != nil {
+		ctx.JSON(500, gin.H{"message": err.Error()})
+		return
+	}
+
+	ctxReq, cancel := context.WithTimeout(ctx.Request.Context(), 8*time.Second)
+	defer cancel()
+
+	keys, err := client.TsigKeys(ctxReq)
+	if err != nil {
+		pdnsError(ctx, err, "fetch TSIG keys")
+		return
+	}
+
+	known := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		known[k.Name] = true
+	}
+
+	for _, name := range append(append([]string{}, req.AllowAxfr...), req.MasterTsig...) {
+		if !known[name] {
+			ctx.JSON(400, gin.H{"message": fmt.Sprintf("TSIG key %s does not exist on this server", name)})
+			return
+		}
+	}
+
+	if err := client.SetMetadata(ctxReq, zoneID, "TSIG-ALLOW-AXFR", req.AllowAxfr); err != nil {
+		pdnsError(ctx, err, "update zone metadata")
+		return
+	}
+
+	if err := client.SetMetadata(ctxReq, zoneID, "AXFR-MASTER-TSIG", req.MasterTsig); err != nil {
+		pdnsError(ctx, err, "update zone metadata")
+		return
+	}
+
+	log := &models.Log{
+		Username:     ctx.GetString("username"),
+		IdConnection: ctx.Query("connection"),
+		Action:       "update_zone_tsigkeys",
+		Details:      fmt.Sprintf("Set TSIG-ALLOW-AXFR=%v AXFR-MASTER-TSIG=%v for zone %s", req.AllowAxfr, req.MasterTsig, zoneID),
+		Zone:         zoneID,
+		HostServer:   connection.Host,
+		CreatedAt:    time.Now(),
+	}
+
+	if err := log.Insert(ctx.Request.Context()); err != nil {
+		ctx.JSON(500, gin.H{"message": fmt.Sprintf("failed to log zone TSIG update: %v", err)})
+		return
+	}
+
+	ctx.JSON(200, gin.H{"message": "zone TSIG keys updated successfully"})
+}
@@ -0,0 +1,156 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rafinhacuri/SanchezDNS/db"
+	"github.com/rafinhacuri/SanchezDNS/models"
+	"github.com/rafinhacuri/SanchezDNS/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func GetUpdateKeys(ctx *gin.Context) {
+	if !ctx.GetBool("admin") {
+		ctx.JSON(403, gin.H{"error": "forbidden"})
+		return
+	}
+
+	opts := options.Find().SetSort(bson.M{"name": 1})
+
+	cursor, err := db.Database.Collection("update_keys").Find(ctx.Request.Context(), bson.M{}, opts)
+	if err != nil {
+		ctx.JSON(500, gin.H{"message": "failed to fetch update keys"})
+		return
+	}
+
+	keys := []models.UpdateKey{}
+	if err := cursor.All(ctx.Request.Context(), &keys); err != nil {
+		ctx.JSON(500, gin.H{"message": "failed to parse update keys"})
+		return
+	}
+
+	ctx.JSON(200, keys)
+}
+
+// InsertUpdateKey stores a TSIG key for the RFC 2136 update listener. The
+// secret is only returned in this response.
+func InsertUpdateKey(ctx *gin.Context) {
+	if !ctx.GetBool("admin") {
+		ctx.JSON(403, gin.H{"error": "forbidden"})
+		return
+	}
+
+	var req models.UpdateKeyRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(400, gin.H{"message": fmt.Sprintf("invalid request body: %v", err)})
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		ctx.JSON(400, gin.H{"message": fmt.Sprintf("validation error: %v", err)})
+		return
+	}
+
+	ctxReq, cancel := context.WithTimeout(ctx.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	connection, err := models.FindConnection(ctxReq, req.Connection)
+	if err != nil {
+		ctx.JSON(404, gin.H{"message": "connection not found"})
+		return
+	}
+
+	count, err := db.Database.Collection("update_keys").CountDocuments(ctxReq, bson.M{"name": req.Name})
+	if err != nil {
+		ctx.JSON(500, gin.H{"message": err.Error()})
+		return
+	}
+	if count > 0 {
+		ctx.JSON(409, gin.H{"message": "update key already exists"})
+		return
+	}
+
+	secret := req.Secret
+	if secret == "" {
+		if secret, err = models.GenerateTsigSecret(req.Algorithm); err != nil {
+			ctx.JSON(500, gin.H{"message": err.Error()})
+			return
+		}
+	}
+
+	encrypted, err := utils.Encrypt(secret)
+	if err != nil {
+		ctx.JSON(500, gin.H{"message": "failed to encrypt secret"})
+		return
+	}
+
+	username := ctx.GetString("username")
+
+	key := models.UpdateKey{
+		Name:         req.Name,
+		Algorithm:    req.Algorithm,
+		Secret:       encrypted,
+		IdConnection: connection.ID.Hex(),
+		Zones:        req.Zones,
+		Username:     req.Username,
+		CreatedBy:    username,
+		CreatedAt:    time.Now(),
+	}
+
+	result, err := db.Database.Collection("update_keys").InsertOne(ctxReq, key)
+	if err != nil {
+		ctx.JSON(500, gin.H{"message": err.Error()})
+		return
+	}
+
+	log := &models.Log{
+		Username:     username,
+		IdConnection: key.IdConnection,
+		Action:       "create_update_key",
+		Details:      fmt.Sprintf("User %s created DNS update key %s for %s", username, key.Name, key.Username),
+		HostServer:   connection.Host,
+		CreatedAt:    time.Now(),
+	}
+
+	_ = log.Insert(ctxReq)
+
+	ctx.JSON(201, gin.H{"message": "update key created successfully", "id": result.InsertedID, "name": key.Name, "algorithm": key.Algorithm, "secret": secret})
+}
+
+func DeleteUpdateKey(ctx *gin.Context) {
+	if !ctx.GetBool("admin") {
+		ctx.JSON(403, gin.H{"error": "forbidden"})
+		return
+	}
+
+	id, err := primitive.ObjectIDFromHex(ctx.Query("id"))
+	if err != nil {
+		ctx.JSON(400, gin.H{"message": "invalid update key ID"})
+		return
+	}
+
+	var key models.UpdateKey
+	if err := db.Database.Collection("update_keys").FindOneAndDelete(ctx.Request.Context(), bson.M{"_id": id}).Decode(&key); err != nil {
+		ctx.JSON(404, gin.H{"message": "update key not found"})
+		return
+	}
+
+	username := ctx.GetString("username")
+
+	log := &models.Log{
+		Username:     username,
+		IdConnection: key.IdConnection,
+		Action:       "delete_update_key",
+		Details:      fmt.Sprintf("User %s deleted DNS update key %s", username, key.Name),
+		CreatedAt:    time.Now(),
+	}
+
+	_ = log.Insert(ctx.Request.Context())
+
+	ctx.JSON(200, gin.H{"message": "update key deleted successfully"})
+}
@@ -59,15 +59,23 @@ func InsertUser(ctx *gin.Context) {
 		return
 	}
 	if count > 0 {
+		recordSecurityEvent(ctx, models.SecurityUserCreated, user.Email, models.OutcomeFailure, "email_exists")
 		ctx.JSON(400, gin.H{"message": "User with this email already exists"})
 		return
 	}
 
 	if _, err := db.Database.Collection("users").InsertOne(ctxReq, user); err != nil {
+		recordSecurityEvent(ctx, models.SecurityUserCreated, user.Email, models.OutcomeFailure, "error")
 		ctx.JSON(500, gin.H{"message": err.Error()})
 		return
 	}
 
+	reason := ""
+	if user.Level == "admin" {
+		reason = "first user, granted admin"
+	}
+	recordSecurityEvent(ctx, models.SecurityUserCreated, user.Email, models.OutcomeSuccess, reason)
+
 	ctx.JSON(201, gin.H{"message": "User created successfully"})
 }
 
@@ -87,6 +95,7 @@ func ChangePassword(ctx *gin.Context) {
 	username, _ := ctx.Get("username")
 
 	if username != request.Email {
+		recordSecurityEvent(ctx, models.SecurityPasswordChange, request.Email, models.OutcomeFailure, "not_own_account")
 		ctx.JSON(403, gin.H{"message": "You can only change your own password"})
 		return
 	}
@@ -109,13 +118,17 @@ func ChangePassword(ctx *gin.Context) {
 
 	result, err := db.Database.Collection("users").UpdateOne(ctxReq, bson.M{"email": request.Email}, update)
 	if err != nil {
+		recordSecurityEvent(ctx, models.SecurityPasswordChange, request.Email, models.OutcomeFailure, "error")
 		ctx.JSON(500, gin.H{"message": err.Error()})
 		return
 	}
 	if result.MatchedCount == 0 {
+		recordSecurityEvent(ctx, models.SecurityPasswordChange, request.Email, models.OutcomeFailure, "user_not_found")
 		ctx.JSON(404, gin.H{"message": "User not found"})
 		return
 	}
 
+	recordSecurityEvent(ctx, models.SecurityPasswordChange, request.Email, models.OutcomeSuccess, "")
+
 	ctx.JSON(200, gin.H{"message": "Password changed successfully"})
 }
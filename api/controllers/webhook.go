@@ -0,0 +1,255 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rafinhacuri/SanchezDNS/db"
+	"github.com/rafinhacuri/SanchezDNS/models"
+	"github.com/rafinhacuri/SanchezDNS/utils"
+	"github.com/rafinhacuri/SanchezDNS/workers"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func GetWebhooks(ctx *gin.Context) {
+	if !ctx.GetBool("admin") {
+		ctx.JSON(403, gin.H{"error": "forbidden"})
+		return
+	}
+
+	listByName[models.Webhook](ctx, "webhooks", "webhook")
+}
+
+// InsertWebhook creates a webhook subscription. The signing secret is only
+// returned in this response.
+func InsertWebhook(ctx *gin.Context) {
+	if !ctx.GetBool("admin") {
+		ctx.JSON(403, gin.H{"error": "forbidden"})
+		return
+	}
+
+	var req models.WebhookRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(400, gin.H{"message": fmt.Sprintf("invalid request body: %v", err)})
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		ctx.JSON(400, gin.H{"message": fmt.Sprintf("validation error: %v", err)})
+		return
+	}
+
+	secret := req.Secret
+	if secret == "" {
+		var err error
+		if secret, err = utils.RandomToken(32); err != nil {
+			ctx.JSON(500, gin.H{"message": err.Error()})
+			return
+		}
+	}
+
+	encrypted, err := utils.Encrypt(secret)
+	if err != nil {
+		ctx.JSON(500, gin.H{"message": "failed to encrypt secret"})
+		return
+	}
+
+	username := ctx.GetString("username")
+	now := time.Now()
+
+	hook := models.Webhook{
+		Name:        req.Name,
+		URL:         req.URL,
+		Secret:      encrypted,
+		Connections: req.Connections,
+		Zones:       req.Zones,
+		Actions:     req.Actions,
+		Enabled:     req.Enabled == nil || *req.Enabled,
+		CreatedBy:   username,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	ctxReq, cancel := context.WithTimeout(ctx.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	result, err := db.Database.Collection("webhooks").InsertOne(ctxReq, hook)
+	if err != nil {
+		ctx.JSON(500, gin.H{"message": err.Error()})
+		return
+	}
+
+	logAdminAction(ctxReq, username, "create_webhook", fmt.Sprintf("User %s created webhook %s for %s", username, hook.Name, hook.URL))
+
+	ctx.JSON(201, gin.H{"message": "webhook created successfully", "id": result.InsertedID, "secret": secret})
+}
+
+// EditWebhook replaces the settings of a webhook. The secret is kept unless
+// a new one is given.
+func EditWebhook(ctx *gin.Context) {
+	if !ctx.GetBool("admin") {
+		ctx.JSON(403, gin.H{"error": "forbidden"})
+		return
+	}
+
+	hook, err := workers.FindWebhook(ctx.Request.Context(), ctx.Query("id"))
+	if err != nil {
+		ctx.JSON(500, gin.H{"message": "failed to fetch webhook"})
+		return
+	}
+	if hook == nil {
+		ctx.JSON(404, gin.H{"message": "webhook not found"})
+		return
+	}
+
+	var req models.WebhookRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(400, gin.H{"message": fmt.Sprintf("invalid request body: %v", err)})
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		ctx.JSON(400, gin.H{"message": fmt.Sprintf("validation error: %v", err)})
+		return
+	}
+
+	update := bson.M{
+		"name":        req.Name,
+		"url":         req.URL,
+		"connections": req.Connections,
+		"zones":       req.Zones,
+		"actions":     req.Actions,
+		"enabled":     req.Enabled == nil || *req.Enabled,
+		"updatedAt":   time.Now(),
+	}
+
+	if req.Secret != "" {
+		encrypted, err := utils.Encrypt(req.Secret)
+		if err != nil {
+			ctx.JSON(500, gin.H{"message": "failed to encrypt secret"})
+			return
+		}
+		update["secret"] = encrypted
+	}
+
+	ctxReq, cancel := context.WithTimeout(ctx.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	if _, err := db.Database.Collection("webhooks").UpdateOne(ctxReq, bson.M{"_id": hook.ID}, bson.M{"$set": update}); err != nil {
+		ctx.JSON(500, gin.H{"message": err.Error()})
+		return
+	}
+
+	username := ctx.GetString("username")
+	logAdminAction(ctxReq, username, "edit_webhook", fmt.Sprintf("User %s edited webhook %s", username, req.Name))
+
+	ctx.JSON(200, gin.H{"message": "webhook updated successfully"})
+}
+
+func DeleteWebhook(ctx *gin.Context) {
+	if !ctx.GetBool("admin") {
+		ctx.JSON(403, gin.H{"error": "forbidden"})
+		return
+	}
+
+	hook, ok := deleteByID[models.Webhook](ctx, "webhooks", "webhook")
+	if !ok {
+		return
+	}
+
+	username := ctx.GetString("username")
+	logAdminAction(ctx.Request.Context(), username, "delete_webhook", fmt.Sprintf("User %s deleted webhook %s", username, hook.Name))
+
+	ctx.JSON(200, gin.H{"message": "webhook deleted successfully"})
+}
+
+// GetWebhookDeliveries returns the delivery log of a webhook, newest first,
+// optionally filtered by ?status=.
+func GetWebhookDeliveries(ctx *gin.Context) {
+	if !ctx.GetBool("admin") {
+		ctx.JSON(403, gin.H{"error": "forbidden"})
+		return
+	}
+
+	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(ctx.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 200 {
+		limit = 20
+	}
+
+	filter := bson.M{"idWebhook": ctx.Query("id")}
+	if status := ctx.Query("status"); status != "" {
+		filter["status"] = status
+	}
+
+	total, _ := db.Database.Collection("webhook_deliveries").CountDocuments(ctx.Request.Context(), filter)
+
+	opts := options.Find().
+		SetSort(bson.M{"createdAt": -1}).
+		SetSkip(int64((page - 1) * limit)).
+		SetLimit(int64(limit))
+
+	cursor, err := db.Database.Collection("webhook_deliveries").Find(ctx.Request.Context(), filter, opts)
+	if err != nil {
+		ctx.JSON(500, gin.H{"message": "failed to fetch webhook deliveries"})
+		return
+	}
+
+	deliveries := []models.WebhookDelivery{}
+	if err := cursor.All(ctx.Request.Context(), &deliveries); err != nil {
+		ctx.JSON(500, gin.H{"message": "failed to parse webhook deliveries"})
+		return
+	}
+
+	ctx.JSON(200, gin.H{"data": deliveries, "total": total, "page": page, "limit": limit})
+}
+
+// TestWebhook sends a "test" event to a webhook right away and returns the
+// outcome. The delivery is recorded and retried like any other.
+func TestWebhook(ctx *gin.Context) {
+	if !ctx.GetBool("admin") {
+		ctx.JSON(403, gin.H{"error": "forbidden"})
+		return
+	}
+
+	ctxReq, cancel := context.WithTimeout(ctx.Request.Context(), 20*time.Second)
+	defer cancel()
+
+	hook, err := workers.FindWebhook(ctxReq, ctx.Query("id"))
+	if err != nil {
+		ctx.JSON(500, gin.H{"message": "failed to fetch webhook"})
+		return
+	}
+	if hook == nil {
+		ctx.JSON(404, gin.H{"message": "webhook not found"})
+		return
+	}
+
+	username := ctx.GetString("username")
+	event := &models.Log{
+		Username:  username,
+		Action:    "test",
+		Details:   fmt.Sprintf("Test delivery requested by %s", username),
+		CreatedAt: time.Now(),
+	}
+
+	delivery, err := workers.QueueWebhookDelivery(ctxReq, hook, event)
+	if err != nil {
+		ctx.JSON(500, gin.H{"message": err.Error()})
+		return
+	}
+
+	if err := workers.DeliverWebhook(ctxReq, hook, delivery); err != nil {
+		ctx.JSON(502, gin.H{"message": fmt.Sprintf("test delivery failed: %v", err), "delivery": delivery})
+		return
+	}
+
+	ctx.JSON(200, gin.H{"message": "test delivery succeeded", "delivery": delivery})
+}
@@ -3,14 +3,15 @@ package controllers
 import (
 	"context"
 	"fmt"
+	"log/slog"
+	"net/http"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/go-resty/resty/v2"
-	"github.com/rafinhacuri/SanchezDNS/db"
 	"github.com/rafinhacuri/SanchezDNS/models"
-	"github.com/rafinhacuri/SanchezDNS/utils"
+	"github.com/rafinhacuri/SanchezDNS/pdns"
+	"github.com/rafinhacuri/SanchezDNS/workers"
 )
 
 func CreateZone(ctx *gin.Context) {
@@ -33,99 +34,136 @@ func CreateZone(ctx *gin.Context) {
 	domain := strings.TrimSuffix(req.Domain, ".")
 	domainWithDot := domain + "."
 
-	soaMname := strings.TrimSuffix(req.Soa.StartOfAuthority, ".")
-	soaRname := strings.TrimSuffix(req.Soa.Email, ".")
-
-	plainKey, err := utils.Decrypt(connection.ApiKey)
+	var rrsets []map[string]any
+	details := fmt.Sprintf("Created zone %s", domain)
+
+	if req.Template != "" {
+		template, err := findTemplate(ctx.Request.Context(), req.Template)
+		if err != nil {
+			ctx.JSON(404, gin.H{"message": "template not found"})
+			return
+		}
+
+		if req.Soa == (models.Soa{}) {
+			soa, err := template.RenderSoa(domain, req.Variables)
+			if err != nil {
+				ctx.JSON(400, gin.H{"message": fmt.Sprintf("template error: %v", err)})
+				return
+			}
+			if soa == nil {
+				ctx.JSON(400, gin.H{"message": "validation error: soa is required when the template has no default SOA"})
+				return
+			}
+			req.Soa = *soa
+		}
+
+		if err := req.Soa.Validate(); err != nil {
+			ctx.JSON(400, gin.H{"message": fmt.Sprintf("validation error: soa: %v", err)})
+			return
+		}
+
+		rendered, err := template.Render(domain, req.Variables, ctx.GetString("username"))
+		if err != nil {
+			ctx.JSON(400, gin.H{"message": fmt.Sprintf("template error: %v", err)})
+			return
+		}
+
+		for i := range rendered {
+			rrsets = append(rrsets, rendered[i].Patch())
+		}
+
+		details = fmt.Sprintf("Created zone %s from template %s", domain, template.Name)
+	}
+
+	client, err := pdns.New(connection)
 	if err != nil {
-		ctx.JSON(500, gin.H{"message": fmt.Sprintf("failed to decrypt api key: %v", err)})
+		ctx.JSON(500, gin.H{"message": err.Error()})
 		return
 	}
 
 	ctxReq, cancel := context.WithTimeout(ctx.Request.Context(), 8*time.Second)
 	defer cancel()
 
-	base := utils.NormalizeBase(connection.Host)
-
-	httpc := resty.New().SetBaseURL(base).SetHeader("X-API-Key", plainKey).SetHeader("Accept", "application/json").SetTimeout(6 * time.Second).SetRetryCount(2)
+	if err := createZone(ctxReq, client, domainWithDot, req.Soa, rrsets); err != nil {
+		pdnsError(ctx, err, "create zone")
+		return
+	}
 
-	zonePayload := map[string]any{
-		"name":         domainWithDot,
-		"kind":         "Native",
-		"soa_edit_api": "DEFAULT",
+	log := &models.Log{
+		Username:     ctx.GetString("username"),
+		IdConnection: ctx.Query("connection"),
+		Action:       "create_zone",
+		Details:      details,
+		Zone:         domain,
+		HostServer:   connection.Host,
+		CreatedAt:    time.Now(),
 	}
 
-	resp, err := httpc.R().SetContext(ctxReq).SetBody(zonePayload).Post(fmt.Sprintf("/api/v1/servers/%s/zones", connection.ServerId))
+	err = log.Insert(ctx.Request.Context())
 
 	if err != nil {
-		ctx.JSON(502, gin.H{"message": fmt.Sprintf("failed to create zone: %v", err)})
-		return
-	}
-
-	if resp.IsError() {
-		ctx.JSON(resp.StatusCode(), gin.H{"message": fmt.Sprintf("PowerDNS error: %v", resp.String())})
+		ctx.JSON(500, gin.H{"message": fmt.Sprintf("failed to log zone creation: %v", err)})
 		return
 	}
 
-	dnssecPayload := map[string]any{
-		"active":  true,
-		"keytype": "ksk",
-	}
-
-	respDNSSEC, err := httpc.R().SetContext(ctxReq).SetBody(dnssecPayload).Post(fmt.Sprintf("/api/v1/servers/%s/zones/%s/cryptokeys", connection.ServerId, domainWithDot))
+	workers.RecordZoneState(connection, domainWithDot, ctx.GetString("username"))
 
-	if err != nil {
-		fmt.Printf("failed to enable DNSSEC for zone %s: %v\n", domain, err)
-	}
-	if respDNSSEC.IsError() {
-		fmt.Printf("PowerDNS DNSSEC PATCH error: status=%d, body=%s\n", respDNSSEC.StatusCode(), respDNSSEC.String())
-	}
+	ctx.JSON(201, gin.H{"message": "zone created successfully"})
+}
 
-	rrsets := []map[string]any{
+// createZone creates a native zone with DNSSEC enabled. The SOA and any
+// extra rrsets are part of the creation request, so PowerDNS either creates
+// the whole zone or nothing.
+func createZone(ctx context.Context, client *pdns.Client, domainWithDot string, soa models.Soa, rrsets []map[string]any) error {
+	initial := []map[string]any{
 		{
-			"name":       domainWithDot,
-			"type":       "SOA",
-			"ttl":        3600,
-			"changetype": "REPLACE",
+			"name": domainWithDot,
+			"type": "SOA",
+			"ttl":  3600,
 			"records": []map[string]any{
 				{
-					"content":  fmt.Sprintf("%s. %s. 1 %d %d %d %d", soaMname, soaRname, req.Soa.Refresh, req.Soa.Retry, req.Soa.Expire, req.Soa.NegativeCacheTtl),
+					"content":  soa.Content(1),
 					"disabled": false,
 				},
 			},
 		},
 	}
 
-	body := map[string]any{
-		"rrsets": rrsets,
-	}
+	for _, rrset := range rrsets {
+		if rrset["changetype"] == "DELETE" || rrset["type"] == "SOA" {
+			continue
+		}
 
-	respPatch, err := httpc.R().SetContext(ctxReq).SetBody(body).Patch(fmt.Sprintf("/api/v1/servers/%s/zones/%s", connection.ServerId, domainWithDot))
-	if err != nil {
-		fmt.Printf("failed to create mandatory records for zone %s: %v\n", domain, err)
+		rr := make(map[string]any, len(rrset))
+		for k, v := range rrset {
+			if k != "changetype" {
+				rr[k] = v
+			}
+		}
+		initial = append(initial, rr)
 	}
-	if respPatch.IsError() {
-		fmt.Printf("PowerDNS PATCH error: status=%d, body=%s\n", respPatch.StatusCode(), respPatch.String())
+
+	zonePayload := map[string]any{
+		"name":         domainWithDot,
+		"kind":         "Native",
+		"soa_edit_api": "DEFAULT",
+		"rrsets":       initial,
 	}
 
-	log := &models.Log{
-		Username:     ctx.GetString("username"),
-		IdConnection: ctx.Query("connection"),
-		Action:       "create_zone",
-		Details:      fmt.Sprintf("Created zone %s", domain),
-		Zone:         domain,
-		HostServer:   connection.Host,
-		CreatedAt:    time.Now(),
+	if err := client.Do(ctx, http.MethodPost, client.Path("/zones"), zonePayload, nil); err != nil {
+		return err
 	}
 
-	_, err = db.Database.Collection("logs").InsertOne(ctx.Request.Context(), log)
+	dnssecPayload := map[string]any{
+		"active":  true,
+		"keytype": "ksk",
+	}
 
-	if err != nil {
-		ctx.JSON(500, gin.H{"message": fmt.Sprintf("failed to log zone creation: %v", err)})
-		return
+	if err := client.Do(ctx, http.MethodPost, client.Path("/zones/%s/cryptokeys", domainWithDot), dnssecPayload, nil); err != nil {
+		slog.Error("failed to enable DNSSEC", "zone", domainWithDot, "error", err)
 	}
 
-	ctx.JSON(201, gin.H{"message": "zone created successfully"})
+	return nil
 }
 
 func DeleteZone(ctx *gin.Context) {
@@ -140,20 +178,16 @@ func DeleteZone(ctx *gin.Context) {
 		return
 	}
 
-	plainKey, err := utils.Decrypt(connection.ApiKey)
+	client, err := pdns.New(connection)
 	if err != nil {
-		ctx.JSON(500, gin.H{"message": fmt.Sprintf("failed to decrypt api key: %v", err)})
+		ctx.JSON(500, gin.H{"message": err.Error()})
 		return
 	}
 
 	ctxReq, cancel := context.WithTimeout(ctx.Request.Context(), 8*time.Second)
 	defer cancel()
 
-	base := utils.NormalizeBase(connection.Host)
-
-	httpc := resty.New().SetBaseURL(base).SetHeader("X-API-Key", plainKey).SetHeader("Accept", "application/json").SetTimeout(6 * time.Second).SetRetryCount(2)
-
-	resp, err := httpc.R().SetContext(ctxReq).Delete(fmt.Sprintf("/api/v1/servers/%s/zones/%s", connection.ServerId, zoneID))
+	resp, err := client.R(ctxReq).Delete(client.Path("/zones/%s", zoneID))
 
 	if err != nil {
 		ctx.JSON(502, gin.H{"message": fmt.Sprintf("failed to delete zone: %v", err)})
@@ -175,12 +209,16 @@ func DeleteZone(ctx *gin.Context) {
 		CreatedAt:    time.Now(),
 	}
 
-	_, err = db.Database.Collection("logs").InsertOne(ctx.Request.Context(), log)
+	err = log.Insert(ctx.Request.Context())
 	if err != nil {
 		ctx.JSON(500, gin.H{"message": fmt.Sprintf("failed to log zone deletion: %v", err)})
 		return
 	}
 
+	if err := workers.ForgetZoneState(ctx.Request.Context(), ctx.Query("connection"), zoneID); err != nil {
+		slog.Error("failed to remove known zone state", "zone", zoneID, "error", err)
+	}
+
 	ctx.JSON(200, gin.H{"message": "zone deleted successfully"})
 }
 
@@ -207,19 +245,15 @@ func UpdateSOA(ctx *gin.Context) {
 		return
 	}
 
-	plainKey, err := utils.Decrypt(connection.ApiKey)
+	client, err := pdns.New(connection)
 	if err != nil {
-		ctx.JSON(500, gin.H{"message": fmt.Sprintf("failed to decrypt api key: %v", err)})
+		ctx.JSON(500, gin.H{"message": err.Error()})
 		return
 	}
 
 	ctxReq, cancel := context.WithTimeout(ctx.Request.Context(), 8*time.Second)
 	defer cancel()
 
-	base := utils.NormalizeBase(connection.Host)
-
-	httpc := resty.New().SetBaseURL(base).SetHeader("X-API-Key", plainKey).SetHeader("Accept", "application/json").SetTimeout(6 * time.Second).SetRetryCount(2)
-
 	soaName := strings.TrimSuffix(req.StartOfAuthority, ".")
 	soaEmail := strings.TrimSuffix(req.Email, ".")
 
@@ -242,7 +276,7 @@ func UpdateSOA(ctx *gin.Context) {
 		"rrsets": rrsets,
 	}
 
-	resp, err := httpc.R().SetContext(ctxReq).SetBody(body).Patch(fmt.Sprintf("/api/v1/servers/%s/zones/%s", connection.ServerId, zoneID))
+	resp, err := client.R(ctxReq).SetBody(body).Patch(client.Path("/zones/%s", zoneID))
 
 	if err != nil {
 		ctx.JSON(502, gin.H{"message": fmt.Sprintf("failed to update SOA record: %v", err)})
@@ -264,12 +298,14 @@ func UpdateSOA(ctx *gin.Context) {
 		CreatedAt:    time.Now(),
 	}
 
-	_, err = db.Database.Collection("logs").InsertOne(ctx.Request.Context(), log)
+	err = log.Insert(ctx.Request.Context())
 
 	if err != nil {
 		ctx.JSON(500, gin.H{"message": fmt.Sprintf("failed to log SOA update: %v", err)})
 		return
 	}
 
+	workers.RecordZoneState(connection, zoneID, ctx.GetString("username"))
+
 	ctx.JSON(200, gin.H{"message": "SOA record updated successfully"})
 }
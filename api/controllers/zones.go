@@ -7,9 +7,8 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/go-resty/resty/v2"
 	"github.com/rafinhacuri/SanchezDNS/models"
-	"github.com/rafinhacuri/SanchezDNS/utils"
+	"github.com/rafinhacuri/SanchezDNS/pdns"
 )
 
 func GetZones(ctx *gin.Context) {
@@ -18,21 +17,17 @@ func GetZones(ctx *gin.Context) {
 		return
 	}
 
-	plainKey, err := utils.Decrypt(connection.ApiKey)
+	client, err := pdns.New(connection)
 	if err != nil {
-		ctx.JSON(500, gin.H{"message": fmt.Sprintf("failed to decrypt api key: %v", err)})
+		ctx.JSON(500, gin.H{"message": err.Error()})
 		return
 	}
 
 	ctxReq, cancel := context.WithTimeout(ctx.Request.Context(), 8*time.Second)
 	defer cancel()
 
-	base := utils.NormalizeBase(connection.Host)
-
-	httpc := resty.New().SetBaseURL(base).SetHeader("X-API-Key", plainKey).SetHeader("Accept", "application/json").SetTimeout(6 * time.Second).SetRetryCount(2)
-
 	var zones []models.PdnsZone
-	zonesResp, err := httpc.R().SetContext(ctxReq).SetResult(&zones).Get(fmt.Sprintf("/api/v1/servers/%s/zones", connection.ServerId))
+	zonesResp, err := client.R(ctxReq).SetResult(&zones).Get(client.Path("/zones"))
 
 	if err != nil {
 		ctx.JSON(http.StatusBadGateway, gin.H{"message": fmt.Sprintf("failed to fetch zones: %v", err)})
@@ -0,0 +1,243 @@
+// Package dnsupdate implements an RFC 2136 dynamic update listener that
+// turns TSIG-signed UPDATE messages into PowerDNS API calls.
+package dnsupdate
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/rafinhacuri/SanchezDNS/db"
+	"github.com/rafinhacuri/SanchezDNS/models"
+	"github.com/rafinhacuri/SanchezDNS/pdns"
+	"github.com/rafinhacuri/SanchezDNS/utils"
+	"github.com/rafinhacuri/SanchezDNS/workers"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Start runs the update listener on DNS_UPDATE_LISTEN (for example
+// ":5353") over UDP and TCP. It does nothing when the variable is unset.
+func Start(ctx context.Context) {
+	addr := os.Getenv("DNS_UPDATE_LISTEN")
+	if addr == "" {
+		return
+	}
+
+	for _, network := range []string{"udp", "tcp"} {
+		srv := &dns.Server{
+			Addr:          addr,
+			Net:           network,
+			Handler:       dns.HandlerFunc(serveUpdate),
+			TsigProvider:  keyProvider{},
+			MsgAcceptFunc: acceptUpdate,
+		}
+
+		go func() {
+			if err := srv.ListenAndServe(); err != nil {
+				slog.Error("DNS update listener stopped", "network", network, "addr", addr, "error", err)
+			}
+		}()
+
+		go func() {
+			<-ctx.Done()
+			_ = srv.Shutdown()
+		}()
+	}
+}
+
+func acceptUpdate(dh dns.Header) dns.MsgAcceptAction {
+	if dh.Bits&(1<<15) != 0 {
+		return dns.MsgIgnore
+	}
+	if opcode := int(dh.Bits>>11) & 0xF; opcode != dns.OpcodeUpdate {
+		return dns.MsgRejectNotImplemented
+	}
+	if dh.Qdcount != 1 {
+		return dns.MsgReject
+	}
+	return dns.MsgAccept
+}
+
+func findKey(ctx context.Context, name string) (*models.UpdateKey, error) {
+	var key models.UpdateKey
+	err := db.Database.Collection("update_keys").FindOne(ctx, bson.M{"name": strings.ToLower(models.Fqdn(name))}).Decode(&key)
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// keyProvider verifies and signs messages with the keys stored in the
+// update_keys collection.
+type keyProvider struct{}
+
+func (keyProvider) Generate(msg []byte, t *dns.TSIG) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	key, err := findKey(ctx, t.Hdr.Name)
+	if err != nil {
+		return nil, dns.ErrSecret
+	}
+	if dns.CanonicalName(key.Algorithm) != dns.CanonicalName(t.Algorithm) {
+		return nil, dns.ErrKeyAlg
+	}
+
+	plain, err := utils.Decrypt(key.Secret)
+	if err != nil {
+		return nil, dns.ErrSecret
+	}
+	secret, err := base64.StdEncoding.DecodeString(plain)
+	if err != nil {
+		return nil, dns.ErrSecret
+	}
+
+	var h func() hash.Hash
+	switch dns.CanonicalName(t.Algorithm) {
+	case dns.HmacSHA1:
+		h = sha1.New
+	case dns.HmacSHA224:
+		h = sha256.New224
+	case dns.HmacSHA256:
+		h = sha256.New
+	case dns.HmacSHA384:
+		h = sha512.New384
+	case dns.HmacSHA512:
+		h = sha512.New
+	default:
+		return nil, dns.ErrKeyAlg
+	}
+
+	mac := hmac.New(h, secret)
+	mac.Write(msg)
+	return mac.Sum(nil), nil
+}
+
+func (p keyProvider) Verify(msg []byte, t *dns.TSIG) error {
+	expected, err := p.Generate(msg, t)
+	if err != nil {
+		return err
+	}
+	mac, err := hex.DecodeString(t.MAC)
+	if err != nil {
+		return err
+	}
+	if !hmac.Equal(expected, mac) {
+		return dns.ErrSig
+	}
+	return nil
+}
+
+func serveUpdate(w dns.ResponseWriter, r *dns.Msg) {
+	m := new(dns.Msg)
+	m.SetRcode(r, handleUpdate(w, r))
+
+	if t := r.IsTsig(); t != nil && w.TsigStatus() == nil {
+		m.SetTsig(t.Hdr.Name, t.Algorithm, 300, time.Now().Unix())
+	}
+
+	if err := w.WriteMsg(m); err != nil {
+		slog.Error("failed to answer DNS update", "remote", w.RemoteAddr().String(), "error", err)
+	}
+}
+
+func handleUpdate(w dns.ResponseWriter, r *dns.Msg) int {
+	t := r.IsTsig()
+	if t == nil {
+		return dns.RcodeRefused
+	}
+	if err := w.TsigStatus(); err != nil {
+		return dns.RcodeNotAuth
+	}
+
+	zoneName := strings.ToLower(r.Question[0].Name)
+	if r.Question[0].Qtype != dns.TypeSOA {
+		return dns.RcodeFormatError
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	key, err := findKey(ctx, t.Hdr.Name)
+	if err != nil || !key.AllowsZone(zoneName) {
+		return dns.RcodeNotAuth
+	}
+
+	connection, err := models.FindConnection(ctx, key.IdConnection)
+	if err != nil {
+		return dns.RcodeNotAuth
+	}
+
+	var user models.User
+	if err := db.Database.Collection("users").FindOne(ctx, bson.M{"email": key.Username}).Decode(&user); err != nil {
+		return dns.RcodeNotAuth
+	}
+	if user.Level != "admin" && !slices.Contains(connection.Users, key.Username) {
+		return dns.RcodeNotAuth
+	}
+
+	client, err := pdns.New(connection)
+	if err != nil {
+		return dns.RcodeServerFailure
+	}
+
+	zone, err := client.Zone(ctx, zoneName)
+	if err != nil {
+		return dns.RcodeNotAuth
+	}
+
+	state := newZoneState(zone)
+
+	if rcode := state.checkPrerequisites(r.Answer); rcode != dns.RcodeSuccess {
+		return rcode
+	}
+	if rcode := state.prescan(r.Ns); rcode != dns.RcodeSuccess {
+		return rcode
+	}
+
+	state.apply(r.Ns)
+
+	changes := state.changes()
+	if len(changes) == 0 {
+		return dns.RcodeSuccess
+	}
+
+	if err := client.PatchZone(ctx, zoneName, models.PatchChanges(changes)); err != nil {
+		slog.Error("failed to apply DNS update", "zone", zoneName, "key", key.Name, "error", err)
+		return dns.RcodeServerFailure
+	}
+
+	now := time.Now()
+	_, _ = db.Database.Collection("update_keys").UpdateOne(ctx, bson.M{"_id": key.ID}, bson.M{"$set": bson.M{"lastUsedAt": now}})
+
+	log := &models.Log{
+		Username:     key.Username,
+		IdConnection: key.IdConnection,
+		Action:       "dns_update",
+		Details:      fmt.Sprintf("Applied RFC 2136 update from %s with key %s (%d rrsets changed)", w.RemoteAddr().String(), key.Name, len(changes)),
+		Zone:         zoneName,
+		HostServer:   connection.Host,
+		Changes:      changes,
+		CreatedAt:    now,
+	}
+
+	if err := log.Insert(ctx); err != nil {
+		slog.Error("failed to log DNS update", "zone", zoneName, "error", err)
+	}
+
+	workers.RecordZoneState(connection, zoneName, key.Username)
+
+	return dns.RcodeSuccess
+}
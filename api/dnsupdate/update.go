@@ -0,0 +1,264 @@
+package dnsupdate
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/miekg/dns"
+	"github.com/rafinhacuri/SanchezDNS/models"
+)
+
+// zoneState is a working copy of a zone that RFC 2136 prerequisites are
+// checked against and updates are applied to.
+type zoneState struct {
+	apex     string
+	original map[string]models.RRSet
+	rrsets   map[string]*models.RRSet
+	touched  map[string]bool
+}
+
+func newZoneState(zone *models.Zone) *zoneState {
+	s := &zoneState{
+		apex:     strings.ToLower(zone.Name),
+		original: map[string]models.RRSet{},
+		rrsets:   map[string]*models.RRSet{},
+		touched:  map[string]bool{},
+	}
+	for _, rr := range zone.RRSets {
+		s.original[rr.Key()] = rr
+		clone := rr
+		clone.Records = slices.Clone(rr.Records)
+		s.rrsets[rr.Key()] = &clone
+	}
+	return s
+}
+
+func rrKey(name string, rrtype uint16) string {
+	return strings.ToLower(models.Fqdn(name)) + "/" + dns.TypeToString[rrtype]
+}
+
+// rdata returns the presentation form of the record data, which is what
+// PowerDNS expects as record content.
+func rdata(rr dns.RR) string {
+	return strings.TrimPrefix(rr.String(), rr.Header().String())
+}
+
+func (s *zoneState) nameInUse(name string) bool {
+	prefix := strings.ToLower(models.Fqdn(name)) + "/"
+	for key, rr := range s.rrsets {
+		if strings.HasPrefix(key, prefix) && len(rr.Records) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *zoneState) rrset(name string, rrtype uint16) *models.RRSet {
+	rr, ok := s.rrsets[rrKey(name, rrtype)]
+	if !ok || len(rr.Records) == 0 {
+		return nil
+	}
+	return rr
+}
+
+func (s *zoneState) contents(name string, rrtype uint16) []string {
+	rr := s.rrset(name, rrtype)
+	if rr == nil {
+		return nil
+	}
+	contents := make([]string, 0, len(rr.Records))
+	for _, rec := range rr.Records {
+		contents = append(contents, strings.ToLower(rec.Content))
+	}
+	slices.Sort(contents)
+	return contents
+}
+
+// checkPrerequisites evaluates the prerequisite section as described in
+// RFC 2136 section 3.2 and returns the resulting rcode.
+func (s *zoneState) checkPrerequisites(prereqs []dns.RR) int {
+	exact := map[string][]string{}
+	exactNames := map[string]dns.RR{}
+
+	for _, rr := range prereqs {
+		h := rr.Header()
+		if !models.InZone(h.Name, s.apex) {
+			return dns.RcodeNotZone
+		}
+		if h.Ttl != 0 {
+			return dns.RcodeFormatError
+		}
+
+		switch h.Class {
+		case dns.ClassANY:
+			if h.Rrtype == dns.TypeANY {
+				if !s.nameInUse(h.Name) {
+					return dns.RcodeNameError
+				}
+			} else if s.rrset(h.Name, h.Rrtype) == nil {
+				return dns.RcodeNXRrset
+			}
+		case dns.ClassNONE:
+			if h.Rrtype == dns.TypeANY {
+				if s.nameInUse(h.Name) {
+					return dns.RcodeYXDomain
+				}
+			} else if s.rrset(h.Name, h.Rrtype) != nil {
+				return dns.RcodeYXRrset
+			}
+		case dns.ClassINET:
+			key := rrKey(h.Name, h.Rrtype)
+			exact[key] = append(exact[key], strings.ToLower(rdata(rr)))
+			exactNames[key] = rr
+		default:
+			return dns.RcodeFormatError
+		}
+	}
+
+	for key, want := range exact {
+		h := exactNames[key].Header()
+		slices.Sort(want)
+		if !slices.Equal(slices.Compact(want), s.contents(h.Name, h.Rrtype)) {
+			return dns.RcodeNXRrset
+		}
+	}
+
+	return dns.RcodeSuccess
+}
+
+// prescan validates the update section as described in RFC 2136 section
+// 3.4.1 before anything is changed.
+func (s *zoneState) prescan(updates []dns.RR) int {
+	for _, rr := range updates {
+		h := rr.Header()
+		if !models.InZone(h.Name, s.apex) {
+			return dns.RcodeNotZone
+		}
+
+		switch h.Class {
+		case dns.ClassINET:
+			if h.Rrtype == dns.TypeANY || h.Rrtype == dns.TypeAXFR || h.Rrtype == dns.TypeIXFR {
+				return dns.RcodeFormatError
+			}
+		case dns.ClassANY:
+			if h.Ttl != 0 || h.Rrtype == dns.TypeAXFR || h.Rrtype == dns.TypeIXFR {
+				return dns.RcodeFormatError
+			}
+		case dns.ClassNONE:
+			if h.Ttl != 0 || h.Rrtype == dns.TypeANY || h.Rrtype == dns.TypeAXFR || h.Rrtype == dns.TypeIXFR {
+				return dns.RcodeFormatError
+			}
+		default:
+			return dns.RcodeFormatError
+		}
+	}
+	return dns.RcodeSuccess
+}
+
+// apply processes the update section as described in RFC 2136 section
+// 3.4.2. SOA changes are ignored since PowerDNS maintains the serial, and
+// the apex NS rrset is never emptied.
+func (s *zoneState) apply(updates []dns.RR) {
+	for _, rr := range updates {
+		h := rr.Header()
+		name := strings.ToLower(models.Fqdn(h.Name))
+		apex := name == s.apex
+
+		if h.Rrtype == dns.TypeSOA {
+			continue
+		}
+
+		switch h.Class {
+		case dns.ClassINET:
+			if h.Rrtype == dns.TypeCNAME && s.hasOtherThanCNAME(name) {
+				continue
+			}
+			if h.Rrtype != dns.TypeCNAME && s.rrset(name, dns.TypeCNAME) != nil {
+				continue
+			}
+			s.add(name, h.Rrtype, h.Ttl, rdata(rr))
+
+		case dns.ClassANY:
+			if h.Rrtype == dns.TypeANY {
+				prefix := name + "/"
+				for key, existing := range s.rrsets {
+					if !strings.HasPrefix(key, prefix) || (apex && (existing.Type == "SOA" || existing.Type == "NS")) {
+						continue
+					}
+					s.clear(key)
+				}
+				continue
+			}
+			if apex && h.Rrtype == dns.TypeNS {
+				continue
+			}
+			s.clear(rrKey(name, h.Rrtype))
+
+		case dns.ClassNONE:
+			if apex && h.Rrtype == dns.TypeNS && len(s.contents(name, dns.TypeNS)) <= 1 {
+				continue
+			}
+			s.remove(name, h.Rrtype, rdata(rr))
+		}
+	}
+}
+
+func (s *zoneState) hasOtherThanCNAME(name string) bool {
+	prefix := name + "/"
+	for key, rr := range s.rrsets {
+		if strings.HasPrefix(key, prefix) && rr.Type != "CNAME" && len(rr.Records) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *zoneState) add(name string, rrtype uint16, ttl uint32, content string) {
+	key := rrKey(name, rrtype)
+	rr, ok := s.rrsets[key]
+	if !ok {
+		rr = &models.RRSet{Name: name, Type: dns.TypeToString[rrtype]}
+		s.rrsets[key] = rr
+	}
+
+	if rrtype == dns.TypeCNAME {
+		rr.Records = nil
+	}
+	if !slices.ContainsFunc(rr.Records, func(rec models.Record) bool { return strings.EqualFold(rec.Content, content) }) {
+		rr.Records = append(rr.Records, models.Record{Content: content})
+	}
+	rr.TTL = int(ttl)
+	s.touched[key] = true
+}
+
+func (s *zoneState) clear(key string) {
+	if rr, ok := s.rrsets[key]; ok {
+		rr.Records = nil
+		s.touched[key] = true
+	}
+}
+
+func (s *zoneState) remove(name string, rrtype uint16, content string) {
+	key := rrKey(name, rrtype)
+	rr, ok := s.rrsets[key]
+	if !ok {
+		return
+	}
+	rr.Records = slices.DeleteFunc(rr.Records, func(rec models.Record) bool { return strings.EqualFold(rec.Content, content) })
+	s.touched[key] = true
+}
+
+// changes returns the rrset changes between the zone as fetched and the
+// working copy.
+func (s *zoneState) changes() []models.RRSetChange {
+	var current, desired []models.RRSet
+	for key := range s.touched {
+		if rr, ok := s.original[key]; ok {
+			current = append(current, rr)
+		}
+		if rr := s.rrsets[key]; len(rr.Records) > 0 {
+			desired = append(desired, *rr)
+		}
+	}
+	return models.DiffRRSets(current, desired, true)
+}
@@ -0,0 +1,262 @@
+package dnsupdate
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/miekg/dns"
+	"github.com/rafinhacuri/SanchezDNS/models"
+)
+
+// fixtureZone is the zone every test starts from.
+func fixtureZone() *models.Zone {
+	rrset := func(name, rrType string, contents ...string) models.RRSet {
+		rr := models.RRSet{Name: name, Type: rrType, TTL: 3600}
+		for _, c := range contents {
+			rr.Records = append(rr.Records, models.Record{Content: c})
+		}
+		return rr
+	}
+
+	return &models.Zone{
+		Name: "example.com.",
+		RRSets: []models.RRSet{
+			rrset("example.com.", "SOA", "ns1.example.com. hostmaster.example.com. 1 10800 3600 604800 3600"),
+			rrset("example.com.", "NS", "ns1.example.com."),
+			rrset("example.com.", "A", "192.0.2.10"),
+			rrset("www.example.com.", "A", "192.0.2.1", "192.0.2.2"),
+			rrset("alias.example.com.", "CNAME", "www.example.com."),
+		},
+	}
+}
+
+// rr parses a record in presentation form. Prerequisites and deletions
+// without rdata are given as "<name> [<ttl>] <class> <type>".
+func rr(t *testing.T, s string) dns.RR {
+	t.Helper()
+
+	switch f := strings.Fields(s); len(f) {
+	case 3:
+		return &dns.RR_Header{Name: f[0], Class: dns.StringToClass[f[1]], Rrtype: dns.StringToType[f[2]]}
+	case 4:
+		if ttl, err := strconv.ParseUint(f[1], 10, 32); err == nil {
+			return &dns.RR_Header{Name: f[0], Ttl: uint32(ttl), Class: dns.StringToClass[f[2]], Rrtype: dns.StringToType[f[3]]}
+		}
+	}
+
+	parsed, err := dns.NewRR(s)
+	if err != nil {
+		t.Fatalf("dns.NewRR(%q): %v", s, err)
+	}
+	return parsed
+}
+
+func rrs(t *testing.T, records []string) []dns.RR {
+	t.Helper()
+
+	parsed := make([]dns.RR, 0, len(records))
+	for _, s := range records {
+		parsed = append(parsed, rr(t, s))
+	}
+	return parsed
+}
+
+// summary renders changes as "<action> <name>/<type> <after>" lines.
+func summary(changes []models.RRSetChange) []string {
+	lines := []string{}
+	for _, c := range changes {
+		lines = append(lines, fmt.Sprintf("%s %s/%s %v", c.Action, c.Name, c.Type, c.After))
+	}
+	return lines
+}
+
+func TestCheckPrerequisites(t *testing.T) {
+	tests := []struct {
+		name    string
+		prereqs []string
+		want    int
+	}{
+		{"none", nil, dns.RcodeSuccess},
+		{"rrset exists", []string{"www.example.com. ANY A"}, dns.RcodeSuccess},
+		{"rrset does not exist", []string{"www.example.com. ANY AAAA"}, dns.RcodeNXRrset},
+		{"name in use", []string{"www.example.com. ANY ANY"}, dns.RcodeSuccess},
+		{"name not in use", []string{"new.example.com. ANY ANY"}, dns.RcodeNameError},
+		{"rrset absent", []string{"www.example.com. NONE AAAA"}, dns.RcodeSuccess},
+		{"rrset present", []string{"www.example.com. NONE A"}, dns.RcodeYXRrset},
+		{"name absent", []string{"new.example.com. NONE ANY"}, dns.RcodeSuccess},
+		{"name present", []string{"alias.example.com. NONE ANY"}, dns.RcodeYXDomain},
+		{
+			"exact rrset in any order and case",
+			[]string{"WWW.example.com. 0 IN A 192.0.2.2", "www.example.com. 0 IN A 192.0.2.1"},
+			dns.RcodeSuccess,
+		},
+		{"exact rrset missing a record", []string{"www.example.com. 0 IN A 192.0.2.1"}, dns.RcodeNXRrset},
+		{"exact rrset of a missing name", []string{"new.example.com. 0 IN A 192.0.2.1"}, dns.RcodeNXRrset},
+		{"name outside the zone", []string{"www.example.org. ANY A"}, dns.RcodeNotZone},
+		{"non-zero ttl", []string{"www.example.com. 300 IN A 192.0.2.1"}, dns.RcodeFormatError},
+		{"unknown class", []string{"www.example.com. CH A"}, dns.RcodeFormatError},
+		{"first failure wins", []string{"new.example.com. ANY ANY", "www.example.org. ANY A"}, dns.RcodeNameError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newZoneState(fixtureZone())
+			if got := s.checkPrerequisites(rrs(t, tt.prereqs)); got != tt.want {
+				t.Errorf("checkPrerequisites = %s, want %s", dns.RcodeToString[got], dns.RcodeToString[tt.want])
+			}
+		})
+	}
+}
+
+func TestPrescan(t *testing.T) {
+	tests := []struct {
+		name    string
+		updates []string
+		want    int
+	}{
+		{"add", []string{"new.example.com. 300 IN A 192.0.2.5"}, dns.RcodeSuccess},
+		{"delete rrset", []string{"www.example.com. ANY A"}, dns.RcodeSuccess},
+		{"delete name", []string{"www.example.com. ANY ANY"}, dns.RcodeSuccess},
+		{"delete record", []string{"www.example.com. 0 NONE A 192.0.2.1"}, dns.RcodeSuccess},
+		{"name outside the zone", []string{"www.example.org. 300 IN A 192.0.2.5"}, dns.RcodeNotZone},
+		{"add of type ANY", []string{"www.example.com. IN ANY"}, dns.RcodeFormatError},
+		{"add of type AXFR", []string{"www.example.com. IN AXFR"}, dns.RcodeFormatError},
+		{"delete rrset of type IXFR", []string{"www.example.com. ANY IXFR"}, dns.RcodeFormatError},
+		{"delete rrset with a ttl", []string{"www.example.com. 300 ANY A"}, dns.RcodeFormatError},
+		{"delete record with a ttl", []string{"www.example.com. 300 NONE A 192.0.2.1"}, dns.RcodeFormatError},
+		{"delete record of type ANY", []string{"www.example.com. NONE ANY"}, dns.RcodeFormatError},
+		{"unknown class", []string{"www.example.com. 0 CH A 192.0.2.1"}, dns.RcodeFormatError},
+		{"one bad record rejects all", []string{"new.example.com. 300 IN A 192.0.2.5", "www.example.org. 300 IN A 192.0.2.5"}, dns.RcodeNotZone},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newZoneState(fixtureZone())
+			if got := s.prescan(rrs(t, tt.updates)); got != tt.want {
+				t.Errorf("prescan = %s, want %s", dns.RcodeToString[got], dns.RcodeToString[tt.want])
+			}
+		})
+	}
+}
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name    string
+		updates []string
+		want    []string
+	}{
+		{
+			name:    "add to a new rrset",
+			updates: []string{"new.example.com. 300 IN A 192.0.2.5"},
+			want:    []string{"create new.example.com./A [192.0.2.5]"},
+		},
+		{
+			name:    "add to an existing rrset",
+			updates: []string{"www.example.com. 600 IN A 192.0.2.3"},
+			want:    []string{"update www.example.com./A [192.0.2.1 192.0.2.2 192.0.2.3]"},
+		},
+		{
+			name:    "add of an existing record only sets the ttl",
+			updates: []string{"www.example.com. 600 IN A 192.0.2.1"},
+			want:    []string{"update www.example.com./A [192.0.2.1 192.0.2.2]"},
+		},
+		{
+			name:    "re-adding an unchanged record is no change",
+			updates: []string{"www.example.com. 3600 IN A 192.0.2.1"},
+			want:    []string{},
+		},
+		{
+			name:    "delete a record",
+			updates: []string{"www.example.com. 0 NONE A 192.0.2.1"},
+			want:    []string{"update www.example.com./A [192.0.2.2]"},
+		},
+		{
+			name:    "delete the last records removes the rrset",
+			updates: []string{"www.example.com. 0 NONE A 192.0.2.1", "www.example.com. 0 NONE A 192.0.2.2"},
+			want:    []string{"delete www.example.com./A []"},
+		},
+		{
+			name:    "delete an rrset",
+			updates: []string{"www.example.com. ANY A"},
+			want:    []string{"delete www.example.com./A []"},
+		},
+		{
+			name:    "delete a name",
+			updates: []string{"alias.example.com. ANY ANY"},
+			want:    []string{"delete alias.example.com./CNAME []"},
+		},
+		{
+			name:    "delete the apex keeps SOA and NS",
+			updates: []string{"example.com. ANY ANY"},
+			want:    []string{"delete example.com./A []"},
+		},
+		{
+			name:    "apex NS rrset is never deleted",
+			updates: []string{"example.com. ANY NS"},
+			want:    []string{},
+		},
+		{
+			name:    "last apex NS record is kept",
+			updates: []string{"example.com. 0 NONE NS ns1.example.com."},
+			want:    []string{},
+		},
+		{
+			name:    "other apex NS records can be removed",
+			updates: []string{"example.com. 3600 IN NS ns2.example.com.", "example.com. 0 NONE NS ns1.example.com."},
+			want:    []string{"update example.com./NS [ns2.example.com.]"},
+		},
+		{
+			name:    "SOA changes are ignored",
+			updates: []string{"example.com. 3600 IN SOA ns1.example.com. hostmaster.example.com. 99 10800 3600 604800 3600"},
+			want:    []string{},
+		},
+		{
+			name:    "CNAME replaces the previous CNAME",
+			updates: []string{"alias.example.com. 300 IN CNAME other.example.com."},
+			want:    []string{"update alias.example.com./CNAME [other.example.com.]"},
+		},
+		{
+			name:    "CNAME is not added next to other data",
+			updates: []string{"www.example.com. 300 IN CNAME other.example.com."},
+			want:    []string{},
+		},
+		{
+			name:    "other data is not added next to a CNAME",
+			updates: []string{"alias.example.com. 300 IN A 192.0.2.5"},
+			want:    []string{},
+		},
+		{
+			name:    "deleting a record that does not exist is no change",
+			updates: []string{"new.example.com. 0 NONE A 192.0.2.5"},
+			want:    []string{},
+		},
+		{
+			name:    "updates apply in order",
+			updates: []string{"www.example.com. ANY A", "www.example.com. 300 IN A 192.0.2.9"},
+			want:    []string{"update www.example.com./A [192.0.2.9]"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newZoneState(fixtureZone())
+			s.apply(rrs(t, tt.updates))
+			if got := summary(s.changes()); !slices.Equal(got, tt.want) {
+				t.Errorf("changes = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyLeavesTheFetchedZoneAlone(t *testing.T) {
+	zone := fixtureZone()
+	s := newZoneState(zone)
+	s.apply(rrs(t, []string{"www.example.com. 0 NONE A 192.0.2.1", "www.example.com. 300 IN A 192.0.2.3"}))
+
+	if got := zone.RRSet("www.example.com.", "A").Contents(); !slices.Equal(got, []string{"192.0.2.1", "192.0.2.2"}) {
+		t.Errorf("fetched rrset changed to %v", got)
+	}
+}
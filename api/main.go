@@ -1,14 +1,19 @@
 package main
 
 import (
+	"context"
 	"io"
 	"log"
 	"os"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
+	"github.com/rafinhacuri/SanchezDNS/audit"
 	"github.com/rafinhacuri/SanchezDNS/db"
+	"github.com/rafinhacuri/SanchezDNS/dnsupdate"
+	"github.com/rafinhacuri/SanchezDNS/metrics"
 	"github.com/rafinhacuri/SanchezDNS/routes"
+	"github.com/rafinhacuri/SanchezDNS/workers"
 )
 
 func init() {
@@ -27,10 +32,14 @@ func main() {
 
 	server := gin.Default()
 
-	server.Use(gin.LoggerWithWriter(os.Stdout, "/healthcheck"))
+	server.Use(gin.LoggerWithWriter(os.Stdout, "/healthcheck", "/metrics"))
+	server.Use(metrics.Middleware)
 
 	server.SetTrustedProxies([]string{"127.0.0.1", "::1"})
 
 	routes.RegisterRoutes(server)
+	audit.Start(context.Background())
+	workers.Start(context.Background())
+	dnsupdate.Start(context.Background())
 	server.Run(":8080")
 }
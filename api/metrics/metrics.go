@@ -0,0 +1,168 @@
+// Package metrics keeps the process metrics exported on /metrics.
+package metrics
+
+import (
+	"context"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	registry = prometheus.NewRegistry()
+
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "sanchezdns_http_requests_total",
+		Help: "HTTP requests handled, by route and status.",
+	}, []string{"method", "route", "status"})
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "sanchezdns_http_request_duration_seconds",
+		Help: "HTTP request latency, by route.",
+	}, []string{"method", "route"})
+
+	pdnsRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "sanchezdns_pdns_requests_total",
+		Help: "PowerDNS API calls, by connection and status.",
+	}, []string{"connection", "method", "status"})
+	pdnsErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "sanchezdns_pdns_request_errors_total",
+		Help: "PowerDNS API calls that failed or returned an error status.",
+	}, []string{"connection"})
+	pdnsDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "sanchezdns_pdns_request_duration_seconds",
+		Help: "PowerDNS API call latency, by connection.",
+	}, []string{"connection"})
+
+	mongoUp = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "sanchezdns_mongo_up",
+		Help: "Whether the last MongoDB ping succeeded.",
+	})
+
+	pdnsUp = prometheus.NewDesc("sanchezdns_pdns_up",
+		"Whether the PowerDNS statistics of a connection could be fetched.", []string{"connection"}, nil)
+	connectionInfo = prometheus.NewDesc("sanchezdns_connection_info",
+		"Name of a connection, by connection ID.", []string{"connection", "name"}, nil)
+
+	// reserved are the names a PowerDNS statistic must not take over.
+	reserved = map[string]bool{
+		"sanchezdns_pdns_up":                              true,
+		"sanchezdns_pdns_requests_total":                  true,
+		"sanchezdns_pdns_request_errors_total":            true,
+		"sanchezdns_pdns_request_duration_seconds":        true,
+		"sanchezdns_pdns_request_duration_seconds_bucket": true,
+		"sanchezdns_pdns_request_duration_seconds_sum":    true,
+		"sanchezdns_pdns_request_duration_seconds_count":  true,
+	}
+
+	handler = promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+)
+
+func init() {
+	registry.MustRegister(httpRequests, httpDuration, pdnsRequests, pdnsErrors, pdnsDuration, mongoUp)
+}
+
+// Handler serves every registered metric in the Prometheus exposition
+// format.
+func Handler() http.Handler {
+	return handler
+}
+
+// ObserveHTTP records one handled HTTP request.
+func ObserveHTTP(method, route string, status int, duration time.Duration) {
+	httpRequests.WithLabelValues(method, route, strconv.Itoa(status)).Inc()
+	httpDuration.WithLabelValues(method, route).Observe(duration.Seconds())
+}
+
+// ObservePdns records one PowerDNS API call. A status of 0 means the call
+// failed before a response was received.
+func ObservePdns(connection, method string, status int, duration time.Duration) {
+	label := strconv.Itoa(status)
+	if status == 0 {
+		label = "error"
+	}
+	pdnsRequests.WithLabelValues(connection, method, label).Inc()
+	pdnsDuration.WithLabelValues(connection).Observe(duration.Seconds())
+	if status == 0 || status >= 400 {
+		pdnsErrors.WithLabelValues(connection).Inc()
+	}
+}
+
+func SetMongoUp(up bool) {
+	mongoUp.Set(boolValue(up))
+}
+
+// PdnsStats holds what one scrape fetched from a connection.
+type PdnsStats struct {
+	ID     string
+	Name   string
+	Up     bool
+	Values map[string]float64
+}
+
+// pdnsCollector exports the PowerDNS statistics fetched at scrape time, so
+// every scrape sees one consistent set and removed connections disappear
+// on their own. The metric names depend on what PowerDNS reports, which
+// makes it an unchecked collector.
+type pdnsCollector struct {
+	fetch func(context.Context) []PdnsStats
+}
+
+// RegisterPdnsCollector makes every scrape call fetch and export its result
+// as sanchezdns_pdns_<statistic> gauges labelled with the connection ID.
+func RegisterPdnsCollector(fetch func(context.Context) []PdnsStats) {
+	registry.MustRegister(pdnsCollector{fetch: fetch})
+}
+
+func (c pdnsCollector) Describe(chan<- *prometheus.Desc) {}
+
+func (c pdnsCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	for _, conn := range c.fetch(ctx) {
+		ch <- prometheus.MustNewConstMetric(connectionInfo, prometheus.GaugeValue, 1, conn.ID, conn.Name)
+		ch <- prometheus.MustNewConstMetric(pdnsUp, prometheus.GaugeValue, boolValue(conn.Up), conn.ID)
+
+		seen := map[string]bool{}
+		for _, name := range slices.Sorted(maps.Keys(conn.Values)) {
+			suffix := metricName(name)
+			metric := "sanchezdns_pdns_" + suffix
+			if reserved[metric] || seen[metric] {
+				continue
+			}
+			seen[metric] = true
+
+			desc := prometheus.NewDesc(metric, "PowerDNS statistic "+suffix+".", []string{"connection"}, nil)
+			if m, err := prometheus.NewConstMetric(desc, prometheus.GaugeValue, conn.Values[name], conn.ID); err == nil {
+				ch <- m
+			}
+		}
+	}
+}
+
+// metricName turns a statistic name into a metric name suffix.
+func metricName(name string) string {
+	var b strings.Builder
+	for _, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_':
+			b.WriteRune(r)
+		default:
+			b.WriteByte('_')
+		}
+	}
+	return b.String()
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,20 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Middleware counts requests per route template, so /zone/records?x=1
+// and /zone/records?x=2 share one series.
+func Middleware(ctx *gin.Context) {
+	start := time.Now()
+	ctx.Next()
+
+	route := ctx.FullPath()
+	if route == "" {
+		route = "unmatched"
+	}
+	ObserveHTTP(ctx.Request.Method, route, ctx.Writer.Status(), time.Since(start))
+}
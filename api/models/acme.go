@@ -0,0 +1,158 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// AcmeTxtLifetime is how long a challenge value is published before the
+	// cleanup job removes it.
+	AcmeTxtLifetime = time.Hour
+	// AcmeMaxTxt matches acme-dns, which keeps the two latest values so a
+	// wildcard and its base name can be validated together.
+	AcmeMaxTxt = 2
+	AcmeTxtTTL = 60
+)
+
+var acmeTxtRe = regexp.MustCompile(`^[A-Za-z0-9_-]{43}$`)
+
+type AcmeTxt struct {
+	Value     string    `bson:"value" json:"value"`
+	CreatedAt time.Time `bson:"createdAt" json:"createdAt"`
+}
+
+// AcmeAccount holds acme-dns style credentials that may only publish TXT
+// values on one _acme-challenge name.
+type AcmeAccount struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	Subdomain    string             `bson:"subdomain" json:"subdomain"`
+	Username     string             `bson:"username" json:"username"`
+	Password     string             `bson:"password" json:"-"`
+	FullDomain   string             `bson:"fulldomain" json:"fulldomain"`
+	Zone         string             `bson:"zone" json:"zone"`
+	IdConnection string             `bson:"idConnection" json:"idConnection"`
+	AllowFrom    []string           `bson:"allowfrom" json:"allowfrom"`
+	Txt          []AcmeTxt          `bson:"txt" json:"txt"`
+	CreatedBy    string             `bson:"createdBy" json:"createdBy"`
+	CreatedAt    time.Time          `bson:"createdAt" json:"createdAt"`
+	LastUpdateAt *time.Time         `bson:"lastUpdateAt,omitempty" json:"lastUpdateAt,omitempty"`
+}
+
+type AcmeRegisterRequest struct {
+	Connection string   `json:"connection"`
+	Zone       string   `json:"zone"`
+	Domain     string   `json:"domain"`
+	AllowFrom  []string `json:"allowfrom,omitempty"`
+}
+
+func (r *AcmeRegisterRequest) Validate() error {
+	r.Zone = strings.ToLower(strings.TrimSpace(r.Zone))
+	r.Domain = strings.ToLower(strings.TrimSpace(r.Domain))
+
+	if strings.TrimSpace(r.Connection) == "" {
+		return errors.New("the field 'connection' is required")
+	}
+	if r.Zone == "" {
+		return errors.New("the field 'zone' is required")
+	}
+	if r.Domain == "" {
+		return errors.New("the field 'domain' is required")
+	}
+
+	r.Zone = Fqdn(r.Zone)
+	r.Domain = Fqdn(strings.TrimPrefix(strings.TrimPrefix(r.Domain, "*."), "_acme-challenge."))
+	if !InZone(r.Domain, r.Zone) {
+		return fmt.Errorf("domain %s is outside of zone %s", r.Domain, r.Zone)
+	}
+
+	for i, cidr := range r.AllowFrom {
+		prefix, err := netip.ParsePrefix(strings.TrimSpace(cidr))
+		if err != nil {
+			return fmt.Errorf("allowfrom[%d] is not a valid CIDR", i)
+		}
+		r.AllowFrom[i] = prefix.Masked().String()
+	}
+
+	return nil
+}
+
+// ChallengeName returns the name the TXT records are published on.
+func (r *AcmeRegisterRequest) ChallengeName() string {
+	return "_acme-challenge." + r.Domain
+}
+
+type AcmeUpdateRequest struct {
+	Subdomain string `json:"subdomain"`
+	Txt       string `json:"txt"`
+}
+
+func (r *AcmeUpdateRequest) Validate() error {
+	r.Subdomain = strings.TrimSpace(r.Subdomain)
+	r.Txt = strings.TrimSpace(r.Txt)
+
+	if r.Subdomain == "" {
+		return errors.New("bad_subdomain")
+	}
+	if !acmeTxtRe.MatchString(r.Txt) {
+		return errors.New("bad_txt")
+	}
+	return nil
+}
+
+// AllowsAddr reports whether updates are accepted from addr. An empty
+// allowfrom list accepts every address.
+func (a *AcmeAccount) AllowsAddr(addr string) bool {
+	if len(a.AllowFrom) == 0 {
+		return true
+	}
+	ip, err := netip.ParseAddr(addr)
+	if err != nil {
+		return false
+	}
+	ip = ip.Unmap()
+	return slices.ContainsFunc(a.AllowFrom, func(cidr string) bool {
+		prefix, err := netip.ParsePrefix(cidr)
+		return err == nil && prefix.Contains(ip)
+	})
+}
+
+// AddTxt publishes value, dropping expired values and keeping at most
+// AcmeMaxTxt of the newest ones.
+func (a *AcmeAccount) AddTxt(value string, now time.Time) {
+	a.Txt = slices.DeleteFunc(a.Txt, func(t AcmeTxt) bool { return t.Value == value })
+	a.Txt = append(a.Txt, AcmeTxt{Value: value, CreatedAt: now})
+	a.PruneTxt(now)
+	if len(a.Txt) > AcmeMaxTxt {
+		a.Txt = a.Txt[len(a.Txt)-AcmeMaxTxt:]
+	}
+}
+
+// PruneTxt drops values older than AcmeTxtLifetime and reports whether
+// anything was removed.
+func (a *AcmeAccount) PruneTxt(now time.Time) bool {
+	n := len(a.Txt)
+	a.Txt = slices.DeleteFunc(a.Txt, func(t AcmeTxt) bool { return now.Sub(t.CreatedAt) >= AcmeTxtLifetime })
+	return len(a.Txt) != n
+}
+
+// Patch returns the PowerDNS rrset change that publishes the current
+// values, or deletes the TXT rrset when there are none.
+func (a *AcmeAccount) Patch() map[string]any {
+	if len(a.Txt) == 0 {
+		return map[string]any{"name": a.FullDomain, "type": "TXT", "changetype": "DELETE"}
+	}
+
+	rr := RRSet{Name: a.FullDomain, Type: "TXT", TTL: AcmeTxtTTL}
+	for _, t := range a.Txt {
+		rr.Records = append(rr.Records, Record{Content: `"` + t.Value + `"`})
+	}
+	return rr.Patch()
+}
@@ -0,0 +1,109 @@
+package models
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func txtValues(a *AcmeAccount) []string {
+	values := []string{}
+	for _, t := range a.Txt {
+		values = append(values, t.Value)
+	}
+	return values
+}
+
+func TestAcmeAccountAddTxt(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		txt   []AcmeTxt
+		value string
+		want  []string
+	}{
+		{
+			name:  "empty",
+			value: "a",
+			want:  []string{"a"},
+		},
+		{
+			name:  "keeps previous value",
+			txt:   []AcmeTxt{{Value: "a", CreatedAt: now.Add(-time.Minute)}},
+			value: "b",
+			want:  []string{"a", "b"},
+		},
+		{
+			name:  "drops oldest over the limit",
+			txt:   []AcmeTxt{{Value: "a", CreatedAt: now.Add(-2 * time.Minute)}, {Value: "b", CreatedAt: now.Add(-time.Minute)}},
+			value: "c",
+			want:  []string{"b", "c"},
+		},
+		{
+			name:  "same value is refreshed not duplicated",
+			txt:   []AcmeTxt{{Value: "a", CreatedAt: now.Add(-2 * time.Minute)}, {Value: "b", CreatedAt: now.Add(-time.Minute)}},
+			value: "a",
+			want:  []string{"b", "a"},
+		},
+		{
+			name:  "expired values are dropped",
+			txt:   []AcmeTxt{{Value: "a", CreatedAt: now.Add(-AcmeTxtLifetime)}},
+			value: "b",
+			want:  []string{"b"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := &AcmeAccount{Txt: slices.Clone(tt.txt)}
+			a.AddTxt(tt.value, now)
+			if got := txtValues(a); !slices.Equal(got, tt.want) {
+				t.Errorf("AddTxt(%q) = %v; want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAcmeAccountPruneTxt(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		txt     []AcmeTxt
+		want    []string
+		removed bool
+	}{
+		{
+			name: "nothing to prune",
+			txt:  []AcmeTxt{{Value: "a", CreatedAt: now.Add(-time.Minute)}},
+			want: []string{"a"},
+		},
+		{
+			name:    "expired at exactly the lifetime",
+			txt:     []AcmeTxt{{Value: "a", CreatedAt: now.Add(-AcmeTxtLifetime)}, {Value: "b", CreatedAt: now}},
+			want:    []string{"b"},
+			removed: true,
+		},
+		{
+			name:    "all expired",
+			txt:     []AcmeTxt{{Value: "a", CreatedAt: now.Add(-2 * AcmeTxtLifetime)}},
+			want:    []string{},
+			removed: true,
+		},
+		{
+			name: "empty",
+			want: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := &AcmeAccount{Txt: slices.Clone(tt.txt)}
+			removed := a.PruneTxt(now)
+			if got := txtValues(a); removed != tt.removed || !slices.Equal(got, tt.want) {
+				t.Errorf("PruneTxt() = %v, %v; want %v, %v", got, removed, tt.want, tt.removed)
+			}
+		})
+	}
+}
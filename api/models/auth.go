@@ -7,9 +7,17 @@ import (
 	"github.com/rafinhacuri/SanchezDNS/db"
 	"github.com/rafinhacuri/SanchezDNS/passwords"
 	"github.com/rafinhacuri/SanchezDNS/utils"
+	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/v2/bson"
 )
 
+// The two login failures share a message so callers cannot tell whether
+// an account exists; they stay distinct for the security events log.
+var (
+	ErrUnknownUser   = errors.New("invalid email or password")
+	ErrWrongPassword = errors.New("invalid email or password")
+)
+
 type Auth struct {
 	Email    string `json:"email" binding:"required"`
 	Password string `json:"password" binding:"required"`
@@ -35,11 +43,14 @@ func (u *Auth) Validate() error {
 func (u *Auth) Login(ctx context.Context) (token string, isAdmin bool, err error) {
 	var user User
 	if err := db.Database.Collection("users").FindOne(ctx, bson.M{"email": u.Email}).Decode(&user); err != nil {
-		return "", false, errors.New("invalid email or password")
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return "", false, ErrUnknownUser
+		}
+		return "", false, err
 	}
 
 	if !passwords.VerifyBCrypt(u.Password, user.Password) {
-		return "", false, errors.New("invalid email or password")
+		return "", false, ErrWrongPassword
 	}
 
 	token, err = utils.GenerateJWT(user.Email, user.Level == "admin")
@@ -0,0 +1,139 @@
+package models
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+type ZoneIssue struct {
+	Severity string `json:"severity"`
+	Name     string `json:"name,omitempty"`
+	Type     string `json:"type,omitempty"`
+	Message  string `json:"message"`
+}
+
+type ZoneCheckResult struct {
+	Zone     string      `json:"zone"`
+	Valid    bool        `json:"valid"`
+	Errors   int         `json:"errors"`
+	Warnings int         `json:"warnings"`
+	Issues   []ZoneIssue `json:"issues"`
+}
+
+// Check runs the consistency checks PowerDNS only offers through
+// `pdnsutil check-zone`, which is not exposed by the HTTP API.
+func (z *Zone) Check() ZoneCheckResult {
+	result := ZoneCheckResult{Zone: z.Name, Issues: []ZoneIssue{}}
+
+	add := func(severity, name, rrType, format string, args ...any) {
+		result.Issues = append(result.Issues, ZoneIssue{Severity: severity, Name: name, Type: rrType, Message: fmt.Sprintf(format, args...)})
+		if severity == "error" {
+			result.Errors++
+		} else {
+			result.Warnings++
+		}
+	}
+
+	apex := strings.ToLower(Fqdn(z.Name))
+	typesByName := map[string][]string{}
+	soaCount := 0
+	apexNS := 0
+
+	for _, rr := range z.RRSets {
+		name := strings.ToLower(rr.Name)
+		typesByName[name] = append(typesByName[name], rr.Type)
+
+		if !InZone(name, apex) {
+			add("error", rr.Name, rr.Type, "record is outside of zone %s", z.Name)
+			continue
+		}
+
+		if len(rr.Records) == 0 {
+			add("warning", rr.Name, rr.Type, "rrset has no records")
+		}
+
+		if rr.TTL <= 0 {
+			add("warning", rr.Name, rr.Type, "TTL %d is not positive", rr.TTL)
+		}
+
+		seen := map[string]bool{}
+		for _, rec := range rr.Records {
+			key := strings.ToLower(rec.Content)
+			if seen[key] {
+				add("error", rr.Name, rr.Type, "duplicate record %q", rec.Content)
+			}
+			seen[key] = true
+		}
+
+		switch rr.Type {
+		case "SOA":
+			soaCount++
+			if name != apex {
+				add("error", rr.Name, rr.Type, "SOA record must be at the zone apex")
+			}
+			if len(rr.Records) != 1 {
+				add("error", rr.Name, rr.Type, "SOA rrset must contain exactly one record")
+			}
+		case "NS":
+			if name == apex {
+				apexNS += len(rr.Records)
+			}
+		case "CNAME":
+			if name == apex {
+				add("error", rr.Name, rr.Type, "CNAME is not allowed at the zone apex")
+			}
+			if len(rr.Records) > 1 {
+				add("error", rr.Name, rr.Type, "CNAME rrset must contain a single record")
+			}
+		}
+	}
+
+	if soaCount == 0 {
+		add("error", z.Name, "SOA", "zone has no SOA record")
+	}
+	if apexNS == 0 {
+		add("error", z.Name, "NS", "zone has no NS records at the apex")
+	} else if apexNS == 1 {
+		add("warning", z.Name, "NS", "zone has a single nameserver")
+	}
+
+	names := make([]string, 0, len(typesByName))
+	for name := range typesByName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		types := typesByName[name]
+		if len(types) > 1 && slices.Contains(types, "CNAME") {
+			add("error", name, "CNAME", "CNAME cannot coexist with other records (%s)", strings.Join(types, ", "))
+		}
+	}
+
+	for _, rr := range z.RRSets {
+		if rr.Type == "CNAME" || rr.Type == "ALIAS" || rr.Type == "PTR" || rr.Type == "DNAME" {
+			continue
+		}
+		for _, rec := range rr.Records {
+			target := strings.ToLower(RecordTarget(rr.Type, rec.Content))
+			if target == "" || target == "." || !InZone(target, apex) {
+				continue
+			}
+			types, ok := typesByName[Fqdn(target)]
+			switch {
+			case !ok:
+				add("warning", rr.Name, rr.Type, "target %s does not exist in the zone", target)
+			case slices.Contains(types, "CNAME"):
+				add("warning", rr.Name, rr.Type, "target %s is a CNAME", target)
+			case !slices.Contains(types, "A") && !slices.Contains(types, "AAAA"):
+				add("warning", rr.Name, rr.Type, "target %s has no A or AAAA records", target)
+			}
+		}
+	}
+
+	result.Valid = result.Errors == 0
+
+	return result
+}
@@ -0,0 +1,171 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+type CloneZoneRequest struct {
+	SourceConnection string `json:"sourceConnection"`
+	SourceZone       string `json:"sourceZone"`
+	Connection       string `json:"connection"`
+	Domain           string `json:"domain"`
+	Soa              *Soa   `json:"soa,omitempty"`
+}
+
+func (r *CloneZoneRequest) Validate() error {
+	if strings.TrimSpace(r.SourceConnection) == "" {
+		return errors.New("the field 'sourceConnection' is required")
+	}
+	if strings.TrimSpace(r.SourceZone) == "" {
+		return errors.New("the field 'sourceZone' is required")
+	}
+	if strings.TrimSpace(r.Connection) == "" {
+		r.Connection = r.SourceConnection
+	}
+	if strings.TrimSpace(r.Domain) == "" {
+		return errors.New("the field 'domain' is required")
+	}
+
+	r.SourceZone = Fqdn(r.SourceZone)
+	r.Domain = Fqdn(strings.TrimSpace(r.Domain))
+
+	if strings.EqualFold(r.SourceZone, r.Domain) && r.SourceConnection == r.Connection {
+		return errors.New("source and destination zones must differ")
+	}
+
+	if r.Soa != nil {
+		if err := r.Soa.Validate(); err != nil {
+			return fmt.Errorf("soa: %w", err)
+		}
+	}
+
+	return nil
+}
+
+type CloneIssue struct {
+	Name    string `json:"name"`
+	Type    string `json:"type"`
+	Content string `json:"content,omitempty"`
+	Reason  string `json:"reason"`
+}
+
+// dnssecTypes are generated by PowerDNS from the zone keys and are never
+// copied between zones.
+var dnssecTypes = []string{"DNSKEY", "RRSIG", "NSEC", "NSEC3", "NSEC3PARAM", "CDS", "CDNSKEY"}
+
+func ParseSoa(content string) (*Soa, int64, error) {
+	parts := strings.Fields(content)
+	if len(parts) < 7 {
+		return nil, 0, fmt.Errorf("invalid SOA content %q", content)
+	}
+
+	serial, _ := strconv.ParseInt(parts[2], 10, 64)
+	refresh, _ := strconv.Atoi(parts[3])
+	retry, _ := strconv.Atoi(parts[4])
+	expire, _ := strconv.Atoi(parts[5])
+	negTTL, _ := strconv.Atoi(parts[6])
+
+	return &Soa{
+		StartOfAuthority: parts[0],
+		Email:            parts[1],
+		Refresh:          refresh,
+		Retry:            retry,
+		Expire:           expire,
+		NegativeCacheTtl: negTTL,
+	}, serial, nil
+}
+
+// RenameName moves name from zone from to zone to. Names outside of from are
+// returned unchanged.
+func RenameName(name, from, to string) (string, bool) {
+	fqdn := Fqdn(name)
+	if !InZone(fqdn, from) {
+		return name, false
+	}
+	prefix := fqdn[:len(fqdn)-len(Fqdn(from))]
+	return prefix + Fqdn(to), true
+}
+
+// RewriteZone copies the rrsets of a zone under a new apex. Owner names and
+// in-zone targets are rewritten; records whose content still mentions the
+// source domain in a way that cannot be rewritten safely are reported.
+func RewriteZone(zone *Zone, to string) (rrsets []RRSet, soa *Soa, issues []CloneIssue) {
+	from := Fqdn(zone.Name)
+	to = Fqdn(to)
+	bare := strings.ToLower(strings.TrimSuffix(from, "."))
+
+	for _, rr := range zone.RRSets {
+		if slices.Contains(dnssecTypes, rr.Type) {
+			issues = append(issues, CloneIssue{Name: rr.Name, Type: rr.Type, Reason: "DNSSEC records are generated by the destination server"})
+			continue
+		}
+
+		name, ok := RenameName(rr.Name, from, to)
+		if !ok {
+			issues = append(issues, CloneIssue{Name: rr.Name, Type: rr.Type, Reason: "owner name is outside of the source zone"})
+			continue
+		}
+
+		out := RRSet{Name: name, Type: rr.Type, TTL: rr.TTL, Comments: rr.Comments}
+
+		for _, rec := range rr.Records {
+			content := rewriteContent(rr.Type, rec.Content, from, to)
+
+			if rr.Type == "SOA" {
+				parsed, _, err := ParseSoa(content)
+				if err != nil {
+					issues = append(issues, CloneIssue{Name: rr.Name, Type: rr.Type, Content: rec.Content, Reason: err.Error()})
+					continue
+				}
+				soa = parsed
+				continue
+			}
+
+			if content == rec.Content && strings.Contains(strings.ToLower(content), bare) {
+				issues = append(issues, CloneIssue{Name: rr.Name, Type: rr.Type, Content: rec.Content, Reason: "content references the source domain and was copied unchanged"})
+			}
+
+			out.Records = append(out.Records, Record{Content: content, Disabled: rec.Disabled})
+		}
+
+		if rr.Type == "SOA" || len(out.Records) == 0 {
+			continue
+		}
+
+		rrsets = append(rrsets, out)
+	}
+
+	return rrsets, soa, issues
+}
+
+func rewriteContent(rrType, content, from, to string) string {
+	if rrType == "SOA" {
+		parts := strings.Fields(content)
+		for i := 0; i < 2 && i < len(parts); i++ {
+			parts[i], _ = RenameName(parts[i], from, to)
+		}
+		return strings.Join(parts, " ")
+	}
+
+	idx := targetField(rrType)
+	if idx < 0 {
+		return content
+	}
+
+	parts := strings.Fields(content)
+	if len(parts) <= idx || !strings.HasSuffix(parts[idx], ".") {
+		return content
+	}
+
+	renamed, ok := RenameName(parts[idx], from, to)
+	if !ok {
+		return content
+	}
+	parts[idx] = renamed
+
+	return strings.Join(parts, " ")
+}
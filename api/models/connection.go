@@ -1,10 +1,13 @@
 package models
 
 import (
+	"context"
 	"errors"
 	"strings"
 	"time"
 
+	"github.com/rafinhacuri/SanchezDNS/db"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
@@ -66,3 +69,17 @@ func (u *ConnectingEditRequest) ValidateConnectionEdit() error {
 
 	return nil
 }
+
+func FindConnection(ctx context.Context, id string) (*Connection, error) {
+	connectionID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, errors.New("invalid connection ID")
+	}
+
+	var connection Connection
+	if err := db.Database.Collection("connections").FindOne(ctx, bson.M{"_id": connectionID}).Decode(&connection); err != nil {
+		return nil, errors.New("connection not found")
+	}
+
+	return &connection, nil
+}
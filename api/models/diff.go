@@ -0,0 +1,129 @@
+package models
+
+import (
+	"slices"
+	"sort"
+	"strings"
+)
+
+type RRSetChange struct {
+	Action string   `bson:"action" json:"action"`
+	Name   string   `bson:"name" json:"name"`
+	Type   string   `bson:"type" json:"type"`
+	TTL    int      `bson:"ttl,omitempty" json:"ttl,omitempty"`
+	Before []string `bson:"before,omitempty" json:"before,omitempty"`
+	After  []string `bson:"after,omitempty" json:"after,omitempty"`
+	RRSet  *RRSet   `bson:"-" json:"-"`
+}
+
+func (r *RRSet) Key() string {
+	return strings.ToLower(Fqdn(r.Name)) + "/" + r.Type
+}
+
+func (r *RRSet) Contents() []string {
+	contents := make([]string, 0, len(r.Records))
+	for _, rec := range r.Records {
+		if rec.Disabled {
+			contents = append(contents, rec.Content+" (disabled)")
+			continue
+		}
+		contents = append(contents, rec.Content)
+	}
+	sort.Strings(contents)
+	return contents
+}
+
+func (r *RRSet) Equal(other *RRSet) bool {
+	return r.TTL == other.TTL && slices.Equal(r.Contents(), other.Contents())
+}
+
+func (r *RRSet) Patch() map[string]any {
+	records := make([]map[string]any, 0, len(r.Records))
+	for _, rec := range r.Records {
+		records = append(records, map[string]any{"content": rec.Content, "disabled": rec.Disabled})
+	}
+
+	patch := map[string]any{
+		"name":       Fqdn(r.Name),
+		"type":       r.Type,
+		"ttl":        r.TTL,
+		"changetype": "REPLACE",
+		"records":    records,
+	}
+	if len(r.Comments) > 0 {
+		patch["comments"] = r.Comments
+	}
+
+	return patch
+}
+
+func (c *RRSetChange) Patch() map[string]any {
+	if c.Action == "delete" {
+		return map[string]any{"name": Fqdn(c.Name), "type": c.Type, "changetype": "DELETE"}
+	}
+	return c.RRSet.Patch()
+}
+
+// DiffRRSets lists the changes needed to turn current into desired. When
+// prune is false rrsets missing from desired are left alone instead of
+// being deleted.
+func DiffRRSets(current, desired []RRSet, prune bool) []RRSetChange {
+	existing := make(map[string]*RRSet, len(current))
+	for i := range current {
+		existing[current[i].Key()] = &current[i]
+	}
+
+	wanted := make(map[string]bool, len(desired))
+	changes := []RRSetChange{}
+
+	for i := range desired {
+		rr := &desired[i]
+		wanted[rr.Key()] = true
+
+		old, ok := existing[rr.Key()]
+		switch {
+		case !ok:
+			changes = append(changes, RRSetChange{Action: "create", Name: rr.Name, Type: rr.Type, TTL: rr.TTL, After: rr.Contents(), RRSet: rr})
+		case !old.Equal(rr):
+			changes = append(changes, RRSetChange{Action: "update", Name: rr.Name, Type: rr.Type, TTL: rr.TTL, Before: old.Contents(), After: rr.Contents(), RRSet: rr})
+		}
+	}
+
+	if prune {
+		for i := range current {
+			rr := &current[i]
+			if wanted[rr.Key()] {
+				continue
+			}
+			changes = append(changes, RRSetChange{Action: "delete", Name: rr.Name, Type: rr.Type, TTL: rr.TTL, Before: rr.Contents(), RRSet: rr})
+		}
+	}
+
+	sort.SliceStable(changes, func(i, j int) bool {
+		if changes[i].Name != changes[j].Name {
+			return changes[i].Name < changes[j].Name
+		}
+		return changes[i].Type < changes[j].Type
+	})
+
+	return changes
+}
+
+func PatchChanges(changes []RRSetChange) []map[string]any {
+	rrsets := make([]map[string]any, 0, len(changes))
+	for i := range changes {
+		rrsets = append(rrsets, changes[i].Patch())
+	}
+	return rrsets
+}
+
+// RRSet returns the rrset with the given name and type, or nil.
+func (z *Zone) RRSet(name, rrType string) *RRSet {
+	key := strings.ToLower(Fqdn(name)) + "/" + rrType
+	for i := range z.RRSets {
+		if z.RRSets[i].Key() == key {
+			return &z.RRSets[i]
+		}
+	}
+	return nil
+}
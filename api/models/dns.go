@@ -31,3 +31,14 @@ type PdnsStat struct {
 	Type  string `json:"type"`
 	Value any    `json:"value"`
 }
+
+type PdnsSearchResult struct {
+	Content    string `json:"content"`
+	Disabled   bool   `json:"disabled"`
+	Name       string `json:"name"`
+	ObjectType string `json:"object_type"`
+	ZoneID     string `json:"zone_id"`
+	Zone       string `json:"zone"`
+	Type       string `json:"type"`
+	TTL        int    `json:"ttl"`
+}
@@ -0,0 +1,106 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ZoneState is the last rrset content SanchezDNS knows for a zone. Pinned
+// states are baselines that are not refreshed by changes made through
+// SanchezDNS.
+type ZoneState struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	IdConnection string             `bson:"idConnection" json:"idConnection"`
+	Zone         string             `bson:"zone" json:"zone"`
+	RRSets       []RRSet            `bson:"rrsets" json:"rrsets"`
+	Hash         string             `bson:"hash" json:"hash"`
+	Pinned       bool               `bson:"pinned" json:"pinned"`
+	UpdatedBy    string             `bson:"updatedBy" json:"updatedBy"`
+	UpdatedAt    time.Time          `bson:"updatedAt" json:"updatedAt"`
+}
+
+type DriftEvent struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	IdConnection string             `bson:"idConnection" json:"idConnection"`
+	HostServer   string             `bson:"hostServer" json:"hostServer"`
+	Zone         string             `bson:"zone" json:"zone"`
+	Changes      []RRSetChange      `bson:"changes" json:"changes"`
+	Hash         string             `bson:"hash" json:"hash"`
+	Resolved     bool               `bson:"resolved" json:"resolved"`
+	Resolution   string             `bson:"resolution,omitempty" json:"resolution,omitempty"`
+	ResolvedBy   string             `bson:"resolvedBy,omitempty" json:"resolvedBy,omitempty"`
+	ResolvedAt   *time.Time         `bson:"resolvedAt,omitempty" json:"resolvedAt,omitempty"`
+	DetectedAt   time.Time          `bson:"detectedAt" json:"detectedAt"`
+	UpdatedAt    time.Time          `bson:"updatedAt" json:"updatedAt"`
+}
+
+// withoutSerial masks the SOA serial, which PowerDNS bumps on its own and
+// is not considered drift.
+func withoutSerial(rrsets []RRSet) []RRSet {
+	out := make([]RRSet, 0, len(rrsets))
+	for _, rr := range rrsets {
+		if rr.Type == "SOA" {
+			masked := rr
+			masked.Records = make([]Record, 0, len(rr.Records))
+			for _, rec := range rr.Records {
+				parts := strings.Fields(rec.Content)
+				if len(parts) >= 3 {
+					parts[2] = "0"
+				}
+				masked.Records = append(masked.Records, Record{Content: strings.Join(parts, " "), Disabled: rec.Disabled})
+			}
+			rr = masked
+		}
+		out = append(out, rr)
+	}
+	return out
+}
+
+func StateHash(rrsets []RRSet) string {
+	lines := make([]string, 0, len(rrsets))
+	for _, rr := range withoutSerial(rrsets) {
+		lines = append(lines, fmt.Sprintf("%s %d %s", rr.Key(), rr.TTL, strings.Join(rr.Contents(), "\x00")))
+	}
+	sort.Strings(lines)
+
+	sum := sha256.Sum256([]byte(strings.Join(lines, "\n")))
+	return hex.EncodeToString(sum[:])
+}
+
+// Drift lists what changed on the server since the known state was taken.
+func (s *ZoneState) Drift(current []RRSet) []RRSetChange {
+	return DiffRRSets(withoutSerial(s.RRSets), withoutSerial(current), true)
+}
+
+// Restore lists the changes that bring current back to the known state. The
+// SOA keeps the live serial so secondaries still see an increment.
+func (s *ZoneState) Restore(current []RRSet) []RRSetChange {
+	serial := ""
+	for _, rr := range current {
+		if rr.Type == "SOA" && len(rr.Records) > 0 {
+			if parts := strings.Fields(rr.Records[0].Content); len(parts) >= 3 {
+				serial = parts[2]
+			}
+		}
+	}
+
+	desired := make([]RRSet, 0, len(s.RRSets))
+	for _, rr := range s.RRSets {
+		if rr.Type == "SOA" && serial != "" && len(rr.Records) > 0 {
+			parts := strings.Fields(rr.Records[0].Content)
+			if len(parts) >= 3 {
+				parts[2] = serial
+				rr.Records = []Record{{Content: strings.Join(parts, " ")}}
+			}
+		}
+		desired = append(desired, rr)
+	}
+
+	return DiffRRSets(current, desired, true)
+}
@@ -0,0 +1,71 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// DynDnsHost holds the dyndns2 credentials of a single record name. The
+// credentials can only update the A and AAAA records of that name.
+type DynDnsHost struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	Hostname     string             `bson:"hostname" json:"hostname"`
+	Zone         string             `bson:"zone" json:"zone"`
+	IdConnection string             `bson:"idConnection" json:"idConnection"`
+	Username     string             `bson:"username" json:"username"`
+	Password     string             `bson:"password" json:"-"`
+	TTL          int                `bson:"ttl" json:"ttl"`
+	LastIPv4     string             `bson:"lastIPv4,omitempty" json:"lastIPv4,omitempty"`
+	LastIPv6     string             `bson:"lastIPv6,omitempty" json:"lastIPv6,omitempty"`
+	LastUpdateAt *time.Time         `bson:"lastUpdateAt,omitempty" json:"lastUpdateAt,omitempty"`
+	CreatedBy    string             `bson:"createdBy" json:"createdBy"`
+	CreatedAt    time.Time          `bson:"createdAt" json:"createdAt"`
+}
+
+type DynDnsHostRequest struct {
+	Hostname   string `json:"hostname"`
+	Zone       string `json:"zone"`
+	Connection string `json:"connection"`
+	Username   string `json:"username,omitempty"`
+	Password   string `json:"password,omitempty"`
+	TTL        int    `json:"ttl,omitempty"`
+}
+
+func (r *DynDnsHostRequest) Validate() error {
+	r.Hostname = strings.ToLower(strings.TrimSpace(r.Hostname))
+	r.Zone = strings.ToLower(strings.TrimSpace(r.Zone))
+	r.Username = strings.TrimSpace(r.Username)
+
+	if r.Hostname == "" {
+		return errors.New("the field 'hostname' is required")
+	}
+	if r.Zone == "" {
+		return errors.New("the field 'zone' is required")
+	}
+	if strings.TrimSpace(r.Connection) == "" {
+		return errors.New("the field 'connection' is required")
+	}
+
+	r.Hostname = Fqdn(r.Hostname)
+	r.Zone = Fqdn(r.Zone)
+	if !InZone(r.Hostname, r.Zone) {
+		return fmt.Errorf("hostname %s is outside of zone %s", r.Hostname, r.Zone)
+	}
+
+	if r.Username == "" {
+		r.Username = strings.TrimSuffix(r.Hostname, ".")
+	}
+	if r.Password != "" && len(r.Password) < 12 {
+		return errors.New("the field 'password' must be at least 12 characters long")
+	}
+
+	if r.TTL <= 0 {
+		r.TTL = 60
+	}
+
+	return nil
+}
@@ -0,0 +1,89 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ConnectionHealth is the current health of a connection's PowerDNS API,
+// kept in the "connection_health" collection.
+type ConnectionHealth struct {
+	IdConnection        string     `bson:"idConnection" json:"idConnection"`
+	Name                string     `bson:"name" json:"name"`
+	HostServer          string     `bson:"hostServer" json:"hostServer"`
+	Status              string     `bson:"status" json:"status"`
+	LatencyMs           int64      `bson:"latencyMs" json:"latencyMs"`
+	LastError           string     `bson:"lastError,omitempty" json:"lastError,omitempty"`
+	ConsecutiveFailures int        `bson:"consecutiveFailures" json:"consecutiveFailures"`
+	LastCheckAt         time.Time  `bson:"lastCheckAt" json:"lastCheckAt"`
+	LastChangeAt        *time.Time `bson:"lastChangeAt,omitempty" json:"lastChangeAt,omitempty"`
+}
+
+// HealthCheck is a single probe result, kept in "health_checks".
+type HealthCheck struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	IdConnection string             `bson:"idConnection" json:"idConnection"`
+	Up           bool               `bson:"up" json:"up"`
+	LatencyMs    int64              `bson:"latencyMs" json:"latencyMs"`
+	Error        string             `bson:"error,omitempty" json:"error,omitempty"`
+	CheckedAt    time.Time          `bson:"checkedAt" json:"checkedAt"`
+}
+
+// HealthEvent records an up/down transition, kept in "health_events".
+type HealthEvent struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	IdConnection string             `bson:"idConnection" json:"idConnection"`
+	Name         string             `bson:"name" json:"name"`
+	HostServer   string             `bson:"hostServer" json:"hostServer"`
+	Status       string             `bson:"status" json:"status"`
+	Previous     string             `bson:"previous" json:"previous"`
+	Error        string             `bson:"error,omitempty" json:"error,omitempty"`
+	At           time.Time          `bson:"at" json:"at"`
+}
+
+const (
+	HealthUp      = "up"
+	HealthDown    = "down"
+	HealthUnknown = "unknown"
+)
+
+// Apply updates the health with a probe result and returns the new status.
+// A connection only goes down after failures consecutive failed probes so
+// a single timeout does not raise an alert.
+func (h *ConnectionHealth) Apply(check *HealthCheck, failures int) string {
+	h.LastCheckAt = check.CheckedAt
+	h.LatencyMs = check.LatencyMs
+
+	if check.Up {
+		h.ConsecutiveFailures = 0
+		h.LastError = ""
+		return HealthUp
+	}
+
+	h.ConsecutiveFailures++
+	h.LastError = check.Error
+	if h.ConsecutiveFailures >= failures {
+		return HealthDown
+	}
+	if h.Status == "" {
+		return HealthUnknown
+	}
+	return h.Status
+}
+
+// UptimeRatio returns the share of successful checks, or nil without
+// checks.
+func UptimeRatio(checks []HealthCheck) *float64 {
+	if len(checks) == 0 {
+		return nil
+	}
+	up := 0
+	for _, c := range checks {
+		if c.Up {
+			up++
+		}
+	}
+	r := float64(up) / float64(len(checks))
+	return &r
+}
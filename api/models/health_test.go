@@ -0,0 +1,86 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestConnectionHealthApply(t *testing.T) {
+	t0 := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name         string
+		health       ConnectionHealth
+		up           bool
+		failures     int
+		want         string
+		wantFailures int
+		wantError    string
+	}{
+		{
+			name:   "first probe up",
+			health: ConnectionHealth{},
+			up:     true, failures: 2,
+			want: HealthUp,
+		},
+		{
+			name:   "first probe down below the threshold",
+			health: ConnectionHealth{},
+			up:     false, failures: 2,
+			want: HealthUnknown, wantFailures: 1, wantError: "timeout",
+		},
+		{
+			name:   "first probe down with a threshold of one",
+			health: ConnectionHealth{},
+			up:     false, failures: 1,
+			want: HealthDown, wantFailures: 1, wantError: "timeout",
+		},
+		{
+			name:   "single failure keeps an up connection up",
+			health: ConnectionHealth{Status: HealthUp},
+			up:     false, failures: 2,
+			want: HealthUp, wantFailures: 1, wantError: "timeout",
+		},
+		{
+			name:   "reaching the threshold goes down",
+			health: ConnectionHealth{Status: HealthUp, ConsecutiveFailures: 1, LastError: "refused"},
+			up:     false, failures: 2,
+			want: HealthDown, wantFailures: 2, wantError: "timeout",
+		},
+		{
+			name:   "further failures stay down",
+			health: ConnectionHealth{Status: HealthDown, ConsecutiveFailures: 5},
+			up:     false, failures: 2,
+			want: HealthDown, wantFailures: 6, wantError: "timeout",
+		},
+		{
+			name:   "recovery resets the failures",
+			health: ConnectionHealth{Status: HealthDown, ConsecutiveFailures: 3, LastError: "refused"},
+			up:     true, failures: 2,
+			want: HealthUp,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			check := &HealthCheck{Up: tt.up, LatencyMs: 42, CheckedAt: t0}
+			if !tt.up {
+				check.Error = "timeout"
+			}
+
+			h := tt.health
+			if got := h.Apply(check, tt.failures); got != tt.want {
+				t.Errorf("Apply = %q, want %q", got, tt.want)
+			}
+			if h.ConsecutiveFailures != tt.wantFailures {
+				t.Errorf("consecutive failures = %d, want %d", h.ConsecutiveFailures, tt.wantFailures)
+			}
+			if h.LastError != tt.wantError {
+				t.Errorf("last error = %q, want %q", h.LastError, tt.wantError)
+			}
+			if !h.LastCheckAt.Equal(t0) || h.LatencyMs != 42 {
+				t.Errorf("last check = %s, latency = %d", h.LastCheckAt, h.LatencyMs)
+			}
+		})
+	}
+}
@@ -0,0 +1,217 @@
+package models
+
+import (
+	"net/netip"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// maxIpamScan bounds how many addresses are walked per range when looking
+// for free ones.
+const maxIpamScan = 1 << 16
+
+type IpamName struct {
+	Name string `json:"name"`
+	Zone string `json:"zone"`
+	Type string `json:"type"`
+}
+
+type IpamEntry struct {
+	Address     string     `json:"address"`
+	Names       []IpamName `json:"names"`
+	ReverseName string     `json:"reverseName,omitempty"`
+	ReverseZone string     `json:"reverseZone,omitempty"`
+	Ptr         []string   `json:"ptr"`
+	Zones       []string   `json:"zones"`
+	Conflicts   []string   `json:"conflicts"`
+
+	addr netip.Addr
+}
+
+type IpamRange struct {
+	Prefix    string      `json:"prefix"`
+	Used      int         `json:"used"`
+	Conflicts int         `json:"conflicts"`
+	Free      []string    `json:"free"`
+	Entries   []IpamEntry `json:"entries"`
+}
+
+// ReverseAddr parses an in-addr.arpa or ip6.arpa name back into the address
+// it describes. RFC 2317 names such as "65.64-26.0.0.10.in-addr.arpa." are
+// understood as well.
+func ReverseAddr(name string) (netip.Addr, bool) {
+	name = strings.ToLower(strings.TrimSuffix(name, "."))
+
+	if rest, ok := strings.CutSuffix(name, ".in-addr.arpa"); ok {
+		labels := strings.Split(rest, ".")
+		if len(labels) == 5 && classlessRe.MatchString(labels[1]) {
+			labels = append(labels[:1], labels[2:]...)
+		}
+		if len(labels) != 4 {
+			return netip.Addr{}, false
+		}
+		var b [4]byte
+		for i, label := range labels {
+			n, err := strconv.Atoi(label)
+			if err != nil || n < 0 || n > 255 {
+				return netip.Addr{}, false
+			}
+			b[3-i] = byte(n)
+		}
+		return netip.AddrFrom4(b), true
+	}
+
+	if rest, ok := strings.CutSuffix(name, ".ip6.arpa"); ok {
+		labels := strings.Split(rest, ".")
+		if len(labels) != 32 {
+			return netip.Addr{}, false
+		}
+		var b [16]byte
+		for i, label := range labels {
+			n, err := strconv.ParseUint(label, 16, 8)
+			if err != nil || len(label) != 1 {
+				return netip.Addr{}, false
+			}
+			pos := 31 - i
+			if pos%2 == 0 {
+				b[pos/2] |= byte(n) << 4
+			} else {
+				b[pos/2] |= byte(n)
+			}
+		}
+		return netip.AddrFrom16(b), true
+	}
+
+	return netip.Addr{}, false
+}
+
+// BuildIpam aggregates the A, AAAA and PTR records of zones into a per-IP
+// inventory for each prefix and suggests up to free unused addresses.
+func BuildIpam(prefixes []netip.Prefix, zones []*Zone, free int) []IpamRange {
+	entries := map[netip.Addr]*IpamEntry{}
+	entry := func(addr netip.Addr) *IpamEntry {
+		e, ok := entries[addr]
+		if !ok {
+			e = &IpamEntry{Address: addr.String(), Names: []IpamName{}, Ptr: []string{}, Zones: []string{}, Conflicts: []string{}, addr: addr}
+			entries[addr] = e
+		}
+		return e
+	}
+
+	covered := func(addr netip.Addr) bool {
+		return slices.ContainsFunc(prefixes, func(p netip.Prefix) bool { return p.Contains(addr) })
+	}
+
+	for _, zone := range zones {
+		for _, rr := range zone.RRSets {
+			switch rr.Type {
+			case "A", "AAAA":
+				for _, rec := range rr.Records {
+					addr, err := netip.ParseAddr(rec.Content)
+					if err != nil || !covered(addr.Unmap()) {
+						continue
+					}
+					e := entry(addr.Unmap())
+					e.Names = append(e.Names, IpamName{Name: rr.Name, Zone: zone.Name, Type: rr.Type})
+					if !slices.Contains(e.Zones, zone.Name) {
+						e.Zones = append(e.Zones, zone.Name)
+					}
+				}
+			case "PTR":
+				addr, ok := ReverseAddr(rr.Name)
+				if !ok || !covered(addr) {
+					continue
+				}
+				e := entry(addr)
+				e.ReverseName, e.ReverseZone = rr.Name, zone.Name
+				for _, rec := range rr.Records {
+					e.Ptr = append(e.Ptr, rec.Content)
+				}
+				if !slices.Contains(e.Zones, zone.Name) {
+					e.Zones = append(e.Zones, zone.Name)
+				}
+			}
+		}
+	}
+
+	for _, e := range entries {
+		e.Conflicts = e.conflicts()
+	}
+
+	ranges := make([]IpamRange, 0, len(prefixes))
+	for _, prefix := range prefixes {
+		r := IpamRange{Prefix: prefix.String(), Entries: []IpamEntry{}, Free: []string{}}
+
+		for addr, e := range entries {
+			if prefix.Contains(addr) {
+				r.Entries = append(r.Entries, *e)
+				if len(e.Conflicts) > 0 {
+					r.Conflicts++
+				}
+			}
+		}
+		slices.SortFunc(r.Entries, func(a, b IpamEntry) int { return a.addr.Compare(b.addr) })
+		r.Used = len(r.Entries)
+
+		r.Free = freeAddrs(prefix, entries, free)
+		ranges = append(ranges, r)
+	}
+
+	return ranges
+}
+
+func (e *IpamEntry) conflicts() []string {
+	conflicts := []string{}
+
+	forward := map[string]bool{}
+	for _, n := range e.Names {
+		forward[strings.ToLower(n.Name)] = true
+	}
+
+	switch {
+	case len(e.Names) == 0:
+		conflicts = append(conflicts, "ptr_without_forward")
+	case len(e.Ptr) == 0:
+		conflicts = append(conflicts, "missing_ptr")
+	default:
+		if !slices.ContainsFunc(e.Ptr, func(p string) bool { return forward[strings.ToLower(Fqdn(p))] }) {
+			conflicts = append(conflicts, "ptr_mismatch")
+		}
+	}
+
+	if len(forward) > 1 {
+		conflicts = append(conflicts, "shared_address")
+	}
+	if len(e.Ptr) > 1 {
+		conflicts = append(conflicts, "multiple_ptr")
+	}
+
+	return conflicts
+}
+
+// freeAddrs returns up to n addresses of prefix that are not in use,
+// skipping the network and broadcast addresses of IPv4 subnets.
+func freeAddrs(prefix netip.Prefix, used map[netip.Addr]*IpamEntry, n int) []string {
+	free := []string{}
+	if n <= 0 {
+		return free
+	}
+
+	addr := prefix.Addr()
+	skipEdges := addr.Is4() && prefix.Bits() < 31
+
+	for i := 0; i < maxIpamScan && prefix.Contains(addr) && len(free) < n; i++ {
+		next := addr.Next()
+		edge := skipEdges && (addr == prefix.Addr() || !prefix.Contains(next))
+		if _, ok := used[addr]; !ok && !edge {
+			free = append(free, addr.String())
+		}
+		if !next.IsValid() {
+			break
+		}
+		addr = next
+	}
+
+	return free
+}
@@ -0,0 +1,409 @@
+package models
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/rafinhacuri/SanchezDNS/db"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	LogHashSHA256     = "sha256"
+	LogHashHMACSHA256 = "hmac-sha256"
+
+	// maxChainIssues bounds the problems listed per chain in a report.
+	maxChainIssues = 100
+)
+
+// LogChain is the head of the hash chain of one connection, kept in
+// "log_chains" under the connection ID ("" for entries without one).
+// PrunedSeq and PrunedHash mark where retention cut the chain. With
+// LOG_HMAC_KEY set, HeadMAC and PrunedMAC sign the head and the prune
+// marker so neither can be moved without the key.
+type LogChain struct {
+	ID         string    `bson:"_id" json:"connection"`
+	Seq        int64     `bson:"seq" json:"seq"`
+	Hash       string    `bson:"hash" json:"hash"`
+	HeadMAC    string    `bson:"headMac,omitempty" json:"-"`
+	PrunedSeq  int64     `bson:"prunedSeq,omitempty" json:"prunedSeq,omitempty"`
+	PrunedHash string    `bson:"prunedHash,omitempty" json:"prunedHash,omitempty"`
+	PrunedMAC  string    `bson:"prunedMac,omitempty" json:"-"`
+	UpdatedAt  time.Time `bson:"updatedAt" json:"updatedAt"`
+}
+
+// chainMu serialises inserts within this process and is held by Insert
+// around insertChained. Inserts from other processes are caught by the
+// unique (idConnection, seq) index and retried.
+var chainMu sync.Mutex
+
+// maxChainAttempts bounds the inserts tried when the sequence number is
+// already taken.
+const maxChainAttempts = 5
+
+// logHashKey is the optional server key entries are signed with
+// (LOG_HMAC_KEY).
+func logHashKey() []byte {
+	return []byte(os.Getenv("LOG_HMAC_KEY"))
+}
+
+// HashLog computes the hash of an entry over its content, sequence number
+// and the hash of the previous entry. alg selects plain SHA-256 or
+// HMAC-SHA256 with key.
+func HashLog(l *Log, alg string, key []byte) string {
+	var changes any
+	if len(l.Changes) > 0 {
+		changes = l.Changes
+	}
+
+	canonical, _ := json.Marshal([]any{
+		l.Seq,
+		l.PrevHash,
+		l.IdConnection,
+		l.HostServer,
+		l.Zone,
+		l.Username,
+		l.Action,
+		l.Details,
+		l.CreatedAt.UTC().Format(time.RFC3339Nano),
+		changes,
+	})
+
+	var h hash.Hash
+	if alg == LogHashHMACSHA256 {
+		h = hmac.New(sha256.New, key)
+	} else {
+		h = sha256.New()
+	}
+	h.Write(canonical)
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// chainMAC signs a position of a chain: its head or its prune marker, as
+// given by kind.
+func chainMAC(kind, connectionID string, seq int64, hash string, key []byte) string {
+	canonical, _ := json.Marshal([]any{kind, connectionID, seq, hash})
+
+	h := hmac.New(sha256.New, key)
+	h.Write(canonical)
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// insertChained links the entry to the head of its connection's chain and
+// stores it. When the head is behind the stored entries, because another
+// process inserted first or an earlier head write was lost, the entry is
+// linked to the last stored entry instead.
+func (l *Log) insertChained(ctx context.Context) error {
+	key := logHashKey()
+	l.HashAlg = LogHashSHA256
+	if len(key) > 0 {
+		l.HashAlg = LogHashHMACSHA256
+	}
+
+	chains := db.Database.Collection("log_chains")
+
+	var head LogChain
+	err := chains.FindOne(ctx, bson.M{"_id": l.IdConnection}).Decode(&head)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		return err
+	}
+
+	for attempt := 1; ; attempt++ {
+		l.Seq = head.Seq + 1
+		l.PrevHash = head.Hash
+		l.Hash = HashLog(l, l.HashAlg, key)
+
+		result, err := db.Database.Collection("logs").InsertOne(ctx, l)
+		if mongo.IsDuplicateKeyError(err) && attempt < maxChainAttempts {
+			if head, err = lastChainedLog(ctx, l.IdConnection); err != nil {
+				return err
+			}
+			time.Sleep(time.Duration(attempt) * 20 * time.Millisecond)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if id, ok := result.InsertedID.(primitive.ObjectID); ok {
+			l.ID = id.Hex()
+		}
+		break
+	}
+
+	// The entry is stored, so the head must follow it even when the
+	// request that logged it is cancelled now.
+	ctxHead, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	set := bson.M{"seq": l.Seq, "hash": l.Hash, "updatedAt": time.Now()}
+	update := bson.M{"$set": set}
+	if len(key) > 0 {
+		set["headMac"] = chainMAC("head", l.IdConnection, l.Seq, l.Hash, key)
+	} else {
+		update["$unset"] = bson.M{"headMac": ""}
+	}
+
+	_, err = chains.UpdateOne(ctxHead, bson.M{"_id": l.IdConnection, "seq": bson.M{"$lt": l.Seq}}, update, options.Update().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		// Another process already moved the head past this entry.
+		return nil
+	}
+	return err
+}
+
+// lastChainedLog returns the sequence number and hash of the last stored
+// entry of a connection's chain.
+func lastChainedLog(ctx context.Context, connectionID string) (LogChain, error) {
+	var last Log
+	opts := options.FindOne().SetSort(bson.D{{Key: "seq", Value: -1}}).SetProjection(bson.M{"seq": 1, "hash": 1})
+	err := db.Database.Collection("logs").FindOne(ctx, bson.M{"idConnection": connectionID, "seq": bson.M{"$exists": true}}, opts).Decode(&last)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		return LogChain{}, err
+	}
+	return LogChain{ID: connectionID, Seq: last.Seq, Hash: last.Hash}, nil
+}
+
+// MarkLogsPruned records, for every chain with entries matching filter,
+// the last sequence number and hash about to be removed, so verification
+// can start from the first remaining entry.
+func MarkLogsPruned(ctx context.Context, filter bson.M) error {
+	match := bson.M{"seq": bson.M{"$exists": true}}
+	for k, v := range filter {
+		match[k] = v
+	}
+
+	cursor, err := db.Database.Collection("logs").Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$sort", Value: bson.D{{Key: "seq", Value: -1}}}},
+		{{Key: "$group", Value: bson.M{"_id": "$idConnection", "seq": bson.M{"$first": "$seq"}, "hash": bson.M{"$first": "$hash"}}}},
+	})
+	if err != nil {
+		return err
+	}
+
+	var pruned []struct {
+		ID   string `bson:"_id"`
+		Seq  int64  `bson:"seq"`
+		Hash string `bson:"hash"`
+	}
+	if err := cursor.All(ctx, &pruned); err != nil {
+		return err
+	}
+
+	key := logHashKey()
+	for _, p := range pruned {
+		set := bson.M{"prunedSeq": p.Seq, "prunedHash": p.Hash}
+		update := bson.M{"$set": set}
+		if len(key) > 0 {
+			set["prunedMac"] = chainMAC("pruned", p.ID, p.Seq, p.Hash, key)
+		} else {
+			update["$unset"] = bson.M{"prunedMac": ""}
+		}
+
+		_, err := db.Database.Collection("log_chains").UpdateOne(ctx,
+			bson.M{"_id": p.ID, "$or": []bson.M{{"prunedSeq": bson.M{"$exists": false}}, {"prunedSeq": bson.M{"$lt": p.Seq}}}},
+			update,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+type LogChainIssue struct {
+	Kind    string `json:"kind"`
+	Seq     int64  `json:"seq,omitempty"`
+	ID      string `json:"id,omitempty"`
+	Details string `json:"details"`
+}
+
+// LogChainReport is the outcome of walking one chain.
+type LogChainReport struct {
+	Connection   string          `json:"connection"`
+	Entries      int64           `json:"entries"`
+	HeadSeq      int64           `json:"headSeq"`
+	PrunedSeq    int64           `json:"prunedSeq,omitempty"`
+	Unverifiable int64           `json:"unverifiable,omitempty"`
+	Valid        bool            `json:"valid"`
+	Issues       []LogChainIssue `json:"issues"`
+	MoreIssues   bool            `json:"moreIssues,omitempty"`
+}
+
+func (r *LogChainReport) issue(kind string, l *Log, details string, args ...any) {
+	r.Valid = false
+	if len(r.Issues) == maxChainIssues {
+		r.MoreIssues = true
+		return
+	}
+
+	issue := LogChainIssue{Kind: kind, Details: fmt.Sprintf(details, args...)}
+	if l != nil {
+		issue.Seq = l.Seq
+		issue.ID = l.ID
+	}
+	r.Issues = append(r.Issues, issue)
+}
+
+// VerifyLogChain walks the chain of one connection in sequence order and
+// reports missing, modified or re-linked entries and a head that does not
+// match the last entry. HMAC-signed entries cannot be checked without
+// LOG_HMAC_KEY and are only counted. With the key, plain SHA-256 entries
+// are counted as unverifiable too, since anyone can recompute them, and
+// the signatures of the head and the prune marker are checked.
+func VerifyLogChain(ctx context.Context, head LogChain) (*LogChainReport, error) {
+	walk := newChainWalk(head, logHashKey())
+
+	opts := options.Find().SetSort(bson.D{{Key: "seq", Value: 1}}).SetBatchSize(1000)
+	cursor, err := db.Database.Collection("logs").Find(ctx, bson.M{"idConnection": head.ID, "seq": bson.M{"$exists": true}}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var entry Log
+		if err := cursor.Decode(&entry); err != nil {
+			return nil, err
+		}
+		walk.add(&entry)
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	return walk.finish(), nil
+}
+
+// chainWalk checks the entries of a chain fed to it in sequence order.
+type chainWalk struct {
+	head     LogChain
+	key      []byte
+	report   *LogChainReport
+	started  bool
+	lastSeq  int64
+	lastHash string
+	signed   bool
+	// firstSigned is whether the first remaining entry is signed.
+	firstSigned bool
+}
+
+func newChainWalk(head LogChain, key []byte) *chainWalk {
+	return &chainWalk{
+		head:   head,
+		key:    key,
+		report: &LogChainReport{Connection: head.ID, HeadSeq: head.Seq, PrunedSeq: head.PrunedSeq, Valid: true, Issues: []LogChainIssue{}},
+	}
+}
+
+func (w *chainWalk) add(entry *Log) {
+	report, head := w.report, w.head
+	report.Entries++
+
+	switch {
+	case !w.started && entry.Seq <= head.PrunedSeq:
+		// Left behind by retention; its predecessor may be gone.
+	case !w.started && entry.Seq == head.PrunedSeq+1:
+		if head.PrunedSeq > 0 && entry.PrevHash != head.PrunedHash {
+			report.issue("broken_link", entry, "previous hash does not match the last pruned entry")
+		}
+	case !w.started:
+		report.issue("missing", entry, "entries %d to %d are missing", head.PrunedSeq+1, entry.Seq-1)
+	case entry.Seq == w.lastSeq:
+		report.issue("duplicate", entry, "sequence number %d is used twice", entry.Seq)
+	case entry.Seq != w.lastSeq+1:
+		report.issue("missing", entry, "entries %d to %d are missing", w.lastSeq+1, entry.Seq-1)
+	case entry.PrevHash != w.lastHash:
+		report.issue("broken_link", entry, "previous hash does not match entry %d", w.lastSeq)
+	}
+
+	if !w.started {
+		w.firstSigned = entry.HashAlg == LogHashHMACSHA256
+	}
+
+	switch entry.HashAlg {
+	case LogHashHMACSHA256:
+		w.signed = true
+		if len(w.key) == 0 {
+			report.Unverifiable++
+		} else if !hmac.Equal([]byte(HashLog(entry, entry.HashAlg, w.key)), []byte(entry.Hash)) {
+			report.issue("modified", entry, "content does not match its signature")
+		}
+	case LogHashSHA256:
+		if w.signed {
+			report.issue("unsigned", entry, "unsigned entry after signed entries")
+		} else if len(w.key) > 0 {
+			report.Unverifiable++
+		}
+		if HashLog(entry, entry.HashAlg, nil) != entry.Hash {
+			report.issue("modified", entry, "content does not match its hash")
+		}
+	default:
+		report.issue("modified", entry, "unknown hash algorithm %q", entry.HashAlg)
+	}
+
+	w.started = true
+	w.lastSeq, w.lastHash = entry.Seq, entry.Hash
+}
+
+// finish compares the head with the last entry and returns the report.
+func (w *chainWalk) finish() *LogChainReport {
+	report, head := w.report, w.head
+
+	switch {
+	case head.Seq > w.lastSeq && head.Seq > head.PrunedSeq:
+		report.issue("truncated", nil, "chain head is at %d but the last entry is %d", head.Seq, w.lastSeq)
+	case head.Seq < w.lastSeq:
+		report.issue("head_mismatch", nil, "chain head is at %d behind the last entry %d", head.Seq, w.lastSeq)
+	case head.Seq == w.lastSeq && head.Seq > 0 && head.Hash != w.lastHash:
+		report.issue("head_mismatch", nil, "chain head hash does not match entry %d", w.lastSeq)
+	}
+
+	if len(w.key) > 0 {
+		w.checkSignatures()
+	}
+
+	return report
+}
+
+// checkSignatures checks the signed head and prune marker. They may only
+// lack a signature while the entries they point at are not signed either,
+// as after LOG_HMAC_KEY was first set.
+func (w *chainWalk) checkSignatures() {
+	report, head := w.report, w.head
+
+	switch {
+	case head.Seq == 0:
+	case head.HeadMAC != "":
+		if !hmac.Equal([]byte(chainMAC("head", head.ID, head.Seq, head.Hash, w.key)), []byte(head.HeadMAC)) {
+			report.issue("head_mismatch", nil, "chain head does not match its signature")
+		}
+	case w.signed:
+		report.issue("unsigned", nil, "chain head is not signed")
+	}
+
+	switch {
+	case head.PrunedSeq == 0:
+	case head.PrunedMAC != "":
+		if !hmac.Equal([]byte(chainMAC("pruned", head.ID, head.PrunedSeq, head.PrunedHash, w.key)), []byte(head.PrunedMAC)) {
+			report.issue("broken_link", nil, "prune marker does not match its signature")
+		}
+	case w.firstSigned:
+		report.issue("unsigned", nil, "prune marker is not signed")
+	}
+}
@@ -0,0 +1,362 @@
+package models
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+// buildChain links n entries starting at seq start after prevHash, the way
+// insertChained does.
+func buildChain(start int64, prevHash string, n int, alg string, key []byte) []Log {
+	t0 := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	entries := make([]Log, n)
+	for i := range entries {
+		l := &entries[i]
+		l.ID = string(rune('a' + i))
+		l.IdConnection = "conn"
+		l.Username = "admin@example.com"
+		l.Action = "create_record"
+		l.Zone = "example.com."
+		l.Details = "Added record"
+		l.Changes = []RRSetChange{{Action: "create", Name: "www.example.com.", Type: "A", TTL: 300, After: []string{"192.0.2.1"}}}
+		l.CreatedAt = t0.Add(time.Duration(i) * time.Minute)
+		l.Seq = start + int64(i)
+		l.PrevHash = prevHash
+		l.HashAlg = alg
+		l.Hash = HashLog(l, alg, key)
+		prevHash = l.Hash
+	}
+	return entries
+}
+
+func headOf(entries []Log) LogChain {
+	last := entries[len(entries)-1]
+	return LogChain{ID: "conn", Seq: last.Seq, Hash: last.Hash}
+}
+
+// signHead signs the head and prune marker the way insertChained and
+// MarkLogsPruned do with a key.
+func signHead(head LogChain, key []byte) LogChain {
+	head.HeadMAC = chainMAC("head", head.ID, head.Seq, head.Hash, key)
+	if head.PrunedSeq > 0 {
+		head.PrunedMAC = chainMAC("pruned", head.ID, head.PrunedSeq, head.PrunedHash, key)
+	}
+	return head
+}
+
+func walkChain(head LogChain, entries []Log, key []byte) *LogChainReport {
+	walk := newChainWalk(head, key)
+	for i := range entries {
+		walk.add(&entries[i])
+	}
+	return walk.finish()
+}
+
+func issueKinds(r *LogChainReport) []string {
+	kinds := []string{}
+	for _, issue := range r.Issues {
+		kinds = append(kinds, issue.Kind)
+	}
+	return kinds
+}
+
+func TestHashLog(t *testing.T) {
+	l := buildChain(1, "", 1, LogHashSHA256, nil)[0]
+
+	if got := HashLog(&l, LogHashSHA256, nil); got != l.Hash {
+		t.Fatalf("HashLog is not deterministic: %s != %s", got, l.Hash)
+	}
+
+	hmac1 := HashLog(&l, LogHashHMACSHA256, []byte("key-one"))
+	hmac2 := HashLog(&l, LogHashHMACSHA256, []byte("key-two"))
+	if hmac1 == l.Hash || hmac1 == hmac2 {
+		t.Errorf("HMAC hashes must differ from SHA-256 and between keys")
+	}
+
+	for name, mutate := range map[string]func(*Log){
+		"seq":       func(l *Log) { l.Seq++ },
+		"prevHash":  func(l *Log) { l.PrevHash = "x" },
+		"username":  func(l *Log) { l.Username = "other@example.com" },
+		"details":   func(l *Log) { l.Details += "!" },
+		"changes":   func(l *Log) { l.Changes[0].After = []string{"192.0.2.2"} },
+		"createdAt": func(l *Log) { l.CreatedAt = l.CreatedAt.Add(time.Millisecond) },
+	} {
+		c := l
+		c.Changes = slices.Clone(l.Changes)
+		mutate(&c)
+		if HashLog(&c, LogHashSHA256, nil) == l.Hash {
+			t.Errorf("changing %s does not change the hash", name)
+		}
+	}
+}
+
+func TestVerifyLogChain(t *testing.T) {
+	key := []byte("secret-key")
+
+	tests := []struct {
+		name         string
+		build        func() (LogChain, []Log)
+		key          []byte
+		want         []string
+		unverifiable int64
+	}{
+		{
+			name: "valid",
+			build: func() (LogChain, []Log) {
+				entries := buildChain(1, "", 5, LogHashSHA256, nil)
+				return headOf(entries), entries
+			},
+		},
+		{
+			name: "modified content",
+			build: func() (LogChain, []Log) {
+				entries := buildChain(1, "", 5, LogHashSHA256, nil)
+				entries[2].Details = "Removed record"
+				return headOf(entries), entries
+			},
+			want: []string{"modified"},
+		},
+		{
+			name: "re-hashed entry breaks the next link",
+			build: func() (LogChain, []Log) {
+				entries := buildChain(1, "", 5, LogHashSHA256, nil)
+				entries[2].Details = "Removed record"
+				entries[2].Hash = HashLog(&entries[2], LogHashSHA256, nil)
+				return headOf(entries), entries
+			},
+			want: []string{"broken_link"},
+		},
+		{
+			name: "missing entries",
+			build: func() (LogChain, []Log) {
+				entries := buildChain(1, "", 5, LogHashSHA256, nil)
+				return headOf(entries), slices.Delete(entries, 1, 3)
+			},
+			want: []string{"missing"},
+		},
+		{
+			name: "missing first entries",
+			build: func() (LogChain, []Log) {
+				entries := buildChain(1, "", 5, LogHashSHA256, nil)
+				return headOf(entries), entries[2:]
+			},
+			want: []string{"missing"},
+		},
+		{
+			name: "duplicate sequence number",
+			build: func() (LogChain, []Log) {
+				entries := buildChain(1, "", 3, LogHashSHA256, nil)
+				return headOf(entries), slices.Insert(entries, 2, entries[1])
+			},
+			want: []string{"duplicate"},
+		},
+		{
+			name: "truncated tail",
+			build: func() (LogChain, []Log) {
+				entries := buildChain(1, "", 5, LogHashSHA256, nil)
+				return headOf(entries), entries[:3]
+			},
+			want: []string{"truncated"},
+		},
+		{
+			name: "head behind the entries",
+			build: func() (LogChain, []Log) {
+				entries := buildChain(1, "", 5, LogHashSHA256, nil)
+				return headOf(entries[:4]), entries
+			},
+			want: []string{"head_mismatch"},
+		},
+		{
+			name: "head hash differs",
+			build: func() (LogChain, []Log) {
+				entries := buildChain(1, "", 5, LogHashSHA256, nil)
+				head := headOf(entries)
+				head.Hash = "0000"
+				return head, entries
+			},
+			want: []string{"head_mismatch"},
+		},
+		{
+			name: "pruned chain",
+			build: func() (LogChain, []Log) {
+				entries := buildChain(1, "", 6, LogHashSHA256, nil)
+				head := headOf(entries)
+				head.PrunedSeq, head.PrunedHash = entries[2].Seq, entries[2].Hash
+				return head, entries[3:]
+			},
+		},
+		{
+			name: "pruned chain keeps entries left behind",
+			build: func() (LogChain, []Log) {
+				entries := buildChain(1, "", 6, LogHashSHA256, nil)
+				head := headOf(entries)
+				head.PrunedSeq, head.PrunedHash = entries[2].Seq, entries[2].Hash
+				return head, entries[2:]
+			},
+		},
+		{
+			name: "pruned chain with a forged first link",
+			build: func() (LogChain, []Log) {
+				entries := buildChain(1, "", 6, LogHashSHA256, nil)
+				head := headOf(entries)
+				head.PrunedSeq, head.PrunedHash = entries[2].Seq, "forged"
+				return head, entries[3:]
+			},
+			want: []string{"broken_link"},
+		},
+		{
+			name: "fully pruned chain",
+			build: func() (LogChain, []Log) {
+				entries := buildChain(1, "", 3, LogHashSHA256, nil)
+				head := headOf(entries)
+				head.PrunedSeq, head.PrunedHash = head.Seq, head.Hash
+				return head, nil
+			},
+		},
+		{
+			name: "signed chain with the key",
+			build: func() (LogChain, []Log) {
+				entries := buildChain(1, "", 4, LogHashHMACSHA256, key)
+				return signHead(headOf(entries), key), entries
+			},
+			key: key,
+		},
+		{
+			name: "signed chain with a wrong key",
+			build: func() (LogChain, []Log) {
+				entries := buildChain(1, "", 2, LogHashHMACSHA256, key)
+				return signHead(headOf(entries), key), entries
+			},
+			key:  []byte("other-key"),
+			want: []string{"modified", "modified", "head_mismatch"},
+		},
+		{
+			name: "signed chain truncated with its head moved back",
+			build: func() (LogChain, []Log) {
+				entries := buildChain(1, "", 4, LogHashHMACSHA256, key)
+				head := signHead(headOf(entries), key)
+				head.Seq, head.Hash = entries[1].Seq, entries[1].Hash
+				return head, entries[:2]
+			},
+			key:  key,
+			want: []string{"head_mismatch"},
+		},
+		{
+			name: "signed chain with an unsigned head",
+			build: func() (LogChain, []Log) {
+				entries := buildChain(1, "", 2, LogHashHMACSHA256, key)
+				return headOf(entries), entries
+			},
+			key:  key,
+			want: []string{"unsigned"},
+		},
+		{
+			name: "signed chain with a signed prune marker",
+			build: func() (LogChain, []Log) {
+				entries := buildChain(1, "", 4, LogHashHMACSHA256, key)
+				head := headOf(entries)
+				head.PrunedSeq, head.PrunedHash = entries[1].Seq, entries[1].Hash
+				return signHead(head, key), entries[2:]
+			},
+			key: key,
+		},
+		{
+			name: "signed chain with a prune marker moved forward",
+			build: func() (LogChain, []Log) {
+				entries := buildChain(1, "", 4, LogHashHMACSHA256, key)
+				head := headOf(entries)
+				head.PrunedSeq, head.PrunedHash = entries[0].Seq, entries[0].Hash
+				head = signHead(head, key)
+				head.PrunedSeq, head.PrunedHash = entries[1].Seq, entries[1].Hash
+				return head, entries[2:]
+			},
+			key:  key,
+			want: []string{"broken_link"},
+		},
+		{
+			name: "signed chain with an unsigned prune marker",
+			build: func() (LogChain, []Log) {
+				entries := buildChain(1, "", 4, LogHashHMACSHA256, key)
+				head := signHead(headOf(entries), key)
+				head.PrunedSeq, head.PrunedHash = entries[1].Seq, entries[1].Hash
+				return head, entries[2:]
+			},
+			key:  key,
+			want: []string{"unsigned"},
+		},
+		{
+			name: "plain chain with the key",
+			build: func() (LogChain, []Log) {
+				entries := buildChain(1, "", 3, LogHashSHA256, nil)
+				return headOf(entries), entries
+			},
+			key:          key,
+			unverifiable: 3,
+		},
+		{
+			name: "signed chain without the key",
+			build: func() (LogChain, []Log) {
+				entries := buildChain(1, "", 4, LogHashHMACSHA256, key)
+				return headOf(entries), entries
+			},
+			unverifiable: 4,
+		},
+		{
+			name: "signing enabled later",
+			build: func() (LogChain, []Log) {
+				plain := buildChain(1, "", 2, LogHashSHA256, nil)
+				signed := buildChain(3, plain[1].Hash, 2, LogHashHMACSHA256, key)
+				entries := append(plain, signed...)
+				return signHead(headOf(entries), key), entries
+			},
+			key:          key,
+			unverifiable: 2,
+		},
+		{
+			name: "unsigned entry after signed entries",
+			build: func() (LogChain, []Log) {
+				signed := buildChain(1, "", 2, LogHashHMACSHA256, key)
+				plain := buildChain(3, signed[1].Hash, 1, LogHashSHA256, nil)
+				entries := append(signed, plain...)
+				return headOf(entries), entries
+			},
+			key:  key,
+			want: []string{"unsigned", "unsigned"},
+		},
+		{
+			name: "unknown algorithm",
+			build: func() (LogChain, []Log) {
+				entries := buildChain(1, "", 2, LogHashSHA256, nil)
+				entries[1].HashAlg = "md5"
+				return headOf(entries), entries
+			},
+			want: []string{"modified"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			head, entries := tt.build()
+			report := walkChain(head, entries, tt.key)
+
+			want := tt.want
+			if want == nil {
+				want = []string{}
+			}
+			if got := issueKinds(report); !slices.Equal(got, want) {
+				t.Errorf("issues = %v, want %v (%+v)", got, want, report.Issues)
+			}
+			if report.Valid != (len(want) == 0) {
+				t.Errorf("valid = %v with issues %v", report.Valid, want)
+			}
+			if report.Unverifiable != tt.unverifiable {
+				t.Errorf("unverifiable = %d, want %d", report.Unverifiable, tt.unverifiable)
+			}
+			if report.Entries != int64(len(entries)) {
+				t.Errorf("entries = %d, want %d", report.Entries, len(entries))
+			}
+		})
+	}
+}
@@ -0,0 +1,250 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// logSortFields maps the accepted ?sort= values to the BSON type of the
+// field, which a cursor value must have.
+var logSortFields = map[string]bsontype.Type{
+	"createdAt":  bson.TypeDateTime,
+	"username":   bson.TypeString,
+	"action":     bson.TypeString,
+	"zone":       bson.TypeString,
+	"hostServer": bson.TypeString,
+}
+
+const maxLogLimit = 500
+
+// LogQuery is a parsed logs listing request. Results are ordered by Sort
+// and then _id, which makes the order total so a cursor can resume right
+// after the last entry of a page.
+type LogQuery struct {
+	LogExportQuery
+	Usernames  []string
+	Actions    []string
+	Zones      []string
+	Search     string
+	Sort       string
+	Descending bool
+	Limit      int
+	Page       int
+	Cursor     *LogCursor
+}
+
+// LogCursor is the position after the last entry of a page: the value of
+// the sort field and the entry ID. It also carries the sort, the order and
+// a hash of the filters it was issued for, since the position means
+// nothing in another listing.
+type LogCursor struct {
+	Value      bson.RawValue      `bson:"v"`
+	ID         primitive.ObjectID `bson:"id"`
+	Sort       string             `bson:"s"`
+	Descending bool               `bson:"d"`
+	Filters    string             `bson:"f"`
+}
+
+var errCursorMismatch = errors.New("cursor does not match the sort, order or filters")
+
+// ParseLogQuery reads the listing parameters. List parameters accept
+// repeated keys and comma separated values; an empty or "all" connection
+// selects every connection.
+func ParseLogQuery(values url.Values) (*LogQuery, error) {
+	export, err := ParseLogExportQuery("", values.Get("from"), values.Get("to"), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	q := &LogQuery{
+		LogExportQuery: *export,
+		Usernames:      splitValues(values["username"]),
+		Actions:        splitValues(values["action"]),
+		Search:         strings.TrimSpace(values.Get("filter")),
+		Sort:           values.Get("sort"),
+		Descending:     values.Get("order") != "asc",
+	}
+
+	for _, c := range splitValues(values["connection"]) {
+		if c == "all" {
+			q.Connections = nil
+			break
+		}
+		q.Connections = append(q.Connections, c)
+	}
+
+	for _, zone := range splitValues(values["zone"]) {
+		zone = strings.ToLower(zone)
+		q.Zones = append(q.Zones, Fqdn(zone), strings.TrimSuffix(zone, "."))
+	}
+
+	if q.Sort == "" {
+		q.Sort = "createdAt"
+	}
+	if _, ok := logSortFields[q.Sort]; !ok {
+		return nil, errors.New("invalid sort field")
+	}
+
+	q.Limit, _ = strconv.Atoi(values.Get("limit"))
+	if q.Limit < 1 {
+		q.Limit = 10
+	}
+	q.Limit = min(q.Limit, maxLogLimit)
+
+	if raw := values.Get("cursor"); raw != "" {
+		if q.Cursor, err = decodeLogCursor(raw, q); err != nil {
+			if errors.Is(err, errCursorMismatch) {
+				return nil, err
+			}
+			return nil, errors.New("invalid cursor")
+		}
+	} else {
+		q.Page, _ = strconv.Atoi(values.Get("page"))
+		q.Page = max(q.Page, 1)
+	}
+
+	return q, nil
+}
+
+func splitValues(values []string) []string {
+	var out []string
+	for _, v := range values {
+		out = append(out, strings.Split(v, ",")...)
+	}
+	return cleanList(out, false)
+}
+
+// Filter combines the structured filters with the literal search. The
+// cursor condition is not included.
+func (q *LogQuery) Filter() bson.M {
+	filter := q.LogExportQuery.Filter()
+
+	if len(q.Usernames) > 0 {
+		filter["username"] = bson.M{"$in": q.Usernames}
+	}
+	if len(q.Actions) > 0 {
+		filter["action"] = bson.M{"$in": q.Actions}
+	}
+	if len(q.Zones) > 0 {
+		filter["zone"] = bson.M{"$in": q.Zones}
+	}
+
+	if q.Search != "" {
+		pattern := primitive.Regex{Pattern: regexp.QuoteMeta(q.Search), Options: "i"}
+		filter["$or"] = []bson.M{
+			{"username": pattern},
+			{"action": pattern},
+			{"details": pattern},
+			{"zone": pattern},
+			{"hostServer": pattern},
+		}
+	}
+
+	return filter
+}
+
+// PageFilter is Filter restricted to the entries after the cursor.
+func (q *LogQuery) PageFilter() bson.M {
+	filter := q.Filter()
+	if q.Cursor == nil {
+		return filter
+	}
+
+	op := "$gt"
+	if q.Descending {
+		op = "$lt"
+	}
+
+	after := []bson.M{
+		{q.Sort: bson.M{op: q.Cursor.Value}},
+		{q.Sort: q.Cursor.Value, "_id": bson.M{op: q.Cursor.ID}},
+	}
+
+	return bson.M{"$and": []bson.M{filter, {"$or": after}}}
+}
+
+func (q *LogQuery) SortSpec() bson.D {
+	order := 1
+	if q.Descending {
+		order = -1
+	}
+	return bson.D{{Key: q.Sort, Value: order}, {Key: "_id", Value: order}}
+}
+
+// NextCursor encodes the position after entry, read from its raw document.
+func (q *LogQuery) NextCursor(entry bson.Raw) (string, error) {
+	id, ok := entry.Lookup("_id").ObjectIDOK()
+	if !ok {
+		return "", errors.New("log entry without an ObjectID")
+	}
+
+	value, err := entry.LookupErr(q.Sort)
+	if err != nil {
+		return "", err
+	}
+
+	raw, err := bson.Marshal(LogCursor{Value: value, ID: id, Sort: q.Sort, Descending: q.Descending, Filters: q.filtersHash()})
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+// filtersHash identifies the filters of the query. Lists are compared as
+// sets.
+func (q *LogQuery) filtersHash() string {
+	sorted := func(values []string) []string {
+		values = slices.Clone(values)
+		slices.Sort(values)
+		return values
+	}
+
+	canonical, _ := json.Marshal([]any{
+		q.From.UTC().Format(time.RFC3339Nano),
+		q.To.UTC().Format(time.RFC3339Nano),
+		sorted(q.Connections),
+		sorted(q.Usernames),
+		sorted(q.Actions),
+		sorted(q.Zones),
+		q.Search,
+	})
+
+	sum := sha256.Sum256(canonical)
+	return hex.EncodeToString(sum[:16])
+}
+
+// decodeLogCursor reads a cursor and checks it was issued for the same
+// sort, order and filters as q.
+func decodeLogCursor(raw string, q *LogQuery) (*LogCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	var cursor LogCursor
+	if err := bson.Unmarshal(data, &cursor); err != nil {
+		return nil, err
+	}
+	if cursor.Sort != q.Sort || cursor.Descending != q.Descending || cursor.Filters != q.filtersHash() {
+		return nil, errCursorMismatch
+	}
+	if cursor.Value.Type != logSortFields[q.Sort] {
+		return nil, errors.New("cursor value does not match the sort field")
+	}
+
+	return &cursor, nil
+}
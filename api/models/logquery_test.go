@@ -0,0 +1,137 @@
+package models
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"slices"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestParseLogQuery(t *testing.T) {
+	tests := []struct {
+		query   string
+		wantErr bool
+		check   func(*LogQuery) bool
+	}{
+		{"", false, func(q *LogQuery) bool {
+			return q.Sort == "createdAt" && q.Descending && q.Limit == 10 && q.Page == 1 && q.Cursor == nil
+		}},
+		{"sort=username&order=asc&limit=50&page=3", false, func(q *LogQuery) bool {
+			return q.Sort == "username" && !q.Descending && q.Limit == 50 && q.Page == 3
+		}},
+		{"limit=100000", false, func(q *LogQuery) bool { return q.Limit == maxLogLimit }},
+		{"limit=-1&page=0", false, func(q *LogQuery) bool { return q.Limit == 10 && q.Page == 1 }},
+		{"username=a@x.com,b@x.com&username=a@x.com&action=create_zone", false, func(q *LogQuery) bool {
+			return slices.Equal(q.Usernames, []string{"a@x.com", "b@x.com"}) && slices.Equal(q.Actions, []string{"create_zone"})
+		}},
+		{"zone=Example.COM", false, func(q *LogQuery) bool {
+			return slices.Equal(q.Zones, []string{"example.com.", "example.com"})
+		}},
+		{"connection=a,b", false, func(q *LogQuery) bool { return slices.Equal(q.Connections, []string{"a", "b"}) }},
+		{"connection=a&connection=all", false, func(q *LogQuery) bool { return q.Connections == nil }},
+		{"filter=%20needle%20", false, func(q *LogQuery) bool { return q.Search == "needle" }},
+		{"from=2025-01-01&to=2025-01-01", false, func(q *LogQuery) bool {
+			return q.From.Equal(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)) && q.To.Equal(time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC))
+		}},
+		{"sort=details", true, nil},
+		{"from=yesterday", true, nil},
+		{"from=2025-02-01&to=2025-01-01", true, nil},
+		{"cursor=not-a-cursor", true, nil},
+	}
+
+	for _, tt := range tests {
+		values, _ := url.ParseQuery(tt.query)
+		q, err := ParseLogQuery(values)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseLogQuery(%q) error = %v, wantErr %v", tt.query, err, tt.wantErr)
+			continue
+		}
+		if err == nil && !tt.check(q) {
+			t.Errorf("ParseLogQuery(%q) = %+v", tt.query, q)
+		}
+	}
+}
+
+func TestLogCursor(t *testing.T) {
+	id := primitive.NewObjectID()
+	createdAt := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	entry, err := bson.Marshal(bson.M{"_id": id, "createdAt": createdAt, "username": "admin@example.com", "action": "create_zone"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parse := func(query string) *LogQuery {
+		values, _ := url.ParseQuery(query)
+		q, err := ParseLogQuery(values)
+		if err != nil {
+			t.Fatalf("ParseLogQuery(%q): %v", query, err)
+		}
+		return q
+	}
+
+	const listing = "sort=username&order=asc&action=create_zone,delete_zone&zone=example.com"
+	next, err := parse(listing).NextCursor(entry)
+	if err != nil {
+		t.Fatalf("NextCursor: %v", err)
+	}
+
+	t.Run("round trip", func(t *testing.T) {
+		q := parse(listing + "&cursor=" + next)
+		if q.Cursor == nil || q.Cursor.ID != id || q.Cursor.Value.StringValue() != "admin@example.com" {
+			t.Fatalf("cursor = %+v", q.Cursor)
+		}
+		if q.Page != 0 {
+			t.Errorf("page = %d with a cursor", q.Page)
+		}
+	})
+
+	t.Run("filter order does not matter", func(t *testing.T) {
+		values, _ := url.ParseQuery("sort=username&order=asc&action=delete_zone&action=create_zone&zone=example.com&cursor=" + next)
+		if _, err := ParseLogQuery(values); err != nil {
+			t.Errorf("ParseLogQuery: %v", err)
+		}
+	})
+
+	for name, query := range map[string]string{
+		"other sort":   "sort=action&order=asc&action=create_zone,delete_zone&zone=example.com",
+		"other order":  "sort=username&order=desc&action=create_zone,delete_zone&zone=example.com",
+		"other filter": "sort=username&order=asc&action=create_zone&zone=example.com",
+		"other search": listing + "&filter=x",
+		"other range":  listing + "&from=2025-01-01",
+	} {
+		t.Run(name, func(t *testing.T) {
+			values, _ := url.ParseQuery(query + "&cursor=" + next)
+			if _, err := ParseLogQuery(values); !errors.Is(err, errCursorMismatch) {
+				t.Errorf("ParseLogQuery error = %v, want %v", err, errCursorMismatch)
+			}
+		})
+	}
+
+	t.Run("forged value type", func(t *testing.T) {
+		q := parse(listing)
+		_, value, _ := bson.MarshalValue(createdAt)
+		raw, _ := bson.Marshal(LogCursor{Value: bson.RawValue{Type: bson.TypeDateTime, Value: value}, ID: id, Sort: q.Sort, Descending: q.Descending, Filters: q.filtersHash()})
+		if _, err := decodeLogCursor(base64.RawURLEncoding.EncodeToString(raw), q); err == nil {
+			t.Error("decodeLogCursor accepted a date for a string sort field")
+		}
+	})
+
+	t.Run("garbage", func(t *testing.T) {
+		for _, raw := range []string{"!!!", base64.RawURLEncoding.EncodeToString([]byte("not bson"))} {
+			if _, err := decodeLogCursor(raw, parse(listing)); err == nil {
+				t.Errorf("decodeLogCursor(%q) succeeded", raw)
+			}
+		}
+	})
+
+	t.Run("entry without the sort field", func(t *testing.T) {
+		if _, err := parse("sort=zone").NextCursor(entry); err == nil {
+			t.Error("NextCursor succeeded without a zone field")
+		}
+	})
+}
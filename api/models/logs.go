@@ -1,14 +1,191 @@
 package models
 
-import "time"
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
 
 type Log struct {
-	ID           string    `bson:"_id,omitempty" json:"id"`
-	IdConnection string    `bson:"idConnection" json:"idConnection"`
-	HostServer   string    `bson:"hostServer" json:"hostServer"`
-	Zone         string    `bson:"zone" json:"zone"`
-	Username     string    `bson:"username" json:"username"`
-	Action       string    `bson:"action" json:"action"`
-	Details      string    `bson:"details" json:"details"`
-	CreatedAt    time.Time `bson:"createdAt" json:"createdAt"`
+	ID           string        `bson:"_id,omitempty" json:"id"`
+	IdConnection string        `bson:"idConnection" json:"idConnection"`
+	HostServer   string        `bson:"hostServer" json:"hostServer"`
+	Zone         string        `bson:"zone" json:"zone"`
+	Username     string        `bson:"username" json:"username"`
+	Action       string        `bson:"action" json:"action"`
+	Details      string        `bson:"details" json:"details"`
+	Changes      []RRSetChange `bson:"changes,omitempty" json:"changes,omitempty"`
+	Seq          int64         `bson:"seq,omitempty" json:"seq,omitempty"`
+	PrevHash     string        `bson:"prevHash,omitempty" json:"prevHash,omitempty"`
+	Hash         string        `bson:"hash,omitempty" json:"hash,omitempty"`
+	HashAlg      string        `bson:"hashAlg,omitempty" json:"hashAlg,omitempty"`
+	CreatedAt    time.Time     `bson:"createdAt" json:"createdAt"`
+}
+
+var (
+	logHooks     []func(Log)
+	syncLogHooks []func(Log)
+)
+
+// OnLogInsert registers fn to be called, in its own goroutine, with every
+// log entry after it is stored.
+func OnLogInsert(fn func(Log)) {
+	logHooks = append(logHooks, fn)
+}
+
+// OnLogInsertSync registers fn to be called with every log entry right
+// after it is stored, in the order the entries were chained. fn runs while
+// other inserts wait, so it must not block.
+func OnLogInsertSync(fn func(Log)) {
+	syncLogHooks = append(syncLogHooks, fn)
+}
+
+func (l *Log) Insert(ctx context.Context) error {
+	if l.CreatedAt.IsZero() {
+		l.CreatedAt = time.Now()
+	}
+	// MongoDB keeps milliseconds; the hash must match what is read back.
+	l.CreatedAt = l.CreatedAt.Truncate(time.Millisecond)
+
+	chainMu.Lock()
+	err := l.insertChained(ctx)
+	if err == nil {
+		for _, fn := range syncLogHooks {
+			fn(*l)
+		}
+	}
+	chainMu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	for _, fn := range logHooks {
+		go fn(*l)
+	}
+
+	return nil
+}
+
+// LogExportQuery selects the entries streamed by the log export.
+type LogExportQuery struct {
+	Format      string
+	From        time.Time
+	To          time.Time
+	Connections []string
+}
+
+// ParseLogExportQuery reads the export parameters. Dates are RFC 3339
+// timestamps or plain days; a plain "to" day is inclusive.
+func ParseLogExportQuery(format, from, to string, connections []string) (*LogExportQuery, error) {
+	q := &LogExportQuery{Format: strings.ToLower(strings.TrimSpace(format))}
+
+	if q.Format == "" {
+		q.Format = "ndjson"
+	}
+	if q.Format != "ndjson" && q.Format != "csv" {
+		return nil, errors.New("format must be csv or ndjson")
+	}
+
+	var err error
+	if from != "" {
+		if q.From, err = parseLogDate(from, false); err != nil {
+			return nil, errors.New("invalid from date")
+		}
+	}
+	if to != "" {
+		if q.To, err = parseLogDate(to, true); err != nil {
+			return nil, errors.New("invalid to date")
+		}
+	}
+	if !q.From.IsZero() && !q.To.IsZero() && !q.To.After(q.From) {
+		return nil, errors.New("to must be after from")
+	}
+
+	for _, c := range connections {
+		q.Connections = append(q.Connections, strings.Split(c, ",")...)
+	}
+	q.Connections = cleanList(q.Connections, false)
+
+	return q, nil
+}
+
+func parseLogDate(value string, endOfDay bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
+
+func (q *LogExportQuery) Filter() bson.M {
+	filter := bson.M{}
+
+	createdAt := bson.M{}
+	if !q.From.IsZero() {
+		createdAt["$gte"] = q.From
+	}
+	if !q.To.IsZero() {
+		createdAt["$lt"] = q.To
+	}
+	if len(createdAt) > 0 {
+		filter["createdAt"] = createdAt
+	}
+
+	if len(q.Connections) > 0 {
+		filter["idConnection"] = bson.M{"$in": q.Connections}
+	}
+
+	return filter
+}
+
+var LogCSVHeader = []string{"id", "createdAt", "username", "action", "idConnection", "hostServer", "zone", "details", "changes", "seq", "prevHash", "hash"}
+
+// CSVRow flattens the entry for the CSV export. The rrset changes are kept
+// as a JSON column.
+func (l *Log) CSVRow() []string {
+	changes := ""
+	if len(l.Changes) > 0 {
+		raw, _ := json.Marshal(l.Changes)
+		changes = string(raw)
+	}
+
+	row := []string{
+		l.ID,
+		l.CreatedAt.UTC().Format(time.RFC3339),
+		l.Username,
+		l.Action,
+		l.IdConnection,
+		l.HostServer,
+		l.Zone,
+		l.Details,
+		changes,
+		strconv.FormatInt(l.Seq, 10),
+		l.PrevHash,
+		l.Hash,
+	}
+	for i, cell := range row {
+		row[i] = csvCell(cell)
+	}
+	return row
+}
+
+// csvCell keeps spreadsheets from running a cell as a formula. Usernames,
+// zones and details are user input, so a leading =, +, -, @, tab or
+// carriage return is escaped with a quote.
+func csvCell(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
 }
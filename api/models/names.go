@@ -0,0 +1,50 @@
+package models
+
+import (
+	"strings"
+)
+
+func Fqdn(name string) string {
+	if strings.HasSuffix(name, ".") {
+		return name
+	}
+	return name + "."
+}
+
+// ZoneID returns the PowerDNS zone ID of a zone name: the name with "="
+// and "/" escaped as "=3D" and "=2F", as classless reverse zones need.
+func ZoneID(name string) string {
+	return strings.NewReplacer("=", "=3D", "/", "=2F").Replace(Fqdn(name))
+}
+
+func InZone(name, zone string) bool {
+	name = strings.ToLower(Fqdn(name))
+	zone = strings.ToLower(Fqdn(zone))
+	return name == zone || strings.HasSuffix(name, "."+zone)
+}
+
+// targetField returns the index of the field that holds a domain name in the
+// record content for the given type, or -1 if the type carries no target.
+func targetField(rrType string) int {
+	switch rrType {
+	case "CNAME", "NS", "PTR", "ALIAS", "DNAME":
+		return 0
+	case "MX", "HTTPS", "SVCB":
+		return 1
+	case "SRV":
+		return 3
+	}
+	return -1
+}
+
+func RecordTarget(rrType, content string) string {
+	idx := targetField(rrType)
+	if idx < 0 {
+		return ""
+	}
+	parts := strings.Fields(content)
+	if len(parts) <= idx {
+		return ""
+	}
+	return parts[idx]
+}
@@ -0,0 +1,201 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var notificationPlatforms = []string{"slack", "mattermost", "teams"}
+
+// ZoneChangeActions are the log actions that change zone content. Chat
+// channels without an action filter are notified of these only.
+var ZoneChangeActions = []string{
+	"create_zone", "delete_zone", "update_soa", "clone_zone", "create_reverse_zones", "delegate_reverse_zone",
+	"create_record", "delete_record", "edit_record",
+	"apply_template", "apply_zone_spec", "sync_zone", "reapply_baseline", "drift_detected",
+	"dns_update", "dyndns_update", "acme_update",
+}
+
+// maxSummaryChanges bounds the rrset changes rendered in one message.
+const maxSummaryChanges = 20
+
+// NotificationChannel posts zone change summaries to a chat incoming
+// webhook. The webhook URL embeds a token, so it is stored encrypted.
+type NotificationChannel struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	Name        string             `bson:"name" json:"name"`
+	Platform    string             `bson:"platform" json:"platform"`
+	URL         string             `bson:"url" json:"-"`
+	Connections []string           `bson:"connections" json:"connections"`
+	Zones       []string           `bson:"zones" json:"zones"`
+	Actions     []string           `bson:"actions" json:"actions"`
+	Enabled     bool               `bson:"enabled" json:"enabled"`
+	LastError   string             `bson:"lastError,omitempty" json:"lastError,omitempty"`
+	LastSentAt  *time.Time         `bson:"lastSentAt,omitempty" json:"lastSentAt,omitempty"`
+	CreatedBy   string             `bson:"createdBy" json:"createdBy"`
+	CreatedAt   time.Time          `bson:"createdAt" json:"createdAt"`
+	UpdatedAt   time.Time          `bson:"updatedAt" json:"updatedAt"`
+}
+
+type NotificationChannelRequest struct {
+	Name        string   `json:"name"`
+	Platform    string   `json:"platform"`
+	URL         string   `json:"url"`
+	Connections []string `json:"connections"`
+	Zones       []string `json:"zones"`
+	Actions     []string `json:"actions"`
+	Enabled     *bool    `json:"enabled,omitempty"`
+}
+
+// Validate checks the request. The URL may be left empty when editing to
+// keep the stored one.
+func (r *NotificationChannelRequest) Validate(requireURL bool) error {
+	r.Name = strings.TrimSpace(r.Name)
+	r.Platform = strings.ToLower(strings.TrimSpace(r.Platform))
+	r.URL = strings.TrimSpace(r.URL)
+
+	if r.Name == "" {
+		return errors.New("the field 'name' is required")
+	}
+	if !slices.Contains(notificationPlatforms, r.Platform) {
+		return fmt.Errorf("the field 'platform' must be one of %s", strings.Join(notificationPlatforms, ", "))
+	}
+
+	if r.URL != "" || requireURL {
+		u, err := url.Parse(r.URL)
+		if err != nil || u.Scheme != "https" && u.Scheme != "http" || u.Host == "" {
+			return errors.New("the field 'url' must be an http or https URL")
+		}
+	}
+
+	r.Connections = cleanList(r.Connections, false)
+	r.Zones = cleanList(r.Zones, true)
+	r.Actions = cleanList(r.Actions, false)
+
+	for i, zone := range r.Zones {
+		r.Zones[i] = Fqdn(zone)
+	}
+
+	return nil
+}
+
+func (c *NotificationChannel) Matches(l *Log) bool {
+	actions := c.Actions
+	if len(actions) == 0 {
+		actions = ZoneChangeActions
+	}
+	return matchesLog(c.Connections, c.Zones, actions, l)
+}
+
+// ChangeSummary is the rendered form of a log entry shared by the chat
+// payloads.
+type ChangeSummary struct {
+	Title string
+	Text  string
+	Diff  []string
+}
+
+// SummarizeLog renders who changed which zone and, when the entry carries
+// them, the rrset changes as diff lines.
+func SummarizeLog(l *Log, connectionName string) ChangeSummary {
+	who := l.Username
+	if who == "" {
+		who = "system"
+	}
+
+	target := strings.TrimSuffix(l.Zone, ".")
+	if target == "" {
+		target = "-"
+	}
+	if connectionName == "" {
+		connectionName = l.HostServer
+	}
+
+	summary := ChangeSummary{
+		Title: fmt.Sprintf("%s: %s on %s (%s)", who, strings.ReplaceAll(l.Action, "_", " "), target, connectionName),
+		Text:  l.Details,
+	}
+
+	for i, change := range l.Changes {
+		if i == maxSummaryChanges {
+			summary.Diff = append(summary.Diff, fmt.Sprintf("… and %d more rrset changes", len(l.Changes)-maxSummaryChanges))
+			break
+		}
+		for _, content := range change.Before {
+			summary.Diff = append(summary.Diff, fmt.Sprintf("- %s %d %s %s", change.Name, change.TTL, change.Type, content))
+		}
+		for _, content := range change.After {
+			summary.Diff = append(summary.Diff, fmt.Sprintf("+ %s %d %s %s", change.Name, change.TTL, change.Type, content))
+		}
+	}
+
+	return summary
+}
+
+// Payload builds the incoming-webhook body for the channel's platform.
+// Slack and Mattermost share the markdown text format; Teams gets an
+// Adaptive Card.
+func (c *NotificationChannel) Payload(s ChangeSummary) map[string]any {
+	diff := strings.Join(s.Diff, "\n")
+
+	switch c.Platform {
+	case "teams":
+		body := []map[string]any{
+			{"type": "TextBlock", "text": s.Title, "weight": "Bolder", "wrap": true},
+			{"type": "TextBlock", "text": s.Text, "wrap": true, "isSubtle": true},
+		}
+		if diff != "" {
+			body = append(body, map[string]any{"type": "TextBlock", "text": diff, "fontType": "Monospace", "wrap": true})
+		}
+		return map[string]any{
+			"type": "message",
+			"attachments": []map[string]any{{
+				"contentType": "application/vnd.microsoft.card.adaptive",
+				"content": map[string]any{
+					"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+					"type":    "AdaptiveCard",
+					"version": "1.4",
+					"body":    body,
+				},
+			}},
+		}
+
+	case "slack":
+		// Slack rejects section blocks over 3000 characters.
+		blocks := []map[string]any{
+			{"type": "section", "text": map[string]any{"type": "mrkdwn", "text": truncateText("*"+s.Title+"*\n"+s.Text, 3000)}},
+		}
+		if diff != "" {
+			blocks = append(blocks, map[string]any{"type": "section", "text": map[string]any{"type": "mrkdwn", "text": "```" + truncateText(diff, 2900) + "```"}})
+		}
+		return map[string]any{"text": s.Title, "blocks": blocks}
+
+	default:
+		text := "**" + s.Title + "**\n" + s.Text
+		if diff != "" {
+			text += "\n```diff\n" + diff + "\n```"
+		}
+		return map[string]any{"text": text, "username": "SanchezDNS"}
+	}
+}
+
+// truncateText shortens s to at most limit characters, ending with "…".
+// The cut is made after the last complete line when there is one.
+func truncateText(s string, limit int) string {
+	if utf8.RuneCountInString(s) <= limit {
+		return s
+	}
+
+	cut := string([]rune(s)[:limit-1])
+	if i := strings.LastIndex(cut, "\n"); i >= 0 {
+		cut = cut[:i+1]
+	}
+	return cut + "…"
+}
@@ -0,0 +1,53 @@
+package models
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestTruncateText(t *testing.T) {
+	tests := []struct {
+		name  string
+		s     string
+		limit int
+		want  string
+	}{
+		{"short", "a\nb", 10, "a\nb"},
+		{"exact", "abcde", 5, "abcde"},
+		{"cut after the last line", "line one\nline two\nline three", 20, "line one\nline two\n…"},
+		{"no newline", "abcdefghij", 5, "abcd…"},
+		{"multi-byte characters", "ééééééé", 4, "ééé…"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := truncateText(tt.s, tt.limit); got != tt.want {
+				t.Errorf("truncateText(%q, %d) = %q, want %q", tt.s, tt.limit, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSlackPayloadLimits(t *testing.T) {
+	c := &NotificationChannel{Platform: "slack"}
+	s := ChangeSummary{
+		Title: "Zone example.com. changed",
+		Text:  strings.Repeat("é", 4000),
+		Diff:  []string{"+ " + strings.Repeat("x", 5000)},
+	}
+
+	blocks := c.Payload(s)["blocks"].([]map[string]any)
+	if len(blocks) != 2 {
+		t.Fatalf("got %d blocks, want 2", len(blocks))
+	}
+	for i, block := range blocks {
+		text := block["text"].(map[string]any)["text"].(string)
+		if !utf8.ValidString(text) || utf8.RuneCountInString(text) > 3000 {
+			t.Errorf("block %d: %d characters, valid UTF-8 %v", i, utf8.RuneCountInString(text), utf8.ValidString(text))
+		}
+		if !strings.Contains(text, "…") {
+			t.Errorf("block %d is not marked as truncated", i)
+		}
+	}
+}
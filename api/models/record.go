@@ -14,6 +14,7 @@ type AddRecordRequest struct {
 	Port        *int   `json:"port,omitempty"`
 	Target      string `json:"target,omitempty"`
 	Priority    *int   `json:"priority,omitempty"`
+	CreatePtr   bool   `json:"createPtr,omitempty"`
 }
 
 type EditRecordRequest struct {
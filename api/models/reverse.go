@@ -0,0 +1,285 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+type PtrIssue struct {
+	Zone        string   `json:"zone"`
+	Name        string   `json:"name"`
+	Type        string   `json:"type"`
+	Address     string   `json:"address"`
+	ReverseZone string   `json:"reverseZone,omitempty"`
+	ReverseName string   `json:"reverseName,omitempty"`
+	Status      string   `json:"status"`
+	Ptr         []string `json:"ptr,omitempty"`
+}
+
+// classlessRe matches RFC 2317 zone labels such as "64-26" or "64/26".
+var classlessRe = regexp.MustCompile(`^(\d+)[-/](\d+)$`)
+
+func IsReverseZone(zone string) bool {
+	zone = strings.ToLower(Fqdn(zone))
+	return strings.HasSuffix(zone, ".in-addr.arpa.") || strings.HasSuffix(zone, ".ip6.arpa.")
+}
+
+// ReverseName returns the in-addr.arpa or nibble ip6.arpa name of addr.
+func ReverseName(addr netip.Addr) string {
+	if addr.Is4() {
+		b := addr.As4()
+		return fmt.Sprintf("%d.%d.%d.%d.in-addr.arpa.", b[3], b[2], b[1], b[0])
+	}
+
+	b := addr.As16()
+	var sb strings.Builder
+	for i := len(b) - 1; i >= 0; i-- {
+		fmt.Fprintf(&sb, "%x.%x.", b[i]&0x0f, b[i]>>4)
+	}
+	sb.WriteString("ip6.arpa.")
+	return sb.String()
+}
+
+// classlessRange returns the last-octet range served by an RFC 2317 zone.
+// The second number is read as a prefix length when it is between 25 and
+// 32 and as the last address of the range otherwise.
+func classlessRange(label string) (int, int, bool) {
+	m := classlessRe.FindStringSubmatch(label)
+	if m == nil {
+		return 0, 0, false
+	}
+	start, _ := strconv.Atoi(m[1])
+	second, _ := strconv.Atoi(m[2])
+
+	end := second
+	if second >= 25 && second <= 32 {
+		end = start + 1<<(32-second) - 1
+	}
+	if start > 255 || end > 255 || end < start {
+		return 0, 0, false
+	}
+	return start, end, true
+}
+
+// ReverseLocation finds the most specific zone among zones that holds the
+// PTR for addr, including RFC 2317 classless zones, and returns it with the
+// record name to use.
+func ReverseLocation(addr netip.Addr, zones []PdnsZone) (zone PdnsZone, name string, ok bool) {
+	plain := ReverseName(addr)
+	best := -1
+
+	for _, z := range zones {
+		fz := strings.ToLower(Fqdn(z.Name))
+		candidate := ""
+
+		if InZone(plain, fz) {
+			candidate = plain
+		} else if addr.Is4() {
+			label, parent, found := strings.Cut(fz, ".")
+			if !found || !InZone(plain, parent) || strings.Count(parent, ".") != 5 {
+				continue
+			}
+			start, end, valid := classlessRange(label)
+			last := int(addr.As4()[3])
+			if !valid || last < start || last > end {
+				continue
+			}
+			candidate = fmt.Sprintf("%d.%s", last, fz)
+		} else {
+			continue
+		}
+
+		if len(fz) > best {
+			best = len(fz)
+			zone, name, ok = z, candidate, true
+		}
+	}
+
+	return zone, name, ok
+}
+
+func ReverseZones(zones []PdnsZone) []PdnsZone {
+	var reverse []PdnsZone
+	for _, z := range zones {
+		if IsReverseZone(z.Name) {
+			reverse = append(reverse, z)
+		}
+	}
+	return reverse
+}
+
+// maxReverseZones and maxReversePtrs bound what a single request may
+// generate.
+const (
+	maxReverseZones = 256
+	maxReversePtrs  = 4096
+)
+
+type ReverseZoneRequest struct {
+	Prefix      string `json:"prefix"`
+	Soa         Soa    `json:"soa"`
+	PtrTemplate string `json:"ptrTemplate,omitempty"`
+	TTL         int    `json:"ttl,omitempty"`
+
+	prefix netip.Prefix
+}
+
+type ReverseZone struct {
+	Name      string  `json:"name"`
+	Prefix    string  `json:"prefix"`
+	Classless bool    `json:"classless"`
+	Status    string  `json:"status"`
+	Ptrs      int     `json:"ptrs"`
+	Message   string  `json:"message,omitempty"`
+	RRSets    []RRSet `json:"-"`
+	subnet    netip.Prefix
+}
+
+func (r *ReverseZoneRequest) Validate() error {
+	prefix, err := netip.ParsePrefix(strings.TrimSpace(r.Prefix))
+	if err != nil {
+		return fmt.Errorf("invalid prefix: %w", err)
+	}
+	r.prefix = prefix.Masked()
+	r.Prefix = r.prefix.String()
+
+	bits := r.prefix.Bits()
+	if r.prefix.Addr().Is4() && bits < 8 {
+		return errors.New("IPv4 prefixes must be /8 or longer")
+	}
+	if r.prefix.Addr().Is6() && (bits < 4 || bits > 124) {
+		return errors.New("IPv6 prefixes must be between /4 and /124")
+	}
+
+	if err := r.Soa.Validate(); err != nil {
+		return fmt.Errorf("soa: %w", err)
+	}
+
+	if r.TTL <= 0 {
+		r.TTL = 3600
+	}
+
+	r.PtrTemplate = strings.TrimSpace(r.PtrTemplate)
+	if r.PtrTemplate != "" {
+		if !strings.Contains(r.PtrTemplate, "{ip}") && !strings.Contains(r.PtrTemplate, "{ip-dashed}") {
+			return errors.New("ptrTemplate must contain {ip} or {ip-dashed}")
+		}
+		size := r.prefix.Addr().BitLen() - bits
+		if size > 12 {
+			return fmt.Errorf("ptrTemplate can only be used for prefixes of at most %d addresses", maxReversePtrs)
+		}
+		r.PtrTemplate = Fqdn(r.PtrTemplate)
+	}
+
+	return nil
+}
+
+// Zones lists the reverse zones covering the prefix. IPv4 prefixes are
+// split on octet boundaries and IPv6 ones on nibble boundaries; IPv4
+// prefixes longer than /24 get an RFC 2317 zone named "<first>-<bits>".
+func (r *ReverseZoneRequest) Zones() ([]ReverseZone, error) {
+	bits := r.prefix.Bits()
+	step := 4
+	if r.prefix.Addr().Is4() {
+		step = 8
+		if bits > 24 {
+			b := r.prefix.Addr().As4()
+			name := fmt.Sprintf("%d-%d.%d.%d.%d.in-addr.arpa.", b[3], bits, b[2], b[1], b[0])
+			return []ReverseZone{{Name: name, Prefix: r.prefix.String(), Classless: true, subnet: r.prefix}}, nil
+		}
+	}
+
+	boundary := (bits + step - 1) / step * step
+	if boundary-bits > 8 || 1<<(boundary-bits) > maxReverseZones {
+		return nil, fmt.Errorf("prefix %s would create more than %d zones", r.prefix, maxReverseZones)
+	}
+
+	var zones []ReverseZone
+	subnet := netip.PrefixFrom(r.prefix.Addr(), boundary)
+	for range 1 << (boundary - bits) {
+		plain := ReverseName(subnet.Addr())
+		labels := strings.Split(strings.TrimSuffix(plain, "."), ".")
+		hostLabels := len(labels) - 2 - boundary/step
+		name := strings.Join(labels[hostLabels:], ".") + "."
+
+		zones = append(zones, ReverseZone{Name: name, Prefix: subnet.String(), subnet: subnet})
+
+		next := nextPrefix(subnet)
+		if !next.IsValid() {
+			break
+		}
+		subnet = next
+	}
+
+	return zones, nil
+}
+
+// nextPrefix returns the prefix of the same size right after p.
+func nextPrefix(p netip.Prefix) netip.Prefix {
+	addr := p.Addr()
+	bytes := addr.AsSlice()
+	bit := p.Bits() - 1
+	for bit >= 0 {
+		mask := byte(1) << (7 - bit%8)
+		if bytes[bit/8]&mask == 0 {
+			bytes[bit/8] |= mask
+			next, _ := netip.AddrFromSlice(bytes)
+			return netip.PrefixFrom(next, p.Bits())
+		}
+		bytes[bit/8] &^= mask
+		bit--
+	}
+	return netip.Prefix{}
+}
+
+// Ptrs expands the PTR template for every address of the zone. It returns
+// nil when the request has no template.
+func (r *ReverseZoneRequest) Ptrs(zone *ReverseZone) []RRSet {
+	if r.PtrTemplate == "" {
+		return nil
+	}
+
+	located := []PdnsZone{{Name: zone.Name}}
+	var rrsets []RRSet
+	for addr := zone.subnet.Addr(); zone.subnet.Contains(addr) && r.prefix.Contains(addr); addr = addr.Next() {
+		_, name, ok := ReverseLocation(addr, located)
+		if !ok {
+			continue
+		}
+
+		dashed := strings.NewReplacer(".", "-", ":", "-").Replace(addr.String())
+		target := strings.NewReplacer("{ip}", addr.String(), "{ip-dashed}", dashed).Replace(r.PtrTemplate)
+
+		rrsets = append(rrsets, RRSet{Name: name, Type: "PTR", TTL: r.TTL, Records: []Record{{Content: target}}})
+
+		if !addr.Next().IsValid() {
+			break
+		}
+	}
+	return rrsets
+}
+
+// ClasslessParent returns the /24 zone name that delegates an RFC 2317
+// zone and the CNAMEs it needs, one per address of the range.
+func (z *ReverseZone) ClasslessParent() (string, []RRSet) {
+	_, parent, _ := strings.Cut(z.Name, ".")
+
+	var cnames []RRSet
+	for addr := z.subnet.Addr(); z.subnet.Contains(addr); addr = addr.Next() {
+		last := addr.As4()[3]
+		cnames = append(cnames, RRSet{
+			Name:    fmt.Sprintf("%d.%s", last, parent),
+			Type:    "CNAME",
+			TTL:     3600,
+			Records: []Record{{Content: fmt.Sprintf("%d.%s", last, z.Name)}},
+		})
+		if last == 255 {
+			break
+		}
+	}
+	return parent, cnames
+}
@@ -0,0 +1,48 @@
+package models
+
+import "testing"
+
+func TestClasslessRange(t *testing.T) {
+	tests := []struct {
+		label      string
+		start, end int
+		ok         bool
+	}{
+		{"0-26", 0, 63, true},
+		{"64-26", 64, 127, true},
+		{"64/26", 64, 127, true},
+		{"128-25", 128, 255, true},
+		{"200-30", 200, 203, true},
+		{"64-32", 64, 64, true},
+		{"64-100", 64, 100, true},
+		{"250-25", 0, 0, false},
+		{"10-5", 0, 0, false},
+		{"300-310", 0, 0, false},
+		{"64", 0, 0, false},
+		{"a-b", 0, 0, false},
+		{"", 0, 0, false},
+	}
+
+	for _, tt := range tests {
+		start, end, ok := classlessRange(tt.label)
+		if ok != tt.ok || start != tt.start || end != tt.end {
+			t.Errorf("classlessRange(%q) = %d, %d, %v; want %d, %d, %v", tt.label, start, end, ok, tt.start, tt.end, tt.ok)
+		}
+	}
+}
+
+func TestZoneID(t *testing.T) {
+	tests := []struct{ name, want string }{
+		{"2.0.192.in-addr.arpa.", "2.0.192.in-addr.arpa."},
+		{"example.com", "example.com."},
+		{"0/26.2.0.192.in-addr.arpa.", "0=2F26.2.0.192.in-addr.arpa."},
+		{"64-26.2.0.192.in-addr.arpa.", "64-26.2.0.192.in-addr.arpa."},
+		{"a=b.example.", "a=3Db.example."},
+	}
+
+	for _, tt := range tests {
+		if got := ZoneID(tt.name); got != tt.want {
+			t.Errorf("ZoneID(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
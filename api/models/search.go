@@ -0,0 +1,118 @@
+package models
+
+import (
+	"errors"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+type SearchResult struct {
+	Connection     string `json:"connection"`
+	ConnectionName string `json:"connectionName"`
+	Zone           string `json:"zone"`
+	Name           string `json:"name"`
+	Type           string `json:"type"`
+	Content        string `json:"content"`
+	TTL            int    `json:"ttl"`
+	Disabled       bool   `json:"disabled"`
+}
+
+type SearchQuery struct {
+	Q    string
+	Type string
+	Zone string
+
+	re *regexp.Regexp
+}
+
+func (q *SearchQuery) Validate() error {
+	q.Q = strings.TrimSpace(q.Q)
+	if q.Q == "" {
+		return errors.New("the query parameter 'q' is required")
+	}
+	if len(q.Q) > 255 {
+		return errors.New("the query parameter 'q' is too long")
+	}
+
+	q.Type = strings.ToUpper(strings.TrimSpace(q.Type))
+	if q.Zone != "" {
+		q.Zone = strings.ToLower(Fqdn(strings.TrimSpace(q.Zone)))
+	}
+
+	expr := regexp.QuoteMeta(strings.ToLower(q.Pattern()))
+	expr = strings.NewReplacer(`\*`, ".*", `\?`, ".").Replace(expr)
+	q.re = regexp.MustCompile("^" + expr + "$")
+
+	return nil
+}
+
+// Pattern returns the query in search-data form. Without wildcards the
+// query matches anywhere in a name or content.
+func (q *SearchQuery) Pattern() string {
+	if strings.ContainsAny(q.Q, "*?") {
+		return q.Q
+	}
+	return "*" + q.Q + "*"
+}
+
+func (q *SearchQuery) match(value string) bool {
+	return q.re.MatchString(strings.ToLower(value))
+}
+
+func (q *SearchQuery) MatchesZone(zone string) bool {
+	return q.Zone == "" || strings.ToLower(Fqdn(zone)) == q.Zone
+}
+
+// Filter reports whether a result passes the type and zone filters.
+func (q *SearchQuery) Filter(zone, rrType string) bool {
+	if q.Type != "" && rrType != q.Type {
+		return false
+	}
+	return q.MatchesZone(zone)
+}
+
+// Scan searches a zone the same way search-data would, for servers where
+// that endpoint is unavailable.
+func (q *SearchQuery) Scan(zone *Zone) []SearchResult {
+	var results []SearchResult
+	for _, rr := range zone.RRSets {
+		if !q.Filter(zone.Name, rr.Type) {
+			continue
+		}
+		nameMatch := q.match(rr.Name) || q.match(strings.TrimSuffix(rr.Name, "."))
+		for _, rec := range rr.Records {
+			if !nameMatch && !q.match(rec.Content) {
+				continue
+			}
+			results = append(results, SearchResult{
+				Zone:     zone.Name,
+				Name:     rr.Name,
+				Type:     rr.Type,
+				Content:  rec.Content,
+				TTL:      rr.TTL,
+				Disabled: rec.Disabled,
+			})
+		}
+	}
+	return results
+}
+
+func SortSearchResults(results []SearchResult) {
+	sort.SliceStable(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		if a.ConnectionName != b.ConnectionName {
+			return a.ConnectionName < b.ConnectionName
+		}
+		if a.Zone != b.Zone {
+			return a.Zone < b.Zone
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		return a.Content < b.Content
+	})
+}
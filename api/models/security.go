@@ -0,0 +1,130 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rafinhacuri/SanchezDNS/db"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	SecurityLogin          = "login"
+	SecurityPasswordChange = "password_change"
+	SecurityUserCreated    = "user_created"
+
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+)
+
+// SecurityEvent records an authentication or account change attempt in
+// "security_events". Email is the account the attempt was about and Actor
+// the authenticated user that made it, when there was one.
+type SecurityEvent struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	Type      string             `bson:"type" json:"type"`
+	Email     string             `bson:"email" json:"email"`
+	Actor     string             `bson:"actor,omitempty" json:"actor,omitempty"`
+	SourceIP  string             `bson:"sourceIp" json:"sourceIp"`
+	UserAgent string             `bson:"userAgent" json:"userAgent"`
+	Outcome   string             `bson:"outcome" json:"outcome"`
+	Reason    string             `bson:"reason,omitempty" json:"reason,omitempty"`
+	CreatedAt time.Time          `bson:"createdAt" json:"createdAt"`
+}
+
+func (e *SecurityEvent) Insert(ctx context.Context) error {
+	if e.CreatedAt.IsZero() {
+		e.CreatedAt = time.Now()
+	}
+
+	result, err := db.Database.Collection("security_events").InsertOne(ctx, e)
+	if err != nil {
+		return err
+	}
+	if id, ok := result.InsertedID.(primitive.ObjectID); ok {
+		e.ID = id
+	}
+
+	return nil
+}
+
+// SecurityEventQuery filters the security events listing.
+type SecurityEventQuery struct {
+	Email    string
+	Type     string
+	Outcome  string
+	SourceIP string
+	From     time.Time
+	To       time.Time
+	Page     int
+	Limit    int
+}
+
+func ParseSecurityEventQuery(values url.Values) (*SecurityEventQuery, error) {
+	q := &SecurityEventQuery{
+		Email:    strings.ToLower(strings.TrimSpace(values.Get("email"))),
+		Type:     strings.TrimSpace(values.Get("type")),
+		Outcome:  strings.TrimSpace(values.Get("outcome")),
+		SourceIP: strings.TrimSpace(values.Get("ip")),
+	}
+
+	var err error
+	if from := values.Get("from"); from != "" {
+		if q.From, err = parseLogDate(from, false); err != nil {
+			return nil, errors.New("invalid from date")
+		}
+	}
+	if to := values.Get("to"); to != "" {
+		if q.To, err = parseLogDate(to, true); err != nil {
+			return nil, errors.New("invalid to date")
+		}
+	}
+
+	if q.Outcome != "" && q.Outcome != OutcomeSuccess && q.Outcome != OutcomeFailure {
+		return nil, errors.New("outcome must be success or failure")
+	}
+
+	q.Page, _ = strconv.Atoi(values.Get("page"))
+	q.Page = max(q.Page, 1)
+	q.Limit, _ = strconv.Atoi(values.Get("limit"))
+	if q.Limit < 1 || q.Limit > 200 {
+		q.Limit = 20
+	}
+
+	return q, nil
+}
+
+func (q *SecurityEventQuery) Filter() bson.M {
+	filter := bson.M{}
+
+	if q.Email != "" {
+		filter["email"] = q.Email
+	}
+	if q.Type != "" {
+		filter["type"] = q.Type
+	}
+	if q.Outcome != "" {
+		filter["outcome"] = q.Outcome
+	}
+	if q.SourceIP != "" {
+		filter["sourceIp"] = q.SourceIP
+	}
+
+	createdAt := bson.M{}
+	if !q.From.IsZero() {
+		createdAt["$gte"] = q.From
+	}
+	if !q.To.IsZero() {
+		createdAt["$lt"] = q.To
+	}
+	if len(createdAt) > 0 {
+		filter["createdAt"] = createdAt
+	}
+
+	return filter
+}
@@ -0,0 +1,258 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ManagedPrefix marks the rrsets owned by a zone spec. It is stored as the
+// account of the rrset comment, e.g. "managed-by:infra-repo".
+const ManagedPrefix = "managed-by:"
+
+type SpecRRSet struct {
+	Name     string   `json:"name"`
+	Type     string   `json:"type"`
+	TTL      int      `json:"ttl"`
+	Records  []string `json:"records"`
+	Disabled []string `json:"disabled,omitempty"`
+	Comment  string   `json:"comment,omitempty"`
+}
+
+// ZoneSpec is the declarative description of a zone. Only the rrsets it
+// lists, plus the ones previously marked as managed by the same owner, are
+// ever touched when it is applied.
+type ZoneSpec struct {
+	Zone      string      `json:"zone"`
+	ManagedBy string      `json:"managedBy,omitempty"`
+	Adopt     bool        `json:"adopt,omitempty"`
+	Soa       *Soa        `json:"soa,omitempty"`
+	RRSets    []SpecRRSet `json:"rrsets"`
+}
+
+type ZonePlanSummary struct {
+	Create int `json:"create"`
+	Update int `json:"update"`
+	Delete int `json:"delete"`
+}
+
+type ZonePlan struct {
+	Zone       string          `json:"zone"`
+	ManagedBy  string          `json:"managedBy"`
+	Mode       string          `json:"mode"`
+	CreateZone bool            `json:"createZone"`
+	HasChanges bool            `json:"hasChanges"`
+	Summary    ZonePlanSummary `json:"summary"`
+	Changes    []RRSetChange   `json:"changes"`
+	Conflicts  []ZoneIssue     `json:"conflicts"`
+	Applied    bool            `json:"applied"`
+}
+
+func (s *ZoneSpec) Validate() error {
+	s.Zone = strings.TrimSpace(s.Zone)
+	if s.Zone == "" {
+		return errors.New("the field 'zone' is required")
+	}
+	s.Zone = Fqdn(s.Zone)
+
+	s.ManagedBy = strings.TrimSpace(s.ManagedBy)
+	if s.ManagedBy == "" {
+		s.ManagedBy = "sanchezdns"
+	}
+
+	if s.Soa != nil {
+		if err := s.Soa.Validate(); err != nil {
+			return fmt.Errorf("soa: %w", err)
+		}
+	}
+
+	seen := map[string]bool{}
+	for i := range s.RRSets {
+		rr := &s.RRSets[i]
+		rr.Type = strings.ToUpper(strings.TrimSpace(rr.Type))
+		rr.Name = strings.TrimSpace(rr.Name)
+
+		if rr.Type == "" {
+			return fmt.Errorf("rrsets[%d]: the field 'type' is required", i)
+		}
+		if rr.Type == "SOA" {
+			return fmt.Errorf("rrsets[%d]: use the 'soa' field instead of a SOA rrset", i)
+		}
+		if slices.Contains(dnssecTypes, rr.Type) {
+			return fmt.Errorf("rrsets[%d]: %s records are managed by PowerDNS", i, rr.Type)
+		}
+
+		switch {
+		case rr.Name == "" || rr.Name == "@":
+			rr.Name = s.Zone
+		case !strings.HasSuffix(rr.Name, "."):
+			rr.Name = rr.Name + "." + s.Zone
+		}
+		if !InZone(rr.Name, s.Zone) {
+			return fmt.Errorf("rrsets[%d]: name %s is outside of zone %s", i, rr.Name, s.Zone)
+		}
+
+		if rr.TTL <= 0 {
+			rr.TTL = 3600
+		}
+		if len(rr.Records)+len(rr.Disabled) == 0 {
+			return fmt.Errorf("rrsets[%d]: at least one record is required", i)
+		}
+
+		key := strings.ToLower(rr.Name) + "/" + rr.Type
+		if seen[key] {
+			return fmt.Errorf("rrsets[%d]: %s %s is defined more than once", i, rr.Name, rr.Type)
+		}
+		seen[key] = true
+	}
+
+	return nil
+}
+
+func (s *ZoneSpec) marker() string {
+	return ManagedPrefix + s.ManagedBy
+}
+
+// Managed reports whether rr was written by this spec's owner.
+func (s *ZoneSpec) Managed(rr *RRSet) bool {
+	return slices.ContainsFunc(rr.Comments, func(c Comment) bool { return c.Account == s.marker() })
+}
+
+// Desired returns the spec rrsets in PowerDNS form, each carrying the
+// managed marker comment.
+func (s *ZoneSpec) Desired() []RRSet {
+	rrsets := make([]RRSet, 0, len(s.RRSets))
+	for _, sr := range s.RRSets {
+		rr := RRSet{Name: sr.Name, Type: sr.Type, TTL: sr.TTL}
+
+		for _, content := range sr.Records {
+			rr.Records = append(rr.Records, Record{Content: specContent(sr.Type, content)})
+		}
+		for _, content := range sr.Disabled {
+			rr.Records = append(rr.Records, Record{Content: specContent(sr.Type, content), Disabled: true})
+		}
+
+		comment := sr.Comment
+		if comment == "" {
+			comment = "managed by " + s.ManagedBy
+		}
+		rr.Comments = []Comment{{Content: comment, Account: s.marker()}}
+
+		rrsets = append(rrsets, rr)
+	}
+	return rrsets
+}
+
+func specContent(rrType, content string) string {
+	if (rrType == "TXT" || rrType == "SPF") && !strings.HasPrefix(content, "\"") {
+		return fmt.Sprintf("\"%s\"", content)
+	}
+	return content
+}
+
+func commentOf(rr *RRSet) string {
+	if len(rr.Comments) == 0 {
+		return ""
+	}
+	return rr.Comments[0].Content
+}
+
+// Plan computes the changes needed to bring zone in line with the spec. A
+// nil zone means it does not exist yet. Rrsets that exist but are not
+// managed by the spec's owner are reported as conflicts unless Adopt is set.
+func (s *ZoneSpec) Plan(zone *Zone) ZonePlan {
+	plan := ZonePlan{Zone: s.Zone, ManagedBy: s.ManagedBy, Changes: []RRSetChange{}, Conflicts: []ZoneIssue{}}
+
+	desired := s.Desired()
+
+	if zone == nil {
+		plan.CreateZone = true
+		if s.Soa == nil {
+			plan.Conflicts = append(plan.Conflicts, ZoneIssue{Severity: "error", Name: s.Zone, Message: "zone does not exist and the spec has no SOA to create it with"})
+		}
+		plan.Changes = DiffRRSets(nil, desired, false)
+		plan.summarize()
+		return plan
+	}
+
+	existing := make(map[string]*RRSet, len(zone.RRSets))
+	var current []RRSet
+	for i := range zone.RRSets {
+		rr := &zone.RRSets[i]
+		existing[rr.Key()] = rr
+		if s.Managed(rr) {
+			current = append(current, *rr)
+		}
+	}
+
+	wanted := make([]RRSet, 0, len(desired))
+	for i := range desired {
+		rr := &desired[i]
+		old, ok := existing[rr.Key()]
+		if ok && !s.Managed(old) {
+			if !s.Adopt {
+				plan.Conflicts = append(plan.Conflicts, ZoneIssue{
+					Severity: "error",
+					Name:     rr.Name,
+					Type:     rr.Type,
+					Message:  "rrset exists but is not managed by " + s.ManagedBy + ", set 'adopt' to take it over",
+				})
+				continue
+			}
+			current = append(current, *old)
+		}
+		wanted = append(wanted, *rr)
+	}
+
+	plan.Changes = DiffRRSets(current, wanted, true)
+
+	// DiffRRSets ignores comments, so rrsets whose only change is the
+	// comment (or the adoption marker) are added here.
+	byKey := make(map[string]bool, len(plan.Changes))
+	for _, c := range plan.Changes {
+		byKey[strings.ToLower(Fqdn(c.Name))+"/"+c.Type] = true
+	}
+	for i := range wanted {
+		rr := &wanted[i]
+		old, ok := existing[rr.Key()]
+		if !ok || byKey[rr.Key()] {
+			continue
+		}
+		if !s.Managed(old) || commentOf(old) != commentOf(rr) {
+			plan.Changes = append(plan.Changes, RRSetChange{Action: "update", Name: rr.Name, Type: rr.Type, TTL: rr.TTL, Before: old.Contents(), After: rr.Contents(), RRSet: rr})
+		}
+	}
+
+	if s.Soa != nil {
+		for i := range zone.RRSets {
+			rr := &zone.RRSets[i]
+			if rr.Type != "SOA" || len(rr.Records) == 0 {
+				continue
+			}
+			live, serial, err := ParseSoa(rr.Records[0].Content)
+			if err != nil || live.Content(0) != s.Soa.Content(0) {
+				soa := &RRSet{Name: rr.Name, Type: "SOA", TTL: rr.TTL, Records: []Record{{Content: s.Soa.Content(serial)}}}
+				plan.Changes = append(plan.Changes, RRSetChange{Action: "update", Name: rr.Name, Type: "SOA", TTL: rr.TTL, Before: rr.Contents(), After: soa.Contents(), RRSet: soa})
+			}
+		}
+	}
+
+	plan.summarize()
+	return plan
+}
+
+func (p *ZonePlan) summarize() {
+	p.Summary = ZonePlanSummary{}
+	for _, c := range p.Changes {
+		switch c.Action {
+		case "create":
+			p.Summary.Create++
+		case "update":
+			p.Summary.Update++
+		case "delete":
+			p.Summary.Delete++
+		}
+	}
+	p.HasChanges = p.CreateZone || len(p.Changes) > 0
+}
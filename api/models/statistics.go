@@ -0,0 +1,184 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// SampledStats are the PowerDNS counters stored by the statistics
+// collector.
+var SampledStats = []string{
+	"udp-queries", "tcp-queries", "udp-answers", "tcp-answers",
+	"packetcache-hit", "packetcache-miss", "query-cache-hit", "query-cache-miss",
+	"servfail-answers", "nxdomain-answers", "noerror-answers", "uptime", "latency",
+}
+
+type StatMeta struct {
+	IdConnection string `bson:"idConnection" json:"idConnection"`
+}
+
+// StatSample is one reading of a connection's statistics, stored in the
+// "statistics" time-series collection.
+type StatSample struct {
+	Timestamp time.Time          `bson:"timestamp" json:"timestamp"`
+	Meta      StatMeta           `bson:"meta" json:"meta"`
+	Values    map[string]float64 `bson:"values" json:"values"`
+}
+
+// NewStatSample keeps the sampled counters out of a /statistics response.
+func NewStatSample(connectionID string, stats []PdnsStat, now time.Time) StatSample {
+	sample := StatSample{Timestamp: now, Meta: StatMeta{IdConnection: connectionID}, Values: map[string]float64{}}
+	for _, stat := range stats {
+		var value float64
+		switch v := stat.Value.(type) {
+		case float64:
+			value = v
+		case string:
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			value = f
+		default:
+			continue
+		}
+		for _, name := range SampledStats {
+			if stat.Name == name {
+				sample.Values[name] = value
+				break
+			}
+		}
+	}
+	return sample
+}
+
+type StatHistoryQuery struct {
+	Range      time.Duration
+	Resolution time.Duration
+}
+
+// maxStatPoints bounds the number of buckets a single query may return.
+const maxStatPoints = 1000
+
+func ParseStatHistoryQuery(rangeParam, resolutionParam string) (*StatHistoryQuery, error) {
+	q := &StatHistoryQuery{Range: 24 * time.Hour}
+
+	if rangeParam != "" {
+		d, err := time.ParseDuration(rangeParam)
+		if err != nil || d <= 0 {
+			return nil, errors.New("invalid range")
+		}
+		q.Range = d
+	}
+	if q.Range > 90*24*time.Hour {
+		return nil, errors.New("range must not exceed 2160h")
+	}
+
+	if resolutionParam != "" {
+		d, err := time.ParseDuration(resolutionParam)
+		if err != nil || d <= 0 {
+			return nil, errors.New("invalid resolution")
+		}
+		q.Resolution = d
+	} else {
+		q.Resolution = max(q.Range/288, time.Minute).Truncate(time.Minute)
+	}
+
+	if q.Resolution < time.Minute {
+		return nil, errors.New("resolution must be at least 1m")
+	}
+	if q.Range/q.Resolution > maxStatPoints {
+		return nil, fmt.Errorf("range and resolution give more than %d points", maxStatPoints)
+	}
+
+	return q, nil
+}
+
+type StatPoint struct {
+	Timestamp        time.Time `json:"timestamp"`
+	QueriesPerSec    float64   `json:"queriesPerSec"`
+	UDPQueriesPerSec float64   `json:"udpQueriesPerSec"`
+	TCPQueriesPerSec float64   `json:"tcpQueriesPerSec"`
+	ServfailPerSec   float64   `json:"servfailPerSec"`
+	ServfailRatio    *float64  `json:"servfailRatio"`
+	NxdomainPerSec   float64   `json:"nxdomainPerSec"`
+	CacheHitRatio    *float64  `json:"cacheHitRatio"`
+	Latency          *float64  `json:"latency"`
+	Samples          int       `json:"samples"`
+}
+
+type statBucket struct {
+	seconds float64
+	deltas  map[string]float64
+	latency float64
+	samples int
+}
+
+// BuildStatHistory turns samples, sorted by time, into per-second rates
+// grouped in buckets of q.Resolution starting at from. Counter deltas
+// that span a restart are dropped.
+func BuildStatHistory(samples []StatSample, from time.Time, q *StatHistoryQuery) []StatPoint {
+	from = from.Truncate(q.Resolution)
+	count := int(q.Range/q.Resolution) + 1
+	buckets := make([]statBucket, count)
+
+	for i := 1; i < len(samples); i++ {
+		prev, cur := samples[i-1], samples[i]
+
+		seconds := cur.Timestamp.Sub(prev.Timestamp).Seconds()
+		if seconds <= 0 || cur.Values["uptime"] < prev.Values["uptime"] {
+			continue
+		}
+
+		if cur.Timestamp.Before(from) {
+			continue
+		}
+		idx := int(cur.Timestamp.Sub(from) / q.Resolution)
+		if idx >= count {
+			continue
+		}
+
+		b := &buckets[idx]
+		if b.deltas == nil {
+			b.deltas = map[string]float64{}
+		}
+		b.seconds += seconds
+		b.samples++
+		b.latency += cur.Values["latency"]
+		for _, name := range SampledStats {
+			if delta := cur.Values[name] - prev.Values[name]; delta > 0 {
+				b.deltas[name] += delta
+			}
+		}
+	}
+
+	points := make([]StatPoint, 0, count)
+	for i, b := range buckets {
+		point := StatPoint{Timestamp: from.Add(time.Duration(i) * q.Resolution), Samples: b.samples}
+		if b.samples > 0 {
+			udp, tcp := b.deltas["udp-queries"], b.deltas["tcp-queries"]
+			point.UDPQueriesPerSec = udp / b.seconds
+			point.TCPQueriesPerSec = tcp / b.seconds
+			point.QueriesPerSec = (udp + tcp) / b.seconds
+			point.ServfailPerSec = b.deltas["servfail-answers"] / b.seconds
+			point.NxdomainPerSec = b.deltas["nxdomain-answers"] / b.seconds
+			point.ServfailRatio = ratio(b.deltas["servfail-answers"], udp+tcp)
+			point.CacheHitRatio = ratio(b.deltas["packetcache-hit"], b.deltas["packetcache-hit"]+b.deltas["packetcache-miss"])
+			latency := b.latency / float64(b.samples)
+			point.Latency = &latency
+		}
+		points = append(points, point)
+	}
+
+	return points
+}
+
+func ratio(part, total float64) *float64 {
+	if total <= 0 {
+		return nil
+	}
+	r := part / total
+	return &r
+}
@@ -0,0 +1,119 @@
+package models
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestBuildStatHistory(t *testing.T) {
+	t0 := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	q := &StatHistoryQuery{Range: 2 * time.Minute, Resolution: time.Minute}
+
+	sample := func(offset time.Duration, values map[string]float64) StatSample {
+		return StatSample{Timestamp: t0.Add(offset), Values: values}
+	}
+
+	type bucket struct {
+		samples       int
+		qps           float64
+		servfailRatio float64 // -1 when unset
+		cacheHitRatio float64 // -1 when unset
+		latency       float64 // -1 when unset
+	}
+	empty := bucket{0, 0, -1, -1, -1}
+
+	tests := []struct {
+		name    string
+		from    time.Time
+		samples []StatSample
+		want    []bucket
+	}{
+		{
+			name: "rates per second",
+			from: t0,
+			samples: []StatSample{
+				sample(0, map[string]float64{"uptime": 100}),
+				sample(30*time.Second, map[string]float64{
+					"uptime": 130, "udp-queries": 300, "tcp-queries": 60, "servfail-answers": 36,
+					"packetcache-hit": 90, "packetcache-miss": 10, "latency": 200,
+				}),
+			},
+			want: []bucket{{1, 12, 0.1, 0.9, 200}, empty, empty},
+		},
+		{
+			name: "samples of one bucket are summed",
+			from: t0,
+			samples: []StatSample{
+				sample(0, map[string]float64{"uptime": 100}),
+				sample(20*time.Second, map[string]float64{"uptime": 120, "udp-queries": 100, "latency": 100}),
+				sample(40*time.Second, map[string]float64{"uptime": 140, "udp-queries": 500, "latency": 300}),
+				sample(80*time.Second, map[string]float64{"uptime": 180, "udp-queries": 900, "latency": 50}),
+			},
+			want: []bucket{{2, 12.5, 0, -1, 200}, {1, 10, 0, -1, 50}, empty},
+		},
+		{
+			name: "restart drops the delta",
+			from: t0,
+			samples: []StatSample{
+				sample(0, map[string]float64{"uptime": 1000, "udp-queries": 5000}),
+				sample(30*time.Second, map[string]float64{"uptime": 10, "udp-queries": 20}),
+				sample(70*time.Second, map[string]float64{"uptime": 50, "udp-queries": 420}),
+			},
+			want: []bucket{empty, {1, 10, 0, -1, 0}, empty},
+		},
+		{
+			name: "decreasing counter is ignored",
+			from: t0,
+			samples: []StatSample{
+				sample(0, map[string]float64{"uptime": 100, "udp-queries": 500}),
+				sample(10*time.Second, map[string]float64{"uptime": 110, "udp-queries": 400}),
+			},
+			want: []bucket{{1, 0, -1, -1, 0}, empty, empty},
+		},
+		{
+			name: "samples outside the range are skipped",
+			from: t0.Add(30 * time.Second),
+			samples: []StatSample{
+				sample(-time.Minute, map[string]float64{"uptime": 40}),
+				sample(-30*time.Second, map[string]float64{"uptime": 70, "udp-queries": 300}),
+				sample(3*time.Minute, map[string]float64{"uptime": 280, "udp-queries": 600}),
+			},
+			want: []bucket{empty, empty, empty},
+		},
+		{
+			name:    "no samples",
+			from:    t0,
+			samples: nil,
+			want:    []bucket{empty, empty, empty},
+		},
+	}
+
+	optional := func(v *float64) float64 {
+		if v == nil {
+			return -1
+		}
+		return *v
+	}
+	near := func(a, b float64) bool { return math.Abs(a-b) < 1e-9 }
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			points := BuildStatHistory(tt.samples, tt.from, q)
+			if len(points) != len(tt.want) {
+				t.Fatalf("got %d points, want %d", len(points), len(tt.want))
+			}
+			for i, p := range points {
+				if want := tt.from.Truncate(q.Resolution).Add(time.Duration(i) * q.Resolution); !p.Timestamp.Equal(want) {
+					t.Errorf("point %d: timestamp %s, want %s", i, p.Timestamp, want)
+				}
+				got := bucket{p.Samples, p.QueriesPerSec, optional(p.ServfailRatio), optional(p.CacheHitRatio), optional(p.Latency)}
+				w := tt.want[i]
+				if got.samples != w.samples || !near(got.qps, w.qps) || !near(got.servfailRatio, w.servfailRatio) ||
+					!near(got.cacheHitRatio, w.cacheHitRatio) || !near(got.latency, w.latency) {
+					t.Errorf("point %d: got %+v, want %+v", i, got, w)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,217 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type SyncEndpoint struct {
+	Connection string `bson:"connection" json:"connection"`
+	Zone       string `bson:"zone" json:"zone"`
+}
+
+type SyncJob struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	Name           string             `bson:"name" json:"name"`
+	Source         SyncEndpoint       `bson:"source" json:"source"`
+	Destination    SyncEndpoint       `bson:"destination" json:"destination"`
+	IncludeTypes   []string           `bson:"includeTypes" json:"includeTypes"`
+	ExcludeTypes   []string           `bson:"excludeTypes" json:"excludeTypes"`
+	IncludeNames   []string           `bson:"includeNames" json:"includeNames"`
+	ExcludeNames   []string           `bson:"excludeNames" json:"excludeNames"`
+	ConflictPolicy string             `bson:"conflictPolicy" json:"conflictPolicy"`
+	DeleteMissing  bool               `bson:"deleteMissing" json:"deleteMissing"`
+	Interval       int                `bson:"interval" json:"interval"`
+	Enabled        bool               `bson:"enabled" json:"enabled"`
+	LastRunAt      *time.Time         `bson:"lastRunAt,omitempty" json:"lastRunAt,omitempty"`
+	LastStatus     string             `bson:"lastStatus,omitempty" json:"lastStatus,omitempty"`
+	LastError      string             `bson:"lastError,omitempty" json:"lastError,omitempty"`
+	LastChanges    int                `bson:"lastChanges" json:"lastChanges"`
+	CreatedBy      string             `bson:"createdBy" json:"createdBy"`
+	CreatedAt      time.Time          `bson:"createdAt" json:"createdAt"`
+	UpdatedAt      time.Time          `bson:"updatedAt" json:"updatedAt"`
+}
+
+type SyncJobRequest struct {
+	Name           string       `json:"name"`
+	Source         SyncEndpoint `json:"source"`
+	Destination    SyncEndpoint `json:"destination"`
+	IncludeTypes   []string     `json:"includeTypes"`
+	ExcludeTypes   []string     `json:"excludeTypes"`
+	IncludeNames   []string     `json:"includeNames"`
+	ExcludeNames   []string     `json:"excludeNames"`
+	ConflictPolicy string       `json:"conflictPolicy"`
+	DeleteMissing  bool         `json:"deleteMissing"`
+	Interval       int          `json:"interval"`
+	Enabled        bool         `json:"enabled"`
+}
+
+type SyncReport struct {
+	Job       string        `json:"job"`
+	Source    SyncEndpoint  `json:"source"`
+	Target    SyncEndpoint  `json:"destination"`
+	DryRun    bool          `json:"dryRun"`
+	Changes   []RRSetChange `json:"changes"`
+	Conflicts []RRSetChange `json:"conflicts"`
+	Applied   bool          `json:"applied"`
+	RanAt     time.Time     `json:"ranAt"`
+}
+
+var SyncConflictPolicies = []string{"source", "destination", "merge"}
+
+// syncIgnoredTypes are maintained by each server on its own and are never
+// synchronised.
+var syncIgnoredTypes = append([]string{"SOA"}, dnssecTypes...)
+
+func (r *SyncJobRequest) Validate() error {
+	r.Name = strings.TrimSpace(r.Name)
+	if r.Name == "" {
+		return errors.New("the field 'name' is required")
+	}
+	if r.Source.Connection == "" || r.Source.Zone == "" {
+		return errors.New("the fields 'source.connection' and 'source.zone' are required")
+	}
+	if r.Destination.Connection == "" {
+		return errors.New("the field 'destination.connection' is required")
+	}
+	if r.Destination.Zone == "" {
+		r.Destination.Zone = r.Source.Zone
+	}
+
+	r.Source.Zone = Fqdn(r.Source.Zone)
+	r.Destination.Zone = Fqdn(r.Destination.Zone)
+
+	if r.Source == r.Destination {
+		return errors.New("source and destination must differ")
+	}
+
+	if r.ConflictPolicy == "" {
+		r.ConflictPolicy = "source"
+	}
+	if !slices.Contains(SyncConflictPolicies, r.ConflictPolicy) {
+		return fmt.Errorf("the field 'conflictPolicy' must be one of %s", strings.Join(SyncConflictPolicies, ", "))
+	}
+
+	if r.Interval < 0 {
+		return errors.New("the field 'interval' must not be negative")
+	}
+	if r.Interval > 0 && r.Interval < 60 {
+		return errors.New("the field 'interval' must be at least 60 seconds")
+	}
+
+	for i := range r.IncludeTypes {
+		r.IncludeTypes[i] = strings.ToUpper(strings.TrimSpace(r.IncludeTypes[i]))
+	}
+	for i := range r.ExcludeTypes {
+		r.ExcludeTypes[i] = strings.ToUpper(strings.TrimSpace(r.ExcludeTypes[i]))
+	}
+	for _, pattern := range append(append([]string{}, r.IncludeNames...), r.ExcludeNames...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid name pattern %q", pattern)
+		}
+	}
+
+	return nil
+}
+
+// relativeName returns name relative to zone, "@" for the apex.
+func relativeName(name, zone string) string {
+	name = strings.ToLower(Fqdn(name))
+	zone = strings.ToLower(Fqdn(zone))
+	if name == zone {
+		return "@"
+	}
+	return strings.TrimSuffix(name, "."+zone)
+}
+
+func matchesAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(strings.ToLower(pattern), name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Matches reports whether the job covers the rrset. Names are matched
+// relative to the zone, so "@" is the apex and "*.internal" matches every
+// name below internal.
+func (j *SyncJob) Matches(rr *RRSet, zone string) bool {
+	if slices.Contains(syncIgnoredTypes, rr.Type) {
+		return false
+	}
+	if len(j.IncludeTypes) > 0 && !slices.Contains(j.IncludeTypes, rr.Type) {
+		return false
+	}
+	if slices.Contains(j.ExcludeTypes, rr.Type) {
+		return false
+	}
+
+	name := relativeName(rr.Name, zone)
+	if len(j.IncludeNames) > 0 && !matchesAny(j.IncludeNames, name) {
+		return false
+	}
+
+	return !matchesAny(j.ExcludeNames, name)
+}
+
+// Plan computes the changes that make destination match source for the
+// rrsets covered by the job. Conflicts are rrsets present on both sides
+// with different content; how they end up in the changes depends on the
+// job's conflict policy.
+func (j *SyncJob) Plan(source, destination *Zone) (changes, conflicts []RRSetChange) {
+	var current []RRSet
+	existing := map[string]*RRSet{}
+	for i := range destination.RRSets {
+		rr := &destination.RRSets[i]
+		if !j.Matches(rr, destination.Name) {
+			continue
+		}
+		current = append(current, *rr)
+		existing[rr.Key()] = rr
+	}
+
+	var desired []RRSet
+	conflicts = []RRSetChange{}
+
+	for _, rr := range source.RRSets {
+		if !j.Matches(&rr, source.Name) {
+			continue
+		}
+
+		name, _ := RenameName(rr.Name, source.Name, destination.Name)
+		want := RRSet{Name: name, Type: rr.Type, TTL: rr.TTL, Records: rr.Records, Comments: rr.Comments}
+
+		old, ok := existing[want.Key()]
+		if !ok || old.Equal(&want) {
+			desired = append(desired, want)
+			continue
+		}
+
+		conflicts = append(conflicts, RRSetChange{Action: j.ConflictPolicy, Name: want.Name, Type: want.Type, TTL: want.TTL, Before: old.Contents(), After: want.Contents()})
+
+		switch j.ConflictPolicy {
+		case "destination":
+			desired = append(desired, *old)
+		case "merge":
+			merged := *old
+			merged.Records = append([]Record{}, old.Records...)
+			for _, rec := range want.Records {
+				if !slices.ContainsFunc(merged.Records, func(r Record) bool { return r.Content == rec.Content }) {
+					merged.Records = append(merged.Records, rec)
+				}
+			}
+			desired = append(desired, merged)
+		default:
+			desired = append(desired, want)
+		}
+	}
+
+	return DiffRRSets(current, desired, j.DeleteMissing), conflicts
+}
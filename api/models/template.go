@@ -0,0 +1,204 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type TemplateRRSet struct {
+	Name    string   `bson:"name" json:"name"`
+	Type    string   `bson:"type" json:"type"`
+	TTL     int      `bson:"ttl" json:"ttl"`
+	Records []string `bson:"records" json:"records"`
+	Comment string   `bson:"comment,omitempty" json:"comment,omitempty"`
+}
+
+type ZoneTemplate struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	Name        string             `bson:"name" json:"name"`
+	Description string             `bson:"description" json:"description"`
+	Soa         *Soa               `bson:"soa,omitempty" json:"soa,omitempty"`
+	RRSets      []TemplateRRSet    `bson:"rrsets" json:"rrsets"`
+	Variables   map[string]string  `bson:"variables,omitempty" json:"variables,omitempty"`
+	CreatedBy   string             `bson:"createdBy" json:"createdBy"`
+	CreatedAt   time.Time          `bson:"createdAt" json:"createdAt"`
+	UpdatedAt   time.Time          `bson:"updatedAt" json:"updatedAt"`
+}
+
+type ZoneTemplateRequest struct {
+	Name        string            `json:"name"`
+	Description string            `json:"description"`
+	Soa         *Soa              `json:"soa,omitempty"`
+	RRSets      []TemplateRRSet   `json:"rrsets"`
+	Variables   map[string]string `json:"variables,omitempty"`
+}
+
+type ApplyTemplateRequest struct {
+	Variables map[string]string `json:"variables,omitempty"`
+	Strategy  string            `json:"strategy,omitempty"`
+}
+
+var templateVarRe = regexp.MustCompile(`\{\{\s*([a-zA-Z0-9_-]+)\s*\}\}`)
+
+func (r *ZoneTemplateRequest) Validate() error {
+	r.Name = strings.TrimSpace(r.Name)
+	if r.Name == "" {
+		return errors.New("the field 'name' is required")
+	}
+
+	if r.Soa != nil {
+		if err := r.Soa.Validate(); err != nil {
+			return fmt.Errorf("soa: %w", err)
+		}
+	}
+
+	for i := range r.RRSets {
+		rr := &r.RRSets[i]
+		rr.Type = strings.ToUpper(strings.TrimSpace(rr.Type))
+		rr.Name = strings.TrimSpace(rr.Name)
+
+		if rr.Type == "" {
+			return fmt.Errorf("rrsets[%d]: the field 'type' is required", i)
+		}
+		if rr.Type == "SOA" {
+			return fmt.Errorf("rrsets[%d]: use the 'soa' field instead of a SOA rrset", i)
+		}
+		if rr.TTL <= 0 {
+			rr.TTL = 3600
+		}
+		if len(rr.Records) == 0 {
+			return fmt.Errorf("rrsets[%d]: at least one record is required", i)
+		}
+	}
+
+	return nil
+}
+
+func (a *ApplyTemplateRequest) Validate() error {
+	if a.Strategy == "" {
+		a.Strategy = "merge"
+	}
+	if a.Strategy != "merge" && a.Strategy != "replace" {
+		return errors.New("the field 'strategy' must be 'merge' or 'replace'")
+	}
+	return nil
+}
+
+func renderTemplate(value string, vars map[string]string) (string, error) {
+	var missing []string
+	out := templateVarRe.ReplaceAllStringFunc(value, func(match string) string {
+		key := templateVarRe.FindStringSubmatch(match)[1]
+		if v, ok := vars[key]; ok {
+			return v
+		}
+		missing = append(missing, key)
+		return match
+	})
+
+	if len(missing) > 0 {
+		return "", fmt.Errorf("undefined template variables: %s", strings.Join(missing, ", "))
+	}
+
+	return out, nil
+}
+
+func (t *ZoneTemplate) variables(domain string, overrides map[string]string) map[string]string {
+	vars := map[string]string{}
+	for k, v := range t.Variables {
+		vars[k] = v
+	}
+	for k, v := range overrides {
+		vars[k] = v
+	}
+
+	domain = strings.TrimSuffix(domain, ".")
+	vars["domain"] = domain
+	vars["zone"] = domain + "."
+
+	return vars
+}
+
+// RenderSoa returns the template's default SOA with variables expanded, or
+// nil if the template does not define one.
+func (t *ZoneTemplate) RenderSoa(domain string, overrides map[string]string) (*Soa, error) {
+	if t.Soa == nil {
+		return nil, nil
+	}
+
+	vars := t.variables(domain, overrides)
+	soa := *t.Soa
+
+	var err error
+	if soa.StartOfAuthority, err = renderTemplate(soa.StartOfAuthority, vars); err != nil {
+		return nil, err
+	}
+	if soa.Email, err = renderTemplate(soa.Email, vars); err != nil {
+		return nil, err
+	}
+
+	return &soa, nil
+}
+
+// Render expands the template rrsets for domain. Names may be "@" for the
+// apex, relative to the zone, or fully qualified; records sharing a name and
+// type are merged into a single rrset.
+func (t *ZoneTemplate) Render(domain string, overrides map[string]string, account string) ([]RRSet, error) {
+	vars := t.variables(domain, overrides)
+	apex := vars["zone"]
+
+	var rrsets []RRSet
+	index := map[string]int{}
+
+	for _, tr := range t.RRSets {
+		name, err := renderTemplate(tr.Name, vars)
+		if err != nil {
+			return nil, fmt.Errorf("%s %s: %w", tr.Name, tr.Type, err)
+		}
+
+		switch {
+		case name == "" || name == "@":
+			name = apex
+		case !strings.HasSuffix(name, "."):
+			name = name + "." + apex
+		}
+
+		if !InZone(name, apex) {
+			return nil, fmt.Errorf("%s %s: name %s is outside of zone %s", tr.Name, tr.Type, name, apex)
+		}
+
+		rr := RRSet{Name: name, Type: tr.Type, TTL: tr.TTL}
+		key := rr.Key()
+		if i, ok := index[key]; ok {
+			rr = rrsets[i]
+		}
+
+		for _, content := range tr.Records {
+			value, err := renderTemplate(content, vars)
+			if err != nil {
+				return nil, fmt.Errorf("%s %s: %w", tr.Name, tr.Type, err)
+			}
+			if (tr.Type == "TXT" || tr.Type == "SPF") && !strings.HasPrefix(value, "\"") {
+				value = fmt.Sprintf("\"%s\"", value)
+			}
+			rr.Records = append(rr.Records, Record{Content: value})
+		}
+
+		if tr.Comment != "" {
+			rr.Comments = []Comment{{Content: tr.Comment, Account: account}}
+		}
+
+		if i, ok := index[key]; ok {
+			rrsets[i] = rr
+			continue
+		}
+		index[key] = len(rrsets)
+		rrsets = append(rrsets, rr)
+	}
+
+	return rrsets, nil
+}
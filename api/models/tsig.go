@@ -0,0 +1,118 @@
+package models
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+type PdnsTsigKey struct {
+	ID        string `json:"id,omitempty"`
+	Name      string `json:"name,omitempty"`
+	Algorithm string `json:"algorithm,omitempty"`
+	Key       string `json:"key,omitempty"`
+	Type      string `json:"type,omitempty"`
+}
+
+type PdnsMetadata struct {
+	Kind     string   `json:"kind"`
+	Metadata []string `json:"metadata"`
+}
+
+var TsigAlgorithms = []string{"hmac-md5", "hmac-sha1", "hmac-sha224", "hmac-sha256", "hmac-sha384", "hmac-sha512"}
+
+var tsigKeySizes = map[string]int{
+	"hmac-sha256": 32,
+	"hmac-sha512": 64,
+}
+
+var tsigNameRe = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9._-]*[a-zA-Z0-9])?\.?$`)
+
+type TsigKeyRequest struct {
+	Name      string `json:"name"`
+	Algorithm string `json:"algorithm"`
+	Key       string `json:"key,omitempty"`
+}
+
+func (r *TsigKeyRequest) Validate() error {
+	r.Name = strings.TrimSpace(r.Name)
+	r.Algorithm = strings.ToLower(strings.TrimSpace(r.Algorithm))
+	r.Key = strings.TrimSpace(r.Key)
+
+	if r.Name == "" {
+		return errors.New("the field 'name' is required")
+	}
+	if !tsigNameRe.MatchString(r.Name) {
+		return errors.New("the field 'name' must be a valid key name")
+	}
+	if r.Algorithm == "" {
+		r.Algorithm = "hmac-sha256"
+	}
+
+	if r.Key == "" {
+		if _, ok := tsigKeySizes[r.Algorithm]; !ok {
+			return errors.New("generated keys must use 'hmac-sha256' or 'hmac-sha512'")
+		}
+		return nil
+	}
+
+	if !slices.Contains(TsigAlgorithms, r.Algorithm) {
+		return fmt.Errorf("the field 'algorithm' must be one of %s", strings.Join(TsigAlgorithms, ", "))
+	}
+	if _, err := base64.StdEncoding.DecodeString(r.Key); err != nil {
+		return errors.New("the field 'key' must be base64 encoded")
+	}
+
+	return nil
+}
+
+func GenerateTsigSecret(algorithm string) (string, error) {
+	size, ok := tsigKeySizes[algorithm]
+	if !ok {
+		return "", fmt.Errorf("unsupported algorithm %s", algorithm)
+	}
+
+	secret := make([]byte, size)
+	if _, err := rand.Read(secret); err != nil {
+		return "", fmt.Errorf("failed to generate secret: %w", err)
+	}
+
+	return base64.StdEncoding.EncodeToString(secret), nil
+}
+
+type TsigRenameRequest struct {
+	Name string `json:"name"`
+}
+
+func (r *TsigRenameRequest) Validate() error {
+	r.Name = strings.TrimSpace(r.Name)
+	if r.Name == "" {
+		return errors.New("the field 'name' is required")
+	}
+	if !tsigNameRe.MatchString(r.Name) {
+		return errors.New("the field 'name' must be a valid key name")
+	}
+	return nil
+}
+
+type ZoneTsigRequest struct {
+	AllowAxfr  []string `json:"allowAxfr"`
+	MasterTsig []string `json:"masterTsig"`
+}
+
+func (r *ZoneTsigRequest) Validate() error {
+	if len(r.MasterTsig) > 1 {
+		return errors.New("the field 'masterTsig' accepts a single key")
+	}
+	if r.AllowAxfr == nil {
+		r.AllowAxfr = []string{}
+	}
+	if r.MasterTsig == nil {
+		r.MasterTsig = []string{}
+	}
+	return nil
+}
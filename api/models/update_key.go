@@ -0,0 +1,95 @@
+package models
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// UpdateKey is a TSIG key accepted by the RFC 2136 update listener. Updates
+// signed with it act as Username and may only touch the listed zones of
+// one connection.
+type UpdateKey struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	Name         string             `bson:"name" json:"name"`
+	Algorithm    string             `bson:"algorithm" json:"algorithm"`
+	Secret       string             `bson:"secret" json:"-"`
+	IdConnection string             `bson:"idConnection" json:"idConnection"`
+	Zones        []string           `bson:"zones" json:"zones"`
+	Username     string             `bson:"username" json:"username"`
+	CreatedBy    string             `bson:"createdBy" json:"createdBy"`
+	CreatedAt    time.Time          `bson:"createdAt" json:"createdAt"`
+	LastUsedAt   *time.Time         `bson:"lastUsedAt,omitempty" json:"lastUsedAt,omitempty"`
+}
+
+type UpdateKeyRequest struct {
+	Name       string   `json:"name"`
+	Algorithm  string   `json:"algorithm"`
+	Secret     string   `json:"secret,omitempty"`
+	Connection string   `json:"connection"`
+	Zones      []string `json:"zones"`
+	Username   string   `json:"username"`
+}
+
+// updateKeyAlgorithms are the TSIG algorithms the update listener can
+// verify; hmac-md5 is not supported by the DNS library.
+var updateKeyAlgorithms = []string{"hmac-sha1", "hmac-sha224", "hmac-sha256", "hmac-sha384", "hmac-sha512"}
+
+func (r *UpdateKeyRequest) Validate() error {
+	r.Name = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(r.Name), "."))
+	r.Algorithm = strings.ToLower(strings.TrimSpace(r.Algorithm))
+	r.Secret = strings.TrimSpace(r.Secret)
+	r.Username = strings.TrimSpace(r.Username)
+
+	if r.Name == "" {
+		return errors.New("the field 'name' is required")
+	}
+	if !tsigNameRe.MatchString(r.Name) {
+		return errors.New("the field 'name' must be a valid key name")
+	}
+	r.Name = Fqdn(r.Name)
+
+	if r.Algorithm == "" {
+		r.Algorithm = "hmac-sha256"
+	}
+	if !slices.Contains(updateKeyAlgorithms, r.Algorithm) {
+		return fmt.Errorf("the field 'algorithm' must be one of %s", strings.Join(updateKeyAlgorithms, ", "))
+	}
+
+	if r.Secret == "" {
+		if _, ok := tsigKeySizes[r.Algorithm]; !ok {
+			return errors.New("generated keys must use 'hmac-sha256' or 'hmac-sha512'")
+		}
+	} else if _, err := base64.StdEncoding.DecodeString(r.Secret); err != nil {
+		return errors.New("the field 'secret' must be base64 encoded")
+	}
+
+	if strings.TrimSpace(r.Connection) == "" {
+		return errors.New("the field 'connection' is required")
+	}
+	if r.Username == "" {
+		return errors.New("the field 'username' is required")
+	}
+
+	if len(r.Zones) == 0 {
+		return errors.New("at least one zone is required")
+	}
+	for i, zone := range r.Zones {
+		zone = strings.TrimSpace(zone)
+		if zone == "" {
+			return fmt.Errorf("zones[%d] is empty", i)
+		}
+		r.Zones[i] = strings.ToLower(Fqdn(zone))
+	}
+
+	return nil
+}
+
+func (k *UpdateKey) AllowsZone(zone string) bool {
+	return slices.Contains(k.Zones, strings.ToLower(Fqdn(zone)))
+}
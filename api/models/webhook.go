@@ -0,0 +1,162 @@
+package models
+
+import (
+	"errors"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Webhook is a subscription to the events written to the logs collection.
+// Empty filters match everything.
+type Webhook struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	Name        string             `bson:"name" json:"name"`
+	URL         string             `bson:"url" json:"url"`
+	Secret      string             `bson:"secret" json:"-"`
+	Connections []string           `bson:"connections" json:"connections"`
+	Zones       []string           `bson:"zones" json:"zones"`
+	Actions     []string           `bson:"actions" json:"actions"`
+	Enabled     bool               `bson:"enabled" json:"enabled"`
+	CreatedBy   string             `bson:"createdBy" json:"createdBy"`
+	CreatedAt   time.Time          `bson:"createdAt" json:"createdAt"`
+	UpdatedAt   time.Time          `bson:"updatedAt" json:"updatedAt"`
+}
+
+type WebhookRequest struct {
+	Name        string   `json:"name"`
+	URL         string   `json:"url"`
+	Secret      string   `json:"secret,omitempty"`
+	Connections []string `json:"connections"`
+	Zones       []string `json:"zones"`
+	Actions     []string `json:"actions"`
+	Enabled     *bool    `json:"enabled,omitempty"`
+}
+
+func (r *WebhookRequest) Validate() error {
+	r.Name = strings.TrimSpace(r.Name)
+	r.URL = strings.TrimSpace(r.URL)
+	r.Secret = strings.TrimSpace(r.Secret)
+
+	if r.Name == "" {
+		return errors.New("the field 'name' is required")
+	}
+
+	u, err := url.Parse(r.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("the field 'url' must be an http or https URL")
+	}
+
+	if r.Secret != "" && len(r.Secret) < 16 {
+		return errors.New("the field 'secret' must be at least 16 characters long")
+	}
+
+	r.Connections = cleanList(r.Connections, false)
+	r.Zones = cleanList(r.Zones, true)
+	r.Actions = cleanList(r.Actions, false)
+
+	for i, zone := range r.Zones {
+		r.Zones[i] = Fqdn(zone)
+	}
+
+	return nil
+}
+
+func cleanList(values []string, lower bool) []string {
+	out := make([]string, 0, len(values))
+	for _, v := range values {
+		v = strings.TrimSpace(v)
+		if lower {
+			v = strings.ToLower(v)
+		}
+		if v != "" && !slices.Contains(out, v) {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// Matches reports whether a log entry passes the webhook filters.
+func (w *Webhook) Matches(l *Log) bool {
+	return matchesLog(w.Connections, w.Zones, w.Actions, l)
+}
+
+// matchesLog applies connection, zone and action filters to a log entry.
+// An empty filter matches everything.
+func matchesLog(connections, zones, actions []string, l *Log) bool {
+	if len(connections) > 0 && !slices.Contains(connections, l.IdConnection) {
+		return false
+	}
+	if len(zones) > 0 && (l.Zone == "" || !slices.Contains(zones, strings.ToLower(Fqdn(l.Zone)))) {
+		return false
+	}
+	if len(actions) > 0 && !slices.Contains(actions, l.Action) {
+		return false
+	}
+	return true
+}
+
+// WebhookEvent is the JSON body delivered to subscribers.
+type WebhookEvent struct {
+	ID         string        `bson:"id" json:"id"`
+	Action     string        `bson:"action" json:"action"`
+	Connection string        `bson:"connection" json:"connection"`
+	HostServer string        `bson:"hostServer" json:"hostServer"`
+	Zone       string        `bson:"zone" json:"zone"`
+	Username   string        `bson:"username" json:"username"`
+	Details    string        `bson:"details" json:"details"`
+	Changes    []RRSetChange `bson:"changes,omitempty" json:"changes,omitempty"`
+	CreatedAt  time.Time     `bson:"createdAt" json:"createdAt"`
+}
+
+func NewWebhookEvent(id string, l *Log) WebhookEvent {
+	return WebhookEvent{
+		ID:         id,
+		Action:     l.Action,
+		Connection: l.IdConnection,
+		HostServer: l.HostServer,
+		Zone:       l.Zone,
+		Username:   l.Username,
+		Details:    l.Details,
+		Changes:    l.Changes,
+		CreatedAt:  l.CreatedAt,
+	}
+}
+
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed"
+
+	// WebhookMaxAttempts is the number of tries before a delivery is
+	// given up.
+	WebhookMaxAttempts = 8
+)
+
+// WebhookDelivery is one event sent to one webhook, kept in
+// "webhook_deliveries" together with the outcome of its last attempt.
+type WebhookDelivery struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	IdWebhook      string             `bson:"idWebhook" json:"idWebhook"`
+	Event          WebhookEvent       `bson:"event" json:"event"`
+	Status         string             `bson:"status" json:"status"`
+	Attempts       int                `bson:"attempts" json:"attempts"`
+	LastStatusCode int                `bson:"lastStatusCode,omitempty" json:"lastStatusCode,omitempty"`
+	LastError      string             `bson:"lastError,omitempty" json:"lastError,omitempty"`
+	NextAttemptAt  *time.Time         `bson:"nextAttemptAt,omitempty" json:"nextAttemptAt,omitempty"`
+	DeliveredAt    *time.Time         `bson:"deliveredAt,omitempty" json:"deliveredAt,omitempty"`
+	CreatedAt      time.Time          `bson:"createdAt" json:"createdAt"`
+}
+
+// WebhookBackoff returns the delay before the next attempt, doubling from
+// 30 seconds up to one hour.
+func WebhookBackoff(attempts int) time.Duration {
+	d := 30 * time.Second << max(attempts-1, 0)
+	if d > time.Hour || d <= 0 {
+		return time.Hour
+	}
+	return d
+}
@@ -6,12 +6,12 @@ import (
 )
 
 type Soa struct {
-	StartOfAuthority string `json:"startOfAuthority"`
-	Email            string `json:"email"`
-	Refresh          int    `json:"refresh"`
-	Retry            int    `json:"retry"`
-	Expire           int    `json:"expire"`
-	NegativeCacheTtl int    `json:"negativeCacheTtl"`
+	StartOfAuthority string `bson:"startOfAuthority" json:"startOfAuthority"`
+	Email            string `bson:"email" json:"email"`
+	Refresh          int    `bson:"refresh" json:"refresh"`
+	Retry            int    `bson:"retry" json:"retry"`
+	Expire           int    `bson:"expire" json:"expire"`
+	NegativeCacheTtl int    `bson:"negativeCacheTtl" json:"negativeCacheTtl"`
 }
 
 func (s *Soa) Validate() error {
@@ -36,9 +36,17 @@ func (s *Soa) Validate() error {
 	return nil
 }
 
+func (s *Soa) Content(serial int64) string {
+	mname := strings.TrimSuffix(s.StartOfAuthority, ".")
+	rname := strings.TrimSuffix(s.Email, ".")
+	return fmt.Sprintf("%s. %s. %d %d %d %d %d", mname, rname, serial, s.Refresh, s.Retry, s.Expire, s.NegativeCacheTtl)
+}
+
 type CreateZoneRequest struct {
-	Domain string `json:"domain" binding:"required"`
-	Soa    Soa    `json:"soa" binding:"required"`
+	Domain    string            `json:"domain" binding:"required"`
+	Soa       Soa               `json:"soa" binding:"required"`
+	Template  string            `json:"template,omitempty"`
+	Variables map[string]string `json:"variables,omitempty"`
 }
 
 func (req *CreateZoneRequest) Validate() error {
@@ -49,6 +57,10 @@ func (req *CreateZoneRequest) Validate() error {
 		req.Domain = req.Domain + "."
 	}
 
+	if req.Template != "" && req.Soa == (Soa{}) {
+		return nil
+	}
+
 	if err := req.Soa.Validate(); err != nil {
 		return fmt.Errorf("soa: %w", err)
 	}
@@ -57,28 +69,28 @@ func (req *CreateZoneRequest) Validate() error {
 }
 
 type Record struct {
-	Content  string `json:"content"`
-	Disabled bool   `json:"disabled"`
+	Content  string `bson:"content" json:"content"`
+	Disabled bool   `bson:"disabled" json:"disabled"`
 }
 
 type Comment struct {
-	Content    string `json:"content"`
-	Account    string `json:"account"`
-	ModifiedAt int64  `json:"modified_at,omitempty"`
+	Content    string `bson:"content" json:"content"`
+	Account    string `bson:"account" json:"account"`
+	ModifiedAt int64  `bson:"modifiedAt,omitempty" json:"modified_at,omitempty"`
 }
 
-type rrsetRecord struct {
-	Name     string    `json:"name"`
-	Type     string    `json:"type"`
-	TTL      int       `json:"ttl"`
-	Comments []Comment `json:"comments"`
-	Records  []Record  `json:"records"`
+type RRSet struct {
+	Name     string    `bson:"name" json:"name"`
+	Type     string    `bson:"type" json:"type"`
+	TTL      int       `bson:"ttl" json:"ttl"`
+	Comments []Comment `bson:"comments,omitempty" json:"comments"`
+	Records  []Record  `bson:"records" json:"records"`
 }
 type Zone struct {
-	Name         string        `json:"name"`
-	RRSets       []rrsetRecord `json:"rrsets"`
-	Serial       int64         `json:"serial"`
-	EditedSerial int64         `json:"edited_serial"`
+	Name         string  `json:"name"`
+	RRSets       []RRSet `json:"rrsets"`
+	Serial       int64   `json:"serial"`
+	EditedSerial int64   `json:"edited_serial"`
 }
 
 type Simplified struct {
@@ -0,0 +1,73 @@
+package pdns
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/rafinhacuri/SanchezDNS/models"
+	"github.com/rafinhacuri/SanchezDNS/utils"
+)
+
+type Client struct {
+	http     *resty.Client
+	ServerId string
+	Host     string
+}
+
+type Error struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("PowerDNS error: status=%d, body=%s", e.StatusCode, e.Body)
+}
+
+func New(connection *models.Connection) (*Client, error) {
+	plainKey, err := utils.Decrypt(connection.ApiKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt api key: %w", err)
+	}
+
+	serverId := connection.ServerId
+	if serverId == "" {
+		serverId = "localhost"
+	}
+
+	base := utils.NormalizeBase(connection.Host)
+
+	httpc := resty.New().SetBaseURL(base).SetHeader("X-API-Key", plainKey).SetHeader("Accept", "application/json").SetTimeout(6 * time.Second).SetRetryCount(2)
+	Instrument(httpc, connection.ID.Hex())
+
+	return &Client{http: httpc, ServerId: serverId, Host: connection.Host}, nil
+}
+
+func (c *Client) R(ctx context.Context) *resty.Request {
+	return c.http.R().SetContext(ctx)
+}
+
+func (c *Client) Path(format string, args ...any) string {
+	return fmt.Sprintf("/api/v1/servers/%s", c.ServerId) + fmt.Sprintf(format, args...)
+}
+
+func (c *Client) Do(ctx context.Context, method, path string, body, result any) error {
+	req := c.R(ctx)
+	if body != nil {
+		req.SetBody(body)
+	}
+	if result != nil {
+		req.SetResult(result)
+	}
+
+	resp, err := req.Execute(method, path)
+	if err != nil {
+		return err
+	}
+	if resp.IsError() {
+		return &Error{StatusCode: resp.StatusCode(), Body: resp.String()}
+	}
+
+	return nil
+}
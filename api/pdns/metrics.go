@@ -0,0 +1,26 @@
+package pdns
+
+import (
+	"errors"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/rafinhacuri/SanchezDNS/metrics"
+)
+
+// Instrument records the latency and outcome of every call made with httpc
+// under the given connection label, the connection ID.
+func Instrument(httpc *resty.Client, connection string) *resty.Client {
+	httpc.OnSuccess(func(_ *resty.Client, resp *resty.Response) {
+		metrics.ObservePdns(connection, resp.Request.Method, resp.StatusCode(), time.Since(resp.Request.Time))
+	})
+	httpc.OnError(func(req *resty.Request, err error) {
+		status := 0
+		var respErr *resty.ResponseError
+		if errors.As(err, &respErr) && respErr.Response != nil {
+			status = respErr.Response.StatusCode()
+		}
+		metrics.ObservePdns(connection, req.Method, status, time.Since(req.Time))
+	})
+	return httpc
+}
@@ -0,0 +1,55 @@
+package pdns
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+
+	"github.com/rafinhacuri/SanchezDNS/models"
+)
+
+// Search queries the search-data endpoint. The pattern accepts the
+// PowerDNS wildcards * and ?.
+func (c *Client) Search(ctx context.Context, pattern, objectType string, max int) ([]models.PdnsSearchResult, error) {
+	params := url.Values{}
+	params.Set("q", pattern)
+	params.Set("max", strconv.Itoa(max))
+	if objectType != "" {
+		params.Set("object_type", objectType)
+	}
+
+	var results []models.PdnsSearchResult
+	if err := c.Do(ctx, http.MethodGet, c.Path("/search-data?%s", params.Encode()), nil, &results); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// FetchZones loads the given zones with at most parallel requests in
+// flight. Zones that fail to load are left nil and their error is returned
+// at the same index.
+func (c *Client) FetchZones(ctx context.Context, ids []string, parallel int) ([]*models.Zone, []error) {
+	if parallel < 1 {
+		parallel = 1
+	}
+
+	zones := make([]*models.Zone, len(ids))
+	errs := make([]error, len(ids))
+	sem := make(chan struct{}, parallel)
+
+	var wg sync.WaitGroup
+	for i, id := range ids {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			zones[i], errs[i] = c.Zone(ctx, id)
+		}()
+	}
+	wg.Wait()
+
+	return zones, errs
+}
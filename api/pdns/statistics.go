@@ -0,0 +1,16 @@
+package pdns
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/rafinhacuri/SanchezDNS/models"
+)
+
+func (c *Client) Statistics(ctx context.Context) ([]models.PdnsStat, error) {
+	var stats []models.PdnsStat
+	if err := c.Do(ctx, http.MethodGet, c.Path("/statistics"), nil, &stats); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
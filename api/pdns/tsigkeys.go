@@ -0,0 +1,61 @@
+package pdns
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/rafinhacuri/SanchezDNS/models"
+)
+
+func (c *Client) TsigKeys(ctx context.Context) ([]models.PdnsTsigKey, error) {
+	var keys []models.PdnsTsigKey
+	if err := c.Do(ctx, http.MethodGet, c.Path("/tsigkeys"), nil, &keys); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+func (c *Client) TsigKey(ctx context.Context, id string) (*models.PdnsTsigKey, error) {
+	var key models.PdnsTsigKey
+	if err := c.Do(ctx, http.MethodGet, c.Path("/tsigkeys/%s", id), nil, &key); err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (c *Client) CreateTsigKey(ctx context.Context, key models.PdnsTsigKey) (*models.PdnsTsigKey, error) {
+	var created models.PdnsTsigKey
+	if err := c.Do(ctx, http.MethodPost, c.Path("/tsigkeys"), key, &created); err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+func (c *Client) UpdateTsigKey(ctx context.Context, id string, key models.PdnsTsigKey) (*models.PdnsTsigKey, error) {
+	var updated models.PdnsTsigKey
+	if err := c.Do(ctx, http.MethodPut, c.Path("/tsigkeys/%s", id), key, &updated); err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+func (c *Client) DeleteTsigKey(ctx context.Context, id string) error {
+	return c.Do(ctx, http.MethodDelete, c.Path("/tsigkeys/%s", id), nil, nil)
+}
+
+func (c *Client) Metadata(ctx context.Context, zone, kind string) ([]string, error) {
+	var meta models.PdnsMetadata
+	if err := c.Do(ctx, http.MethodGet, c.Path("/zones/%s/metadata/%s", zone, kind), nil, &meta); err != nil {
+		return nil, err
+	}
+	return meta.Metadata, nil
+}
+
+func (c *Client) SetMetadata(ctx context.Context, zone, kind string, values []string) error {
+	if len(values) == 0 {
+		return c.Do(ctx, http.MethodDelete, c.Path("/zones/%s/metadata/%s", zone, kind), nil, nil)
+	}
+
+	meta := models.PdnsMetadata{Kind: kind, Metadata: values}
+	return c.Do(ctx, http.MethodPut, c.Path("/zones/%s/metadata/%s", zone, kind), meta, nil)
+}
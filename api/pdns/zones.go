@@ -0,0 +1,40 @@
+package pdns
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/rafinhacuri/SanchezDNS/models"
+)
+
+func (c *Client) Zones(ctx context.Context) ([]models.PdnsZone, error) {
+	var zones []models.PdnsZone
+	if err := c.Do(ctx, http.MethodGet, c.Path("/zones"), nil, &zones); err != nil {
+		return nil, err
+	}
+	return zones, nil
+}
+
+func (c *Client) Zone(ctx context.Context, zone string) (*models.Zone, error) {
+	var z models.Zone
+	if err := c.Do(ctx, http.MethodGet, c.Path("/zones/%s", zone), nil, &z); err != nil {
+		return nil, err
+	}
+	return &z, nil
+}
+
+func (c *Client) PatchZone(ctx context.Context, zone string, rrsets []map[string]any) error {
+	return c.Do(ctx, http.MethodPatch, c.Path("/zones/%s", zone), map[string]any{"rrsets": rrsets}, nil)
+}
+
+// ZoneOperation runs one of the PUT /zones/{id}/{operation} maintenance
+// calls (notify, axfr-retrieve, rectify) and returns PowerDNS' result text.
+func (c *Client) ZoneOperation(ctx context.Context, zone, operation string) (string, error) {
+	var result struct {
+		Result string `json:"result"`
+	}
+	if err := c.Do(ctx, http.MethodPut, c.Path("/zones/%s/%s", zone, operation), nil, &result); err != nil {
+		return "", err
+	}
+	return result.Result, nil
+}
@@ -3,6 +3,7 @@ package routes
 import (
 	"github.com/gin-gonic/gin"
 	"github.com/rafinhacuri/SanchezDNS/controllers"
+	"github.com/rafinhacuri/SanchezDNS/metrics"
 	"github.com/rafinhacuri/SanchezDNS/middleware"
 )
 
@@ -14,17 +15,25 @@ func RegisterRoutes(server *gin.Engine) {
 	})
 
 	server.GET("/healthcheck", controllers.HealthCheck)
+	server.GET("/metrics", controllers.Metrics)
+	metrics.RegisterPdnsCollector(controllers.CollectPdnsStats)
 
 	server.POST("/login", controllers.Auth)
 	server.PUT("/api/user", controllers.InsertUser)
+	server.GET("/nic/update", controllers.NicUpdate)
+	server.POST("/acme/update", controllers.AcmeUpdate)
 
 	api := server.Group("/api", middleware.Authenticate)
 	apiAdmin := api.Group("/", middleware.AuthenticateAdmin)
 	api.GET("/check-session", middleware.CheckSession)
 
 	api.PATCH("/user/password", controllers.ChangePassword)
+	api.GET("/user/activity", controllers.GetUserActivity)
 	api.GET("/statistics", controllers.GetStatistics)
+	api.GET("/statistics/history", controllers.GetStatisticsHistory)
 	api.GET("/connections", controllers.GetConnections)
+	api.GET("/connections/health", controllers.GetConnectionsHealth)
+	api.GET("/connection/health", controllers.GetHealthHistory)
 	api.GET("/connection", controllers.GetConnection)
 	api.PATCH("/connection/apikey", controllers.EditConnectionApiKey)
 	api.PATCH("/connection", controllers.EditConnection)
@@ -37,11 +46,65 @@ func RegisterRoutes(server *gin.Engine) {
 	api.PUT("/zone/records", controllers.InsertRecord)
 	api.DELETE("/zone/records", controllers.DeleteRecord)
 	api.PATCH("/zone/records", controllers.EditRecord)
+	api.PUT("/zone/notify", controllers.NotifyZone)
+	api.PUT("/zone/axfr-retrieve", controllers.RetrieveZone)
+	api.PUT("/zone/rectify", controllers.RectifyZone)
+	api.GET("/zone/check", controllers.CheckZone)
+	api.GET("/zone/ptr-report", controllers.PtrReport)
+	api.POST("/zone/template", controllers.ApplyTemplate)
+	api.POST("/zone/clone", controllers.CloneZone)
+	api.POST("/zone/apply", controllers.ApplyZoneSpec)
+	api.POST("/zone/reverse", controllers.CreateReverseZones)
+	api.GET("/zone/tsigkeys", controllers.GetZoneTsigKeys)
+	api.PUT("/zone/tsigkeys", controllers.SetZoneTsigKeys)
+	api.GET("/tsigkeys", controllers.GetTsigKeys)
+	api.PUT("/tsigkeys", controllers.CreateTsigKey)
+	api.PATCH("/tsigkeys", controllers.RenameTsigKey)
+	api.DELETE("/tsigkeys", controllers.DeleteTsigKey)
+	api.GET("/search", controllers.Search)
+	api.GET("/ipam", controllers.GetIpam)
+	api.GET("/drift", controllers.GetDriftEvents)
+	api.POST("/drift/check", controllers.CheckDrift)
+	api.POST("/drift/baseline", controllers.SetBaseline)
+	api.POST("/drift/reapply", controllers.ReapplyBaseline)
+	api.GET("/acme/accounts", controllers.GetAcmeAccounts)
+	api.POST("/acme/register", controllers.RegisterAcmeAccount)
+	api.DELETE("/acme/account", controllers.DeleteAcmeAccount)
+	api.GET("/templates", controllers.GetTemplates)
+	api.GET("/template", controllers.GetTemplate)
 
 	apiAdmin.GET("/users", controllers.GetUsers)
 	apiAdmin.GET("/logs", controllers.GetLogs)
+	apiAdmin.GET("/logs/export", controllers.ExportLogs)
+	apiAdmin.GET("/logs/verify", controllers.VerifyLogs)
+	apiAdmin.GET("/security-events", controllers.GetSecurityEvents)
 	apiAdmin.PUT("/connections", controllers.InsertConnection)
 	apiAdmin.GET("/full-connections", controllers.GetFullConnections)
 	apiAdmin.POST("/connection/user", controllers.AddUser)
 	apiAdmin.DELETE("/connection/user", controllers.RemoveUser)
+	apiAdmin.PUT("/templates", controllers.InsertTemplate)
+	apiAdmin.PATCH("/template", controllers.EditTemplate)
+	apiAdmin.DELETE("/template", controllers.DeleteTemplate)
+	apiAdmin.GET("/update-keys", controllers.GetUpdateKeys)
+	apiAdmin.PUT("/update-keys", controllers.InsertUpdateKey)
+	apiAdmin.DELETE("/update-key", controllers.DeleteUpdateKey)
+	apiAdmin.GET("/dyndns-hosts", controllers.GetDynDnsHosts)
+	apiAdmin.PUT("/dyndns-hosts", controllers.InsertDynDnsHost)
+	apiAdmin.DELETE("/dyndns-host", controllers.DeleteDynDnsHost)
+	apiAdmin.GET("/webhooks", controllers.GetWebhooks)
+	apiAdmin.PUT("/webhooks", controllers.InsertWebhook)
+	apiAdmin.PATCH("/webhook", controllers.EditWebhook)
+	apiAdmin.DELETE("/webhook", controllers.DeleteWebhook)
+	apiAdmin.GET("/webhook/deliveries", controllers.GetWebhookDeliveries)
+	apiAdmin.POST("/webhook/test", controllers.TestWebhook)
+	apiAdmin.GET("/notification-channels", controllers.GetNotificationChannels)
+	apiAdmin.PUT("/notification-channels", controllers.InsertNotificationChannel)
+	apiAdmin.PATCH("/notification-channel", controllers.EditNotificationChannel)
+	apiAdmin.DELETE("/notification-channel", controllers.DeleteNotificationChannel)
+	apiAdmin.POST("/notification-channel/test", controllers.TestNotificationChannel)
+	apiAdmin.GET("/sync-jobs", controllers.GetSyncJobs)
+	apiAdmin.PUT("/sync-jobs", controllers.InsertSyncJob)
+	apiAdmin.PATCH("/sync-job", controllers.EditSyncJob)
+	apiAdmin.DELETE("/sync-job", controllers.DeleteSyncJob)
+	apiAdmin.POST("/sync-job/run", controllers.RunSyncJob)
 }
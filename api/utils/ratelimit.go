@@ -0,0 +1,81 @@
+package utils
+
+import (
+	"sync"
+	"time"
+)
+
+// RateLimiter allows at most limit events per key within a sliding window.
+type RateLimiter struct {
+	mu     sync.Mutex
+	limit  int
+	window time.Duration
+	hits   map[string][]time.Time
+}
+
+func NewRateLimiter(limit int, window time.Duration) *RateLimiter {
+	return &RateLimiter{limit: limit, window: window, hits: map[string][]time.Time{}}
+}
+
+// Allow records an event for key and reports whether it is within the
+// limit.
+func (l *RateLimiter) Allow(key string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	hits, now := l.prune(key)
+	if len(hits) >= l.limit {
+		return false
+	}
+
+	l.hits[key] = append(hits, now)
+	return true
+}
+
+// Limited reports whether key has used up its events in the current window
+// without recording one. Together with Add it limits failures only.
+func (l *RateLimiter) Limited(key string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	hits, _ := l.prune(key)
+	return len(hits) >= l.limit
+}
+
+// Add records an event for key.
+func (l *RateLimiter) Add(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	hits, now := l.prune(key)
+	l.hits[key] = append(hits, now)
+}
+
+// prune drops the events of key that left the window, and every expired
+// key once the map grows large. The caller holds l.mu.
+func (l *RateLimiter) prune(key string) ([]time.Time, time.Time) {
+	now := time.Now()
+	cutoff := now.Add(-l.window)
+
+	if len(l.hits) > 10000 {
+		for k, hits := range l.hits {
+			if len(hits) == 0 || hits[len(hits)-1].Before(cutoff) {
+				delete(l.hits, k)
+			}
+		}
+	}
+
+	hits := l.hits[key]
+	i := 0
+	for i < len(hits) && hits[i].Before(cutoff) {
+		i++
+	}
+	hits = hits[i:]
+	if len(hits) == 0 {
+		delete(l.hits, key)
+	} else {
+		l.hits[key] = hits
+	}
+
+	return hits, now
+}
@@ -0,0 +1,53 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRateLimiterAllow(t *testing.T) {
+	l := NewRateLimiter(2, time.Minute)
+
+	if !l.Allow("a") || !l.Allow("a") {
+		t.Fatal("first two events must be allowed")
+	}
+	if l.Allow("a") {
+		t.Fatal("third event must be refused")
+	}
+	if !l.Allow("b") {
+		t.Fatal("keys must be limited independently")
+	}
+}
+
+func TestRateLimiterFailuresOnly(t *testing.T) {
+	l := NewRateLimiter(2, time.Minute)
+
+	for range 5 {
+		if l.Limited("a") {
+			t.Fatal("checking must not count as an event")
+		}
+	}
+
+	l.Add("a")
+	if l.Limited("a") {
+		t.Fatal("one failure must not reach the limit")
+	}
+	l.Add("a")
+	if !l.Limited("a") {
+		t.Fatal("two failures must reach the limit")
+	}
+}
+
+func TestRateLimiterWindow(t *testing.T) {
+	l := NewRateLimiter(1, 20*time.Millisecond)
+
+	l.Add("a")
+	if !l.Limited("a") {
+		t.Fatal("key must be limited inside the window")
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	if l.Limited("a") {
+		t.Fatal("events must expire after the window")
+	}
+}
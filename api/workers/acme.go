@@ -0,0 +1,113 @@
+package workers
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/rafinhacuri/SanchezDNS/db"
+	"github.com/rafinhacuri/SanchezDNS/models"
+	"github.com/rafinhacuri/SanchezDNS/pdns"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// acmeLocks holds one mutex per ACME account ID.
+var acmeLocks sync.Map
+
+// PublishAcmeChallenge writes the current TXT values of an ACME account to
+// its challenge name, deleting the rrset when none are left.
+func PublishAcmeChallenge(ctx context.Context, account *models.AcmeAccount) error {
+	connection, err := models.FindConnection(ctx, account.IdConnection)
+	if err != nil {
+		return err
+	}
+
+	client, err := pdns.New(connection)
+	if err != nil {
+		return err
+	}
+
+	if err := client.PatchZone(ctx, account.Zone, []map[string]any{account.Patch()}); err != nil {
+		return err
+	}
+
+	RecordZoneState(connection, account.Zone, account.CreatedBy)
+	return nil
+}
+
+// UpdateAcmeChallenge reads the ACME account id, lets update change its TXT
+// values, then publishes and saves them. Updates of one account run one at
+// a time, so a wildcard and its base name validated in parallel do not
+// overwrite each other's value. Nothing is written when update returns
+// false.
+func UpdateAcmeChallenge(ctx context.Context, id primitive.ObjectID, update func(*models.AcmeAccount) bool) (*models.AcmeAccount, error) {
+	lock, _ := acmeLocks.LoadOrStore(id, &sync.Mutex{})
+	mu := lock.(*sync.Mutex)
+	mu.Lock()
+	defer mu.Unlock()
+
+	var account models.AcmeAccount
+	if err := db.Database.Collection("acme_accounts").FindOne(ctx, bson.M{"_id": id}).Decode(&account); err != nil {
+		return nil, err
+	}
+
+	if !update(&account) {
+		return &account, nil
+	}
+
+	if err := PublishAcmeChallenge(ctx, &account); err != nil {
+		return nil, err
+	}
+
+	set := bson.M{"txt": account.Txt}
+	if account.LastUpdateAt != nil {
+		set["lastUpdateAt"] = account.LastUpdateAt
+	}
+	if _, err := db.Database.Collection("acme_accounts").UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": set}); err != nil {
+		slog.Error("failed to save ACME account", "name", account.FullDomain, "error", err)
+	}
+
+	return &account, nil
+}
+
+func acmeLoop(ctx context.Context) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			cleanupAcmeChallenges(ctx)
+		}
+	}
+}
+
+// cleanupAcmeChallenges removes challenge values that outlived
+// models.AcmeTxtLifetime.
+func cleanupAcmeChallenges(ctx context.Context) {
+	now := time.Now()
+
+	cursor, err := db.Database.Collection("acme_accounts").Find(ctx, bson.M{"txt.createdAt": bson.M{"$lte": now.Add(-models.AcmeTxtLifetime)}})
+	if err != nil {
+		slog.Error("failed to fetch ACME accounts", "error", err)
+		return
+	}
+
+	var accounts []models.AcmeAccount
+	if err := cursor.All(ctx, &accounts); err != nil {
+		slog.Error("failed to parse ACME accounts", "error", err)
+		return
+	}
+
+	for _, account := range accounts {
+		ctxAccount, cancel := context.WithTimeout(ctx, 15*time.Second)
+		if _, err := UpdateAcmeChallenge(ctxAccount, account.ID, func(a *models.AcmeAccount) bool { return a.PruneTxt(now) }); err != nil {
+			slog.Error("failed to clean up ACME challenge", "name", account.FullDomain, "error", err)
+		}
+		cancel()
+	}
+}
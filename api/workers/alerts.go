@@ -0,0 +1,83 @@
+package workers
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/smtp"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/rafinhacuri/SanchezDNS/models"
+)
+
+// sendHealthAlert delivers a health transition to every configured
+// channel: HEALTH_WEBHOOK_URL and, when SMTP_ADDR and HEALTH_ALERT_EMAILS
+// are set, email through the SMTP relay.
+func sendHealthAlert(ctx context.Context, event *models.HealthEvent) {
+	if url := os.Getenv("HEALTH_WEBHOOK_URL"); url != "" {
+		payload := map[string]any{
+			"event":      "connection_health",
+			"connection": event.IdConnection,
+			"name":       event.Name,
+			"hostServer": event.HostServer,
+			"status":     event.Status,
+			"previous":   event.Previous,
+			"error":      event.Error,
+			"at":         event.At,
+		}
+
+		resp, err := resty.New().SetTimeout(10 * time.Second).R().SetContext(ctx).SetBody(payload).Post(url)
+		if err != nil {
+			slog.Error("failed to send health webhook", "connection", event.Name, "error", err)
+		} else if resp.IsError() {
+			slog.Error("health webhook rejected", "connection", event.Name, "status", resp.StatusCode())
+		}
+	}
+
+	addr := os.Getenv("SMTP_ADDR")
+	recipients := splitList(os.Getenv("HEALTH_ALERT_EMAILS"))
+	if addr == "" || len(recipients) == 0 {
+		return
+	}
+
+	from := os.Getenv("SMTP_FROM")
+	if from == "" {
+		from = "sanchezdns@localhost"
+	}
+
+	// The connection name is user input; a line break in it would let it
+	// add headers to the message.
+	subject := headerValue(fmt.Sprintf("[SanchezDNS] %s is %s", event.Name, strings.ToUpper(event.Status)))
+	body := fmt.Sprintf("Connection: %s\r\nHost: %s\r\nStatus: %s (was %s)\r\nTime: %s\r\n", event.Name, event.HostServer, event.Status, event.Previous, event.At.Format(time.RFC3339))
+	if event.Error != "" {
+		body += "Error: " + event.Error + "\r\n"
+	}
+
+	msg := "From: " + from + "\r\n" +
+		"To: " + strings.Join(recipients, ", ") + "\r\n" +
+		"Subject: " + subject + "\r\n" +
+		"Date: " + event.At.Format(time.RFC1123Z) + "\r\n" +
+		"Content-Type: text/plain; charset=utf-8\r\n\r\n" + body
+
+	if err := smtp.SendMail(addr, nil, from, recipients, []byte(msg)); err != nil {
+		slog.Error("failed to send health email", "connection", event.Name, "error", err)
+	}
+}
+
+// headerValue drops the CR and LF characters that would end a header line.
+func headerValue(s string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(s)
+}
+
+func splitList(raw string) []string {
+	var out []string
+	for _, item := range strings.Split(raw, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
@@ -0,0 +1,291 @@
+package workers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/rafinhacuri/SanchezDNS/db"
+	"github.com/rafinhacuri/SanchezDNS/models"
+	"github.com/rafinhacuri/SanchezDNS/pdns"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func zoneKey(zone string) string {
+	return strings.ToLower(models.Fqdn(zone))
+}
+
+func FindZoneState(ctx context.Context, connectionID, zone string) (*models.ZoneState, error) {
+	var state models.ZoneState
+	err := db.Database.Collection("zone_states").FindOne(ctx, bson.M{"idConnection": connectionID, "zone": zoneKey(zone)}).Decode(&state)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+// SaveZoneState stores the live content of a zone as its known state. A
+// pinned baseline is only replaced when pin is set.
+func SaveZoneState(ctx context.Context, client *pdns.Client, connectionID, zone, username string, pin bool) (*models.ZoneState, error) {
+	existing, err := FindZoneState(ctx, connectionID, zone)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil && existing.Pinned && !pin {
+		return existing, nil
+	}
+
+	zoneData, err := client.Zone(ctx, zone)
+	if err != nil {
+		return nil, err
+	}
+
+	state := models.ZoneState{
+		IdConnection: connectionID,
+		Zone:         zoneKey(zone),
+		RRSets:       zoneData.RRSets,
+		Hash:         models.StateHash(zoneData.RRSets),
+		Pinned:       pin,
+		UpdatedBy:    username,
+		UpdatedAt:    time.Now(),
+	}
+
+	opts := options.Update().SetUpsert(true)
+	filter := bson.M{"idConnection": connectionID, "zone": state.Zone}
+	if _, err := db.Database.Collection("zone_states").UpdateOne(ctx, filter, bson.M{"$set": state}, opts); err != nil {
+		return nil, err
+	}
+
+	return &state, nil
+}
+
+// RecordZoneState refreshes the known state of a zone after a change made
+// through SanchezDNS. It runs in the background so requests are not slowed
+// down by the extra zone fetch.
+func RecordZoneState(connection *models.Connection, zone, username string) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+		defer cancel()
+
+		client, err := pdns.New(connection)
+		if err != nil {
+			slog.Error("failed to record zone state", "zone", zone, "error", err)
+			return
+		}
+
+		if _, err := SaveZoneState(ctx, client, connection.ID.Hex(), zone, username, false); err != nil {
+			slog.Error("failed to record zone state", "zone", zone, "error", err)
+		}
+	}()
+}
+
+func ForgetZoneState(ctx context.Context, connectionID, zone string) error {
+	filter := bson.M{"idConnection": connectionID, "zone": zoneKey(zone)}
+	if _, err := db.Database.Collection("zone_states").DeleteOne(ctx, filter); err != nil {
+		return err
+	}
+	_, err := db.Database.Collection("drift_events").DeleteMany(ctx, filter)
+	return err
+}
+
+func ResolveDrift(ctx context.Context, connectionID, zone, resolution, username string) error {
+	now := time.Now()
+	_, err := db.Database.Collection("drift_events").UpdateMany(ctx,
+		bson.M{"idConnection": connectionID, "zone": zoneKey(zone), "resolved": false},
+		bson.M{"$set": bson.M{"resolved": true, "resolution": resolution, "resolvedBy": username, "resolvedAt": now, "updatedAt": now}},
+	)
+	return err
+}
+
+// DetectZoneDrift compares a zone with its known state. The first time a
+// zone is seen its content becomes the known state. It returns the open
+// drift event for the zone, if any.
+func DetectZoneDrift(ctx context.Context, connection *models.Connection, client *pdns.Client, zone string) (*models.DriftEvent, error) {
+	connectionID := connection.ID.Hex()
+
+	state, err := FindZoneState(ctx, connectionID, zone)
+	if err != nil {
+		return nil, err
+	}
+	if state == nil {
+		_, err := SaveZoneState(ctx, client, connectionID, zone, "system", false)
+		return nil, err
+	}
+
+	zoneData, err := client.Zone(ctx, zone)
+	if err != nil {
+		return nil, err
+	}
+
+	var open models.DriftEvent
+	err = db.Database.Collection("drift_events").FindOne(ctx, bson.M{"idConnection": connectionID, "zone": state.Zone, "resolved": false}).Decode(&open)
+	hasOpen := err == nil
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, err
+	}
+
+	hash := models.StateHash(zoneData.RRSets)
+	if hash == state.Hash {
+		if hasOpen {
+			return nil, ResolveDrift(ctx, connectionID, zone, "reverted", "system")
+		}
+		return nil, nil
+	}
+
+	if hasOpen && open.Hash == hash {
+		return &open, nil
+	}
+
+	now := time.Now()
+	event := models.DriftEvent{
+		IdConnection: connectionID,
+		HostServer:   connection.Host,
+		Zone:         state.Zone,
+		Changes:      state.Drift(zoneData.RRSets),
+		Hash:         hash,
+		DetectedAt:   now,
+		UpdatedAt:    now,
+	}
+
+	if hasOpen {
+		event.ID = open.ID
+		event.DetectedAt = open.DetectedAt
+		_, err = db.Database.Collection("drift_events").UpdateOne(ctx, bson.M{"_id": open.ID}, bson.M{"$set": bson.M{"changes": event.Changes, "hash": hash, "updatedAt": now}})
+	} else {
+		var result *mongo.InsertOneResult
+		result, err = db.Database.Collection("drift_events").InsertOne(ctx, event)
+		if err == nil {
+			event.ID, _ = result.InsertedID.(primitive.ObjectID)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	log := &models.Log{
+		Username:     "system",
+		IdConnection: connectionID,
+		Action:       "drift_detected",
+		Details:      fmt.Sprintf("Detected %d rrset changes made outside SanchezDNS in zone %s", len(event.Changes), state.Zone),
+		Zone:         state.Zone,
+		HostServer:   connection.Host,
+		Changes:      event.Changes,
+		CreatedAt:    now,
+	}
+
+	if err := log.Insert(ctx); err != nil {
+		slog.Error("failed to log drift", "zone", state.Zone, "error", err)
+	}
+
+	notifyDrift(ctx, &event)
+
+	return &event, nil
+}
+
+func notifyDrift(ctx context.Context, event *models.DriftEvent) {
+	url := os.Getenv("DRIFT_WEBHOOK_URL")
+	if url == "" {
+		return
+	}
+
+	payload := map[string]any{
+		"event":      "zone_drift",
+		"connection": event.IdConnection,
+		"hostServer": event.HostServer,
+		"zone":       event.Zone,
+		"changes":    event.Changes,
+		"detectedAt": event.DetectedAt,
+	}
+
+	resp, err := resty.New().SetTimeout(10 * time.Second).R().SetContext(ctx).SetBody(payload).Post(url)
+	if err != nil {
+		slog.Error("failed to send drift webhook", "zone", event.Zone, "error", err)
+		return
+	}
+	if resp.IsError() {
+		slog.Error("drift webhook rejected", "zone", event.Zone, "status", resp.StatusCode())
+	}
+}
+
+func driftInterval() time.Duration {
+	raw := os.Getenv("DRIFT_INTERVAL")
+	if raw == "" {
+		return 15 * time.Minute
+	}
+	interval, err := time.ParseDuration(raw)
+	if err != nil {
+		slog.Error("invalid DRIFT_INTERVAL, drift detection disabled", "value", raw)
+		return 0
+	}
+	return interval
+}
+
+func driftLoop(ctx context.Context) {
+	interval := driftInterval()
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			detectAllDrift(ctx)
+		}
+	}
+}
+
+func detectAllDrift(ctx context.Context) {
+	cursor, err := db.Database.Collection("connections").Find(ctx, bson.M{})
+	if err != nil {
+		slog.Error("failed to fetch connections", "error", err)
+		return
+	}
+
+	var connections []models.Connection
+	if err := cursor.All(ctx, &connections); err != nil {
+		slog.Error("failed to parse connections", "error", err)
+		return
+	}
+
+	for i := range connections {
+		connection := &connections[i]
+
+		client, err := pdns.New(connection)
+		if err != nil {
+			slog.Error("drift detection skipped connection", "connection", connection.Name, "error", err)
+			continue
+		}
+
+		ctxList, cancel := context.WithTimeout(ctx, 10*time.Second)
+		zones, err := client.Zones(ctxList)
+		cancel()
+		if err != nil {
+			slog.Error("drift detection skipped connection", "connection", connection.Name, "error", err)
+			continue
+		}
+
+		for _, z := range zones {
+			ctxZone, cancel := context.WithTimeout(ctx, 10*time.Second)
+			if _, err := DetectZoneDrift(ctxZone, connection, client, z.ID); err != nil {
+				slog.Error("drift detection failed", "connection", connection.Name, "zone", z.Name, "error", err)
+			}
+			cancel()
+		}
+	}
+}
@@ -0,0 +1,198 @@
+package workers
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/rafinhacuri/SanchezDNS/db"
+	"github.com/rafinhacuri/SanchezDNS/models"
+	"github.com/rafinhacuri/SanchezDNS/pdns"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func healthInterval() time.Duration {
+	raw := os.Getenv("HEALTH_INTERVAL")
+	if raw == "" {
+		return 30 * time.Second
+	}
+	interval, err := time.ParseDuration(raw)
+	if err != nil {
+		slog.Error("invalid HEALTH_INTERVAL, health checks disabled", "value", raw)
+		return 0
+	}
+	return interval
+}
+
+// healthFailures is the number of consecutive failed probes before a
+// connection is considered down (HEALTH_FAILURES, 2 by default).
+func healthFailures() int {
+	n, err := strconv.Atoi(os.Getenv("HEALTH_FAILURES"))
+	if err != nil || n < 1 {
+		return 2
+	}
+	return n
+}
+
+// ensureHealthIndexes expires probe results after HEALTH_RETENTION_DAYS
+// (7 by default).
+func ensureHealthIndexes(ctx context.Context) error {
+	days, err := strconv.Atoi(os.Getenv("HEALTH_RETENTION_DAYS"))
+	if err != nil || days <= 0 {
+		days = 7
+	}
+
+	if err := ensureTTLIndex(ctx, "health_checks", "checkedAt", int32(days*24*60*60)); err != nil {
+		return err
+	}
+
+	_, err = db.Database.Collection("health_checks").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "idConnection", Value: 1}, {Key: "checkedAt", Value: -1}},
+	})
+	return err
+}
+
+func healthLoop(ctx context.Context) {
+	interval := healthInterval()
+	if interval <= 0 {
+		return
+	}
+
+	ctxInit, cancel := context.WithTimeout(ctx, 10*time.Second)
+	err := ensureHealthIndexes(ctxInit)
+	cancel()
+	if err != nil {
+		slog.Error("failed to create health check indexes", "error", err)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			checkAllConnections(ctx)
+		}
+	}
+}
+
+func checkAllConnections(ctx context.Context) {
+	cursor, err := db.Database.Collection("connections").Find(ctx, bson.M{})
+	if err != nil {
+		slog.Error("failed to fetch connections", "error", err)
+		return
+	}
+
+	var connections []models.Connection
+	if err := cursor.All(ctx, &connections); err != nil {
+		slog.Error("failed to parse connections", "error", err)
+		return
+	}
+
+	var wg sync.WaitGroup
+	for i := range connections {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			ctxCheck, cancel := context.WithTimeout(ctx, 15*time.Second)
+			defer cancel()
+
+			if err := CheckConnectionHealth(ctxCheck, &connections[i]); err != nil {
+				slog.Error("health check failed", "connection", connections[i].Name, "error", err)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func probe(ctx context.Context, connection *models.Connection) *models.HealthCheck {
+	check := &models.HealthCheck{IdConnection: connection.ID.Hex(), CheckedAt: time.Now()}
+
+	client, err := pdns.New(connection)
+	if err != nil {
+		check.Error = err.Error()
+		return check
+	}
+
+	start := time.Now()
+	err = client.Do(ctx, http.MethodGet, client.Path(""), nil, nil)
+	check.LatencyMs = time.Since(start).Milliseconds()
+	if err != nil {
+		check.Error = err.Error()
+		return check
+	}
+
+	check.Up = true
+	return check
+}
+
+// CheckConnectionHealth probes a connection, stores the result and sends an
+// alert when the connection goes up or down.
+func CheckConnectionHealth(ctx context.Context, connection *models.Connection) error {
+	connectionID := connection.ID.Hex()
+	check := probe(ctx, connection)
+
+	var health models.ConnectionHealth
+	err := db.Database.Collection("connection_health").FindOne(ctx, bson.M{"idConnection": connectionID}).Decode(&health)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		return err
+	}
+
+	previous := health.Status
+	status := health.Apply(check, healthFailures())
+
+	health.IdConnection = connectionID
+	health.Name = connection.Name
+	health.HostServer = connection.Host
+	health.Status = status
+	if status != previous {
+		health.LastChangeAt = &check.CheckedAt
+	}
+
+	if _, err := db.Database.Collection("health_checks").InsertOne(ctx, check); err != nil {
+		return err
+	}
+
+	opts := options.Update().SetUpsert(true)
+	if _, err := db.Database.Collection("connection_health").UpdateOne(ctx, bson.M{"idConnection": connectionID}, bson.M{"$set": health}, opts); err != nil {
+		return err
+	}
+
+	if status == previous || status == models.HealthUnknown {
+		return nil
+	}
+
+	event := models.HealthEvent{
+		IdConnection: connectionID,
+		Name:         connection.Name,
+		HostServer:   connection.Host,
+		Status:       status,
+		Previous:     previous,
+		Error:        health.LastError,
+		At:           check.CheckedAt,
+	}
+	if event.Previous == "" {
+		event.Previous = models.HealthUnknown
+	}
+
+	if _, err := db.Database.Collection("health_events").InsertOne(ctx, event); err != nil {
+		return err
+	}
+
+	// The first successful probe of a connection is not worth an alert.
+	if event.Previous != models.HealthUnknown || status == models.HealthDown {
+		sendHealthAlert(ctx, &event)
+	}
+
+	return nil
+}
@@ -0,0 +1,228 @@
+package workers
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/rafinhacuri/SanchezDNS/db"
+	"github.com/rafinhacuri/SanchezDNS/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// logArchiveBatch is the number of entries written to one archive file.
+const logArchiveBatch = 10000
+
+// logRetention is how long audit log entries are kept
+// (LOG_RETENTION_DAYS). Entries are kept forever when it is unset or zero.
+func logRetention() time.Duration {
+	raw := os.Getenv("LOG_RETENTION_DAYS")
+	if raw == "" {
+		return 0
+	}
+	days, err := strconv.Atoi(raw)
+	if err != nil {
+		slog.Error("invalid LOG_RETENTION_DAYS, log retention disabled", "value", raw)
+		return 0
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// ensureLogIndexes creates the indexes behind the logs listing, one per
+// filterable field followed by the default sort, and the unique index that
+// keeps two entries from taking the same place in a connection's hash
+// chain. Building them on a large collection can take a while, hence the
+// longer timeout.
+func ensureLogIndexes(ctx context.Context) {
+	ctxInit, cancel := context.WithTimeout(ctx, 10*time.Minute)
+	defer cancel()
+
+	byDate := func(field string) mongo.IndexModel {
+		keys := bson.D{{Key: "createdAt", Value: -1}, {Key: "_id", Value: -1}}
+		if field != "" {
+			keys = append(bson.D{{Key: field, Value: 1}}, keys...)
+		}
+		return mongo.IndexModel{Keys: keys}
+	}
+
+	_, err := db.Database.Collection("logs").Indexes().CreateMany(ctxInit, []mongo.IndexModel{
+		byDate(""),
+		byDate("idConnection"),
+		byDate("username"),
+		byDate("action"),
+		byDate("zone"),
+		{
+			Keys:    bson.D{{Key: "idConnection", Value: 1}, {Key: "seq", Value: 1}},
+			Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{"seq": bson.M{"$exists": true}}),
+		},
+	})
+	if err != nil {
+		slog.Error("failed to create log indexes", "error", err)
+	}
+}
+
+func logRetentionLoop(ctx context.Context) {
+	retention := logRetention()
+	if retention <= 0 {
+		return
+	}
+
+	archiveDir := os.Getenv("LOG_ARCHIVE_DIR")
+	if archiveDir != "" {
+		if err := os.MkdirAll(archiveDir, 0o750); err != nil {
+			slog.Error("failed to create log archive directory, log retention disabled", "dir", archiveDir, "error", err)
+			return
+		}
+	}
+
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for {
+		purgeLogs(ctx, time.Now().Add(-retention), archiveDir)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// purgeLogs removes the entries older than cutoff. With an archive
+// directory they are first written there as gzipped NDJSON files, and an
+// entry is only deleted once the file holding it is on disk.
+func purgeLogs(ctx context.Context, cutoff time.Time, archiveDir string) {
+	filter := bson.M{"createdAt": bson.M{"$lt": cutoff}}
+
+	var removed int64
+	if archiveDir == "" {
+		if err := models.MarkLogsPruned(ctx, filter); err != nil {
+			slog.Error("failed to record pruned log chains", "error", err)
+			return
+		}
+
+		result, err := db.Database.Collection("logs").DeleteMany(ctx, filter)
+		if err != nil {
+			slog.Error("failed to purge logs", "error", err)
+			return
+		}
+		removed = result.DeletedCount
+	} else {
+		for {
+			n, err := archiveLogBatch(ctx, filter, archiveDir)
+			if err != nil {
+				slog.Error("failed to archive logs", "error", err)
+				break
+			}
+			removed += n
+			if n < logArchiveBatch {
+				break
+			}
+		}
+	}
+
+	if removed == 0 {
+		return
+	}
+
+	details := fmt.Sprintf("Retention removed %d log entries older than %s", removed, cutoff.UTC().Format(time.RFC3339))
+	if archiveDir != "" {
+		details = fmt.Sprintf("Retention archived %d log entries older than %s to %s", removed, cutoff.UTC().Format(time.RFC3339), archiveDir)
+	}
+
+	log := &models.Log{
+		Username: "system",
+		Action:   "purge_logs",
+		Details:  details,
+	}
+
+	if err := log.Insert(ctx); err != nil {
+		slog.Error("failed to log log purge", "error", err)
+	}
+}
+
+func archiveLogBatch(ctx context.Context, filter bson.M, archiveDir string) (int64, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: 1}, {Key: "_id", Value: 1}}).SetLimit(logArchiveBatch)
+
+	cursor, err := db.Database.Collection("logs").Find(ctx, filter, opts)
+	if err != nil {
+		return 0, err
+	}
+
+	var entries []bson.Raw
+	if err := cursor.All(ctx, &entries); err != nil {
+		return 0, err
+	}
+	if len(entries) == 0 {
+		return 0, nil
+	}
+
+	ids := make([]any, 0, len(entries))
+	lines := make([]models.Log, 0, len(entries))
+	for _, raw := range entries {
+		var entry models.Log
+		if err := bson.Unmarshal(raw, &entry); err != nil {
+			return 0, err
+		}
+		ids = append(ids, raw.Lookup("_id"))
+		lines = append(lines, entry)
+	}
+
+	first, last := lines[0].CreatedAt.UTC(), lines[len(lines)-1].CreatedAt.UTC()
+	name := fmt.Sprintf("logs-%s-%s-%s.ndjson.gz", first.Format("20060102T150405"), last.Format("20060102T150405"), lines[0].ID)
+	if err := writeLogArchive(filepath.Join(archiveDir, name), lines); err != nil {
+		return 0, err
+	}
+
+	batch := bson.M{"_id": bson.M{"$in": ids}}
+	if err := models.MarkLogsPruned(ctx, batch); err != nil {
+		return 0, err
+	}
+
+	result, err := db.Database.Collection("logs").DeleteMany(ctx, batch)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.DeletedCount, nil
+}
+
+// writeLogArchive writes the entries to a temporary file and renames it
+// into place once synced, so a partial archive never has the final name.
+func writeLogArchive(path string, entries []models.Log) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".logs-*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	gz := gzip.NewWriter(tmp)
+	enc := json.NewEncoder(gz)
+	for _, entry := range entries {
+		if err := enc.Encode(entry); err != nil {
+			return err
+		}
+	}
+
+	if err := gz.Close(); err != nil {
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
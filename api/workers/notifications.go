@@ -0,0 +1,96 @@
+package workers
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/rafinhacuri/SanchezDNS/db"
+	"github.com/rafinhacuri/SanchezDNS/models"
+	"github.com/rafinhacuri/SanchezDNS/utils"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// notifyChannels posts a summary of the log entry to every enabled chat
+// channel subscribed to it.
+func notifyChannels(l models.Log) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	cursor, err := db.Database.Collection("notification_channels").Find(ctx, bson.M{"enabled": true})
+	if err != nil {
+		slog.Error("failed to fetch notification channels", "error", err)
+		return
+	}
+
+	var channels []models.NotificationChannel
+	if err := cursor.All(ctx, &channels); err != nil {
+		slog.Error("failed to parse notification channels", "error", err)
+		return
+	}
+
+	var summary *models.ChangeSummary
+	for i := range channels {
+		channel := &channels[i]
+		if !channel.Matches(&l) {
+			continue
+		}
+
+		if summary == nil {
+			name := ""
+			if l.IdConnection != "" {
+				if connection, err := models.FindConnection(ctx, l.IdConnection); err == nil {
+					name = connection.Name
+				}
+			}
+			s := models.SummarizeLog(&l, name)
+			summary = &s
+		}
+
+		_ = SendNotification(ctx, channel, *summary)
+	}
+}
+
+// SendNotification posts a summary to a channel, retrying twice, and
+// records the outcome on the channel.
+func SendNotification(ctx context.Context, channel *models.NotificationChannel, summary models.ChangeSummary) error {
+	err := postNotification(ctx, channel, summary)
+
+	update := bson.M{"$set": bson.M{"lastSentAt": time.Now()}, "$unset": bson.M{"lastError": ""}}
+	if err != nil {
+		slog.Error("failed to send chat notification", "channel", channel.Name, "error", err)
+		update = bson.M{"$set": bson.M{"lastError": err.Error()}}
+	}
+
+	ctxSave, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_, _ = db.Database.Collection("notification_channels").UpdateOne(ctxSave, bson.M{"_id": channel.ID}, update)
+
+	return err
+}
+
+func postNotification(ctx context.Context, channel *models.NotificationChannel, summary models.ChangeSummary) error {
+	url, err := utils.Decrypt(channel.URL)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt url: %w", err)
+	}
+
+	httpc := resty.New().
+		SetTimeout(10 * time.Second).
+		SetRetryCount(2).
+		SetRetryWaitTime(2 * time.Second).
+		AddRetryCondition(func(r *resty.Response, err error) bool {
+			return err != nil || r.StatusCode() == 429 || r.StatusCode() >= 500
+		})
+
+	resp, err := httpc.R().SetContext(ctx).SetBody(channel.Payload(summary)).Post(url)
+	if err != nil {
+		return err
+	}
+	if resp.IsError() {
+		return fmt.Errorf("unexpected status %d: %s", resp.StatusCode(), resp.String())
+	}
+	return nil
+}
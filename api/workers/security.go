@@ -0,0 +1,38 @@
+package workers
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/rafinhacuri/SanchezDNS/db"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ensureSecurityEventIndexes creates the lookup indexes of the security
+// events and expires them after SECURITY_EVENTS_RETENTION_DAYS (365 by
+// default).
+func ensureSecurityEventIndexes(ctx context.Context) {
+	days, err := strconv.Atoi(os.Getenv("SECURITY_EVENTS_RETENTION_DAYS"))
+	if err != nil || days <= 0 {
+		days = 365
+	}
+
+	ctxInit, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+
+	if err := ensureTTLIndex(ctxInit, "security_events", "createdAt", int32(days*24*60*60)); err != nil {
+		slog.Error("failed to create security event indexes", "error", err)
+	}
+
+	_, err = db.Database.Collection("security_events").Indexes().CreateMany(ctxInit, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "email", Value: 1}, {Key: "createdAt", Value: -1}}},
+		{Keys: bson.D{{Key: "sourceIp", Value: 1}, {Key: "createdAt", Value: -1}}},
+	})
+	if err != nil {
+		slog.Error("failed to create security event indexes", "error", err)
+	}
+}
@@ -0,0 +1,138 @@
+package workers
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/rafinhacuri/SanchezDNS/db"
+	"github.com/rafinhacuri/SanchezDNS/models"
+	"github.com/rafinhacuri/SanchezDNS/pdns"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func statsInterval() time.Duration {
+	raw := os.Getenv("STATS_INTERVAL")
+	if raw == "" {
+		return time.Minute
+	}
+	interval, err := time.ParseDuration(raw)
+	if err != nil {
+		slog.Error("invalid STATS_INTERVAL, statistics collection disabled", "value", raw)
+		return 0
+	}
+	return interval
+}
+
+// statsRetention is how long samples are kept, in days (STATS_RETENTION_DAYS,
+// 30 by default).
+func statsRetention() int64 {
+	days, err := strconv.Atoi(os.Getenv("STATS_RETENTION_DAYS"))
+	if err != nil || days <= 0 {
+		days = 30
+	}
+	return int64(days) * 24 * 60 * 60
+}
+
+// ensureStatisticsCollection creates the time-series collection the
+// samples are written to. When it already exists its expiry is set to the
+// current retention, which may have changed since it was created.
+func ensureStatisticsCollection(ctx context.Context) error {
+	opts := options.CreateCollection().
+		SetTimeSeriesOptions(options.TimeSeries().SetTimeField("timestamp").SetMetaField("meta").SetGranularity("minutes")).
+		SetExpireAfterSeconds(statsRetention())
+
+	err := db.Database.CreateCollection(ctx, "statistics", opts)
+	var cmdErr mongo.CommandError
+	if errors.As(err, &cmdErr) && cmdErr.Code == 48 {
+		return db.Database.RunCommand(ctx, bson.D{{Key: "collMod", Value: "statistics"}, {Key: "expireAfterSeconds", Value: statsRetention()}}).Err()
+	}
+	return err
+}
+
+func statsLoop(ctx context.Context) {
+	interval := statsInterval()
+	if interval <= 0 {
+		return
+	}
+
+	ctxInit, cancel := context.WithTimeout(ctx, 10*time.Second)
+	err := ensureStatisticsCollection(ctxInit)
+	cancel()
+	if err != nil {
+		slog.Error("failed to create statistics collection", "error", err)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			sampleStatistics(ctx)
+		}
+	}
+}
+
+func sampleStatistics(ctx context.Context) {
+	cursor, err := db.Database.Collection("connections").Find(ctx, bson.M{})
+	if err != nil {
+		slog.Error("failed to fetch connections", "error", err)
+		return
+	}
+
+	var connections []models.Connection
+	if err := cursor.All(ctx, &connections); err != nil {
+		slog.Error("failed to parse connections", "error", err)
+		return
+	}
+
+	now := time.Now().UTC()
+	samples := make([]any, 0, len(connections))
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for i := range connections {
+		connection := &connections[i]
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			client, err := pdns.New(connection)
+			if err != nil {
+				slog.Error("statistics sampling skipped connection", "connection", connection.Name, "error", err)
+				return
+			}
+
+			ctxStats, cancel := context.WithTimeout(ctx, 10*time.Second)
+			defer cancel()
+
+			stats, err := client.Statistics(ctxStats)
+			if err != nil {
+				slog.Error("statistics sampling failed", "connection", connection.Name, "error", err)
+				return
+			}
+
+			mu.Lock()
+			samples = append(samples, models.NewStatSample(connection.ID.Hex(), stats, now))
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	if len(samples) == 0 {
+		return
+	}
+	if _, err := db.Database.Collection("statistics").InsertMany(ctx, samples); err != nil {
+		slog.Error("failed to store statistics samples", "error", err)
+	}
+}
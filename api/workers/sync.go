@@ -0,0 +1,166 @@
+package workers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/rafinhacuri/SanchezDNS/db"
+	"github.com/rafinhacuri/SanchezDNS/models"
+	"github.com/rafinhacuri/SanchezDNS/pdns"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+var ErrSyncRunning = errors.New("sync job is already running")
+
+var runningSyncs sync.Map
+
+// RunSyncJob plans a sync job and, unless dryRun is set, applies the changes
+// to the destination zone in a single PATCH and records the outcome on the
+// job.
+func RunSyncJob(ctx context.Context, job *models.SyncJob, dryRun bool, username string) (*models.SyncReport, error) {
+	if _, busy := runningSyncs.LoadOrStore(job.ID, true); busy {
+		return nil, ErrSyncRunning
+	}
+	defer runningSyncs.Delete(job.ID)
+
+	report, err := runSync(ctx, job, dryRun, username)
+	if dryRun {
+		return report, err
+	}
+
+	now := time.Now()
+	update := bson.M{"lastRunAt": now, "lastStatus": "success", "lastError": "", "lastChanges": 0}
+	if err != nil {
+		update["lastStatus"] = "failed"
+		update["lastError"] = err.Error()
+	} else {
+		update["lastChanges"] = len(report.Changes)
+	}
+
+	if _, uerr := db.Database.Collection("sync_jobs").UpdateOne(ctx, bson.M{"_id": job.ID}, bson.M{"$set": update}); uerr != nil {
+		slog.Error("failed to update sync job", "job", job.Name, "error", uerr)
+	}
+
+	return report, err
+}
+
+func runSync(ctx context.Context, job *models.SyncJob, dryRun bool, username string) (*models.SyncReport, error) {
+	source, err := models.FindConnection(ctx, job.Source.Connection)
+	if err != nil {
+		return nil, fmt.Errorf("source: %w", err)
+	}
+
+	destination, err := models.FindConnection(ctx, job.Destination.Connection)
+	if err != nil {
+		return nil, fmt.Errorf("destination: %w", err)
+	}
+
+	sourceClient, err := pdns.New(source)
+	if err != nil {
+		return nil, err
+	}
+
+	destinationClient, err := pdns.New(destination)
+	if err != nil {
+		return nil, err
+	}
+
+	sourceZone, err := sourceClient.Zone(ctx, job.Source.Zone)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch source zone: %w", err)
+	}
+
+	destinationZone, err := destinationClient.Zone(ctx, job.Destination.Zone)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch destination zone: %w", err)
+	}
+
+	changes, conflicts := job.Plan(sourceZone, destinationZone)
+
+	report := &models.SyncReport{
+		Job:       job.Name,
+		Source:    job.Source,
+		Target:    job.Destination,
+		DryRun:    dryRun,
+		Changes:   changes,
+		Conflicts: conflicts,
+		RanAt:     time.Now(),
+	}
+
+	if dryRun || len(changes) == 0 {
+		return report, nil
+	}
+
+	if err := destinationClient.PatchZone(ctx, job.Destination.Zone, models.PatchChanges(changes)); err != nil {
+		return report, fmt.Errorf("failed to apply changes: %w", err)
+	}
+	report.Applied = true
+
+	RecordZoneState(destination, job.Destination.Zone, username)
+
+	log := &models.Log{
+		Username:     username,
+		IdConnection: job.Destination.Connection,
+		Action:       "sync_zone",
+		Details:      fmt.Sprintf("Sync job %s applied %d changes from %s on %s (%d conflicts, policy %s)", job.Name, len(changes), job.Source.Zone, source.Name, len(conflicts), job.ConflictPolicy),
+		Zone:         job.Destination.Zone,
+		HostServer:   destination.Host,
+		Changes:      changes,
+		CreatedAt:    time.Now(),
+	}
+
+	if err := log.Insert(ctx); err != nil {
+		slog.Error("failed to log sync job", "job", job.Name, "error", err)
+	}
+
+	return report, nil
+}
+
+func syncLoop(ctx context.Context) {
+	ticker := time.NewTicker(30 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			runDueSyncJobs(ctx)
+		}
+	}
+}
+
+func runDueSyncJobs(ctx context.Context) {
+	cursor, err := db.Database.Collection("sync_jobs").Find(ctx, bson.M{"enabled": true, "interval": bson.M{"$gt": 0}})
+	if err != nil {
+		slog.Error("failed to fetch sync jobs", "error", err)
+		return
+	}
+
+	var jobs []models.SyncJob
+	if err := cursor.All(ctx, &jobs); err != nil {
+		slog.Error("failed to parse sync jobs", "error", err)
+		return
+	}
+
+	now := time.Now()
+	for i := range jobs {
+		job := &jobs[i]
+		if job.LastRunAt != nil && job.LastRunAt.Add(time.Duration(job.Interval)*time.Second).After(now) {
+			continue
+		}
+
+		go func() {
+			ctxJob, cancel := context.WithTimeout(ctx, 60*time.Second)
+			defer cancel()
+
+			if _, err := RunSyncJob(ctxJob, job, false, "system"); err != nil && !errors.Is(err, ErrSyncRunning) {
+				slog.Error("sync job failed", "job", job.Name, "error", err)
+			}
+		}()
+	}
+}
@@ -0,0 +1,241 @@
+package workers
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/rafinhacuri/SanchezDNS/db"
+	"github.com/rafinhacuri/SanchezDNS/models"
+	"github.com/rafinhacuri/SanchezDNS/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// webhookLease keeps a delivery from being picked up by the retry loop
+// while an attempt is in flight.
+const webhookLease = 2 * time.Minute
+
+// enqueueWebhooks creates a delivery for every enabled webhook matching the
+// log entry and makes the first attempt right away.
+func enqueueWebhooks(l models.Log) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	cursor, err := db.Database.Collection("webhooks").Find(ctx, bson.M{"enabled": true})
+	if err != nil {
+		slog.Error("failed to fetch webhooks", "error", err)
+		return
+	}
+
+	var hooks []models.Webhook
+	if err := cursor.All(ctx, &hooks); err != nil {
+		slog.Error("failed to parse webhooks", "error", err)
+		return
+	}
+
+	for i := range hooks {
+		hook := &hooks[i]
+		if !hook.Matches(&l) {
+			continue
+		}
+
+		delivery, err := QueueWebhookDelivery(ctx, hook, &l)
+		if err != nil {
+			slog.Error("failed to queue webhook delivery", "webhook", hook.Name, "error", err)
+			continue
+		}
+
+		go func() {
+			ctxSend, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+			_ = DeliverWebhook(ctxSend, hook, delivery)
+		}()
+	}
+}
+
+// QueueWebhookDelivery stores a pending delivery of l to hook, leased so
+// the caller can make the first attempt.
+func QueueWebhookDelivery(ctx context.Context, hook *models.Webhook, l *models.Log) (*models.WebhookDelivery, error) {
+	id := primitive.NewObjectID()
+	lease := time.Now().Add(webhookLease)
+
+	delivery := &models.WebhookDelivery{
+		ID:            id,
+		IdWebhook:     hook.ID.Hex(),
+		Event:         models.NewWebhookEvent(id.Hex(), l),
+		Status:        models.DeliveryPending,
+		NextAttemptAt: &lease,
+		CreatedAt:     time.Now(),
+	}
+
+	if _, err := db.Database.Collection("webhook_deliveries").InsertOne(ctx, delivery); err != nil {
+		return nil, err
+	}
+	return delivery, nil
+}
+
+// SignWebhook returns the signature sent in X-SanchezDNS-Signature: an
+// HMAC-SHA256 over "<timestamp>.<body>" keyed with the webhook secret.
+func SignWebhook(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// DeliverWebhook makes one attempt at a delivery and records the outcome,
+// scheduling a retry with backoff when it fails.
+func DeliverWebhook(ctx context.Context, hook *models.Webhook, delivery *models.WebhookDelivery) error {
+	err := postWebhook(ctx, hook, delivery)
+
+	now := time.Now()
+	delivery.Attempts++
+
+	switch {
+	case err == nil:
+		delivery.Status = models.DeliveryDelivered
+		delivery.LastError = ""
+		delivery.DeliveredAt = &now
+		delivery.NextAttemptAt = nil
+	case delivery.Attempts >= models.WebhookMaxAttempts:
+		delivery.Status = models.DeliveryFailed
+		delivery.LastError = err.Error()
+		delivery.NextAttemptAt = nil
+	default:
+		next := now.Add(models.WebhookBackoff(delivery.Attempts))
+		delivery.LastError = err.Error()
+		delivery.NextAttemptAt = &next
+	}
+
+	ctxSave, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if _, serr := db.Database.Collection("webhook_deliveries").ReplaceOne(ctxSave, bson.M{"_id": delivery.ID}, delivery); serr != nil {
+		slog.Error("failed to save webhook delivery", "webhook", hook.Name, "error", serr)
+	}
+
+	if err != nil {
+		slog.Error("webhook delivery failed", "webhook", hook.Name, "action", delivery.Event.Action, "attempt", delivery.Attempts, "error", err)
+	}
+	return err
+}
+
+func postWebhook(ctx context.Context, hook *models.Webhook, delivery *models.WebhookDelivery) error {
+	delivery.LastStatusCode = 0
+
+	secret, err := utils.Decrypt(hook.Secret)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt secret: %w", err)
+	}
+
+	body, err := json.Marshal(delivery.Event)
+	if err != nil {
+		return err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	resp, err := resty.New().SetTimeout(10*time.Second).R().SetContext(ctx).
+		SetHeader("Content-Type", "application/json").
+		SetHeader("User-Agent", "SanchezDNS-Webhook").
+		SetHeader("X-SanchezDNS-Event", delivery.Event.Action).
+		SetHeader("X-SanchezDNS-Delivery", delivery.ID.Hex()).
+		SetHeader("X-SanchezDNS-Timestamp", timestamp).
+		SetHeader("X-SanchezDNS-Signature", SignWebhook(secret, timestamp, body)).
+		SetBody(body).
+		Post(hook.URL)
+	if err != nil {
+		return err
+	}
+
+	delivery.LastStatusCode = resp.StatusCode()
+	if resp.IsError() || resp.StatusCode() >= 300 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode())
+	}
+	return nil
+}
+
+func webhookLoop(ctx context.Context) {
+	ticker := time.NewTicker(15 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			retryWebhooks(ctx)
+		}
+	}
+}
+
+// retryWebhooks attempts the pending deliveries that are due, claiming each
+// one with a lease first.
+func retryWebhooks(ctx context.Context) {
+	hooks := map[string]*models.Webhook{}
+
+	for range 100 {
+		now := time.Now()
+		lease := now.Add(webhookLease)
+
+		var delivery models.WebhookDelivery
+		err := db.Database.Collection("webhook_deliveries").FindOneAndUpdate(ctx,
+			bson.M{"status": models.DeliveryPending, "nextAttemptAt": bson.M{"$lte": now}},
+			bson.M{"$set": bson.M{"nextAttemptAt": lease}},
+			options.FindOneAndUpdate().SetSort(bson.M{"nextAttemptAt": 1}).SetReturnDocument(options.After),
+		).Decode(&delivery)
+		if err != nil {
+			return
+		}
+
+		hook, ok := hooks[delivery.IdWebhook]
+		if !ok {
+			hook, err = FindWebhook(ctx, delivery.IdWebhook)
+			if err != nil {
+				slog.Error("failed to fetch webhook", "id", delivery.IdWebhook, "error", err)
+				continue
+			}
+			hooks[delivery.IdWebhook] = hook
+		}
+
+		if hook == nil || !hook.Enabled {
+			_, _ = db.Database.Collection("webhook_deliveries").UpdateOne(ctx, bson.M{"_id": delivery.ID},
+				bson.M{"$set": bson.M{"status": models.DeliveryFailed, "lastError": "webhook removed or disabled"}, "$unset": bson.M{"nextAttemptAt": ""}})
+			continue
+		}
+
+		ctxSend, cancel := context.WithTimeout(ctx, 30*time.Second)
+		_ = DeliverWebhook(ctxSend, hook, &delivery)
+		cancel()
+	}
+}
+
+// FindWebhook returns nil without an error when the webhook does not
+// exist.
+func FindWebhook(ctx context.Context, id string) (*models.Webhook, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, nil
+	}
+
+	var hook models.Webhook
+	err = db.Database.Collection("webhooks").FindOne(ctx, bson.M{"_id": oid}).Decode(&hook)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &hook, nil
+}
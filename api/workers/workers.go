@@ -0,0 +1,47 @@
+package workers
+
+import (
+	"context"
+	"errors"
+
+	"github.com/rafinhacuri/SanchezDNS/db"
+	"github.com/rafinhacuri/SanchezDNS/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Start launches the background jobs. They stop when ctx is cancelled.
+func Start(ctx context.Context) {
+	go syncLoop(ctx)
+	go driftLoop(ctx)
+	go acmeLoop(ctx)
+	go statsLoop(ctx)
+	go healthLoop(ctx)
+	go webhookLoop(ctx)
+	go ensureLogIndexes(ctx)
+	go ensureSecurityEventIndexes(ctx)
+	go logRetentionLoop(ctx)
+
+	models.OnLogInsert(enqueueWebhooks)
+	models.OnLogInsert(notifyChannels)
+}
+
+// ensureTTLIndex expires the documents of collection seconds after their
+// field. An existing index with another expiry, left by an earlier
+// retention setting, is changed in place.
+func ensureTTLIndex(ctx context.Context, collection, field string, seconds int32) error {
+	_, err := db.Database.Collection(collection).Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: field, Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(seconds),
+	})
+
+	var cmdErr mongo.CommandError
+	if errors.As(err, &cmdErr) && cmdErr.Code == 85 {
+		return db.Database.RunCommand(ctx, bson.D{
+			{Key: "collMod", Value: collection},
+			{Key: "index", Value: bson.D{{Key: "keyPattern", Value: bson.D{{Key: field, Value: 1}}}, {Key: "expireAfterSeconds", Value: seconds}}},
+		}).Err()
+	}
+	return err
+}